@@ -1,12 +1,24 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/erickhilda/vugo/internal/database"
 	"github.com/erickhilda/vugo/internal/server"
+	"github.com/erickhilda/vugo/internal/templates"
+)
+
+const (
+	// templatesDir is where templates are read from in development
+	templatesDir = "internal/templates"
+	// shutdownTimeout is how long requests get to finish on shutdown
+	shutdownTimeout = 10 * time.Second
 )
 
 func main() {
@@ -23,6 +35,17 @@ func main() {
 	}
 	defer db.Close()
 
+	// Parse templates. Outside production they are read from disk when
+	// available and reloaded on every render.
+	if os.Getenv("ENV") != "production" && templatesOnDisk() {
+		err = templates.InitDev(templatesDir)
+	} else {
+		err = templates.Init(templates.Files)
+	}
+	if err != nil {
+		log.Fatalf("Failed to parse templates: %v", err)
+	}
+
 	// Get server port from environment or use default
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -32,9 +55,36 @@ func main() {
 	// Initialize server
 	srv := server.New(db.DB)
 
+	// Stop on interrupt, letting background jobs and requests finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Start background jobs
+	srv.StartJobs(ctx)
+
 	// Start server
-	log.Printf("Server starting on :%s", port)
-	if err := http.ListenAndServe(":"+port, srv); err != nil {
-		log.Fatalf("Server failed to start: %v", err)
+	httpServer := &http.Server{Addr: ":" + port, Handler: srv}
+	go func() {
+		log.Printf("Server starting on :%s", port)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed to start: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down server: %v", err)
 	}
+	srv.WaitJobs()
+}
+
+// templatesOnDisk reports whether the server runs from a source checkout
+// with the templates next to it
+func templatesOnDisk() bool {
+	info, err := os.Stat(templatesDir)
+	return err == nil && info.IsDir()
 }
@@ -0,0 +1,57 @@
+-- Restore dense integer positions in rank order
+
+-- Checklists
+ALTER TABLE checklist_items ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE checklist_items SET position = r.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY task_id ORDER BY rank, id) - 1 AS position
+    FROM checklist_items
+) AS r
+WHERE checklist_items.id = r.id;
+
+DROP INDEX idx_checklist_items_rank;
+ALTER TABLE checklist_items DROP COLUMN rank;
+CREATE INDEX idx_checklist_items_position ON checklist_items(task_id, position);
+
+-- Tasks
+ALTER TABLE tasks ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE tasks SET position = r.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY column_id ORDER BY rank, id) - 1 AS position
+    FROM tasks
+) AS r
+WHERE tasks.id = r.id;
+
+DROP INDEX idx_tasks_rank;
+ALTER TABLE tasks DROP COLUMN rank;
+CREATE INDEX idx_tasks_position ON tasks(column_id, position);
+
+-- Columns
+ALTER TABLE columns ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE columns SET position = r.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY board_id ORDER BY rank, id) - 1 AS position
+    FROM columns
+) AS r
+WHERE columns.id = r.id;
+
+DROP INDEX idx_columns_rank;
+ALTER TABLE columns DROP COLUMN rank;
+CREATE INDEX idx_columns_position ON columns(board_id, position);
+
+-- Boards
+ALTER TABLE boards ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE boards SET position = r.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY project_id ORDER BY rank, id) - 1 AS position
+    FROM boards
+) AS r
+WHERE boards.id = r.id;
+
+DROP INDEX idx_boards_rank;
+ALTER TABLE boards DROP COLUMN rank;
+CREATE INDEX idx_boards_position ON boards(project_id, position);
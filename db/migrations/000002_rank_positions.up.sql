@@ -0,0 +1,60 @@
+-- Replace integer positions with LexoRank-style string ranks so moving an
+-- item only rewrites that item's row. Ranks use the digits 0-9a-z, compare
+-- bytewise and never end in '0'. Existing positions are converted to evenly
+-- spaced, fixed width ranks in their current order.
+
+-- Boards
+ALTER TABLE boards ADD COLUMN rank TEXT NOT NULL DEFAULT '';
+
+UPDATE boards SET rank = r.rank
+FROM (
+    SELECT id, printf('%08d', row_number() OVER (PARTITION BY project_id ORDER BY position, created_at, id) * 1000 + 1) AS rank
+    FROM boards
+) AS r
+WHERE boards.id = r.id;
+
+DROP INDEX idx_boards_position;
+ALTER TABLE boards DROP COLUMN position;
+CREATE INDEX idx_boards_rank ON boards(project_id, rank);
+
+-- Columns
+ALTER TABLE columns ADD COLUMN rank TEXT NOT NULL DEFAULT '';
+
+UPDATE columns SET rank = r.rank
+FROM (
+    SELECT id, printf('%08d', row_number() OVER (PARTITION BY board_id ORDER BY position, created_at, id) * 1000 + 1) AS rank
+    FROM columns
+) AS r
+WHERE columns.id = r.id;
+
+DROP INDEX idx_columns_position;
+ALTER TABLE columns DROP COLUMN position;
+CREATE INDEX idx_columns_rank ON columns(board_id, rank);
+
+-- Tasks
+ALTER TABLE tasks ADD COLUMN rank TEXT NOT NULL DEFAULT '';
+
+UPDATE tasks SET rank = r.rank
+FROM (
+    SELECT id, printf('%08d', row_number() OVER (PARTITION BY column_id ORDER BY position, created_at, id) * 1000 + 1) AS rank
+    FROM tasks
+) AS r
+WHERE tasks.id = r.id;
+
+DROP INDEX idx_tasks_position;
+ALTER TABLE tasks DROP COLUMN position;
+CREATE INDEX idx_tasks_rank ON tasks(column_id, rank);
+
+-- Checklists
+ALTER TABLE checklist_items ADD COLUMN rank TEXT NOT NULL DEFAULT '';
+
+UPDATE checklist_items SET rank = r.rank
+FROM (
+    SELECT id, printf('%08d', row_number() OVER (PARTITION BY task_id ORDER BY position, created_at, id) * 1000 + 1) AS rank
+    FROM checklist_items
+) AS r
+WHERE checklist_items.id = r.id;
+
+DROP INDEX idx_checklist_items_position;
+ALTER TABLE checklist_items DROP COLUMN position;
+CREATE INDEX idx_checklist_items_rank ON checklist_items(task_id, rank);
@@ -0,0 +1,3 @@
+-- Drop tables in reverse order of creation
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS mentions;
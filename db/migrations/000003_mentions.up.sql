@@ -0,0 +1,32 @@
+-- Users mentioned with @name or @email in a task description or comment,
+-- and the notifications sent to them.
+
+-- Mentions
+CREATE TABLE mentions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE, -- NULL for the task description
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_mentions_source ON mentions(task_id, IFNULL(comment_id, 0), user_id);
+CREATE INDEX idx_mentions_comment_id ON mentions(comment_id);
+CREATE INDEX idx_mentions_user_id ON mentions(user_id);
+
+-- Notifications
+CREATE TABLE notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, -- recipient
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    project_id INTEGER REFERENCES projects(id) ON DELETE CASCADE,
+    task_id INTEGER REFERENCES tasks(id) ON DELETE CASCADE,
+    comment_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+    type TEXT NOT NULL, -- 'mentioned', etc.
+    read_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notifications_user_id ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_task_id ON notifications(task_id);
+CREATE INDEX idx_notifications_comment_id ON notifications(comment_id);
@@ -0,0 +1,25 @@
+-- Users following a task: its creator, assignees, commenters and anyone who
+-- chose to watch it. Watchers are notified of new comments and of the due
+-- date approaching.
+
+-- Task Watchers
+CREATE TABLE task_watchers (
+    task_id INTEGER NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX idx_task_watchers_user_id ON task_watchers(user_id);
+
+INSERT OR IGNORE INTO task_watchers (task_id, user_id)
+SELECT id, created_by FROM tasks;
+
+INSERT OR IGNORE INTO task_watchers (task_id, user_id)
+SELECT task_id, user_id FROM task_assignees;
+
+INSERT OR IGNORE INTO task_watchers (task_id, user_id)
+SELECT DISTINCT task_id, user_id FROM comments;
+
+-- Unread notifications are counted on every page load
+CREATE INDEX idx_notifications_unread ON notifications(user_id) WHERE read_at IS NULL;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS email_preferences;
+DROP INDEX IF EXISTS idx_notifications_unemailed;
+ALTER TABLE notifications DROP COLUMN emailed_at;
@@ -0,0 +1,19 @@
+-- Email delivery of notifications. Each user chooses per notification type
+-- whether emails are sent immediately, collected into a daily digest or
+-- not sent at all; without a preference they are sent immediately.
+
+ALTER TABLE notifications ADD COLUMN emailed_at DATETIME;
+
+-- Notifications from before emails existed are not sent
+UPDATE notifications SET emailed_at = CURRENT_TIMESTAMP;
+
+CREATE INDEX idx_notifications_unemailed ON notifications(created_at) WHERE emailed_at IS NULL;
+
+-- Email Preferences
+CREATE TABLE email_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL, -- notification type
+    delivery TEXT NOT NULL DEFAULT 'immediate', -- 'immediate', 'digest', 'off'
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, type)
+);
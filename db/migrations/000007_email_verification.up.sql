@@ -0,0 +1,37 @@
+-- Email verification. Accounts from before verification existed never
+-- proved they own their address, so they start unverified and verify it
+-- like new accounts.
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Emails are now stored trimmed and lower case, and looked up that way.
+-- Accounts whose emails differ only in case or spaces could not all keep
+-- their address, so the migration stops until they are merged or renamed
+-- by hand. SQLite can only raise errors from triggers.
+CREATE TEMP TABLE IF NOT EXISTS email_clash_check (checked INTEGER);
+CREATE TEMP TRIGGER IF NOT EXISTS email_clash_check
+BEFORE INSERT ON email_clash_check
+WHEN EXISTS (
+    SELECT 1 FROM users
+    GROUP BY LOWER(TRIM(email))
+    HAVING COUNT(*) > 1
+)
+BEGIN
+    SELECT RAISE(ABORT, 'users have emails that differ only in case or spaces; merge or rename them before migrating, see SELECT LOWER(TRIM(email)), COUNT(*) FROM users GROUP BY 1 HAVING COUNT(*) > 1');
+END;
+INSERT INTO email_clash_check VALUES (1);
+DROP TABLE email_clash_check;
+
+UPDATE users
+SET email = LOWER(TRIM(email))
+WHERE email != LOWER(TRIM(email));
+
+-- Email verification tokens, stored as SHA-256 hashes
+CREATE TABLE email_verification_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id, created_at);
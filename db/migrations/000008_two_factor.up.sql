@@ -0,0 +1,34 @@
+-- TOTP two-factor authentication. A secret without confirmed_at is an
+-- enrollment the user has not yet confirmed with a first code, and does
+-- not affect login. last_used_step stops a code being used twice.
+CREATE TABLE user_totp (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    confirmed_at DATETIME,
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time recovery codes for users who lose their authenticator, stored
+-- as SHA-256 hashes
+CREATE TABLE recovery_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes(user_id, code_hash);
+
+-- Logins that passed the password check and wait for a second factor,
+-- keyed by a SHA-256 hash of the challenge token given to the client
+CREATE TABLE login_challenges (
+    token_hash TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_challenges_expires_at ON login_challenges(expires_at);
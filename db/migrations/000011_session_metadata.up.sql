@@ -0,0 +1,21 @@
+-- Sessions are stored by a SHA-256 hash of the token in the session
+-- cookie, so a leaked database does not hand out live sessions. Existing
+-- sessions cannot be converted and are signed out.
+DROP TABLE sessions;
+
+-- expires_at is the absolute expiry; a session also expires when it has
+-- not been seen for the idle timeout
+CREATE TABLE sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+CREATE INDEX idx_sessions_last_seen_at ON sessions(last_seen_at);
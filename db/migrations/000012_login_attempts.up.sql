@@ -0,0 +1,24 @@
+-- Failed logins by account or client IP, for the SQLite login attempt
+-- store. attempt_key is "account:<email>" or "ip:<address>".
+CREATE TABLE login_attempts (
+    attempt_key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    blocked_until DATETIME,
+    last_failed_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_login_attempts_last_failed_at ON login_attempts(last_failed_at);
+
+-- Security audit log, such as logins locked out after too many failures.
+-- user_id is set when the event is about an existing user.
+CREATE TABLE security_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    event TEXT NOT NULL,
+    ip_address TEXT NOT NULL DEFAULT '',
+    details TEXT NOT NULL DEFAULT '{}', -- JSON blob for extra info
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_security_events_user_id ON security_events(user_id);
+CREATE INDEX idx_security_events_created_at ON security_events(created_at DESC);
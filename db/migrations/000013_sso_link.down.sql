@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS sso_login_states;
+
+CREATE TABLE sso_login_states (
+    state_hash TEXT PRIMARY KEY,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sso_login_states_expires_at ON sso_login_states(expires_at);
@@ -0,0 +1,15 @@
+-- Single sign-on logins can link the provider account to a signed in user
+-- instead of signing in. Pending logins only last minutes, so the table is
+-- recreated rather than altered.
+DROP TABLE IF EXISTS sso_login_states;
+
+CREATE TABLE sso_login_states (
+    state_hash TEXT PRIMARY KEY,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    link_user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sso_login_states_expires_at ON sso_login_states(expires_at);
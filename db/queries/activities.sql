@@ -27,7 +27,7 @@ SELECT
 FROM activities a
 JOIN users u ON a.user_id = u.id
 WHERE a.project_id = ?
-ORDER BY a.created_at DESC
+ORDER BY a.created_at DESC, a.id DESC
 LIMIT ? OFFSET ?;
 
 -- name: ListActivitiesByTask :many
@@ -40,5 +40,6 @@ SELECT
 FROM activities a
 JOIN users u ON a.user_id = u.id
 WHERE a.task_id = ?
-ORDER BY a.created_at DESC;
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT ? OFFSET ?;
 
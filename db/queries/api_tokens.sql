@@ -0,0 +1,30 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (
+    user_id, name, prefix, token_hash, scopes, expires_at
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetAPITokenByHash :one
+SELECT * FROM api_tokens
+WHERE token_hash = ? LIMIT 1;
+
+-- name: ListUserAPITokens :many
+SELECT * FROM api_tokens
+WHERE user_id = ?
+ORDER BY created_at DESC, id DESC;
+
+-- name: TouchAPIToken :exec
+-- Records when a token was used, at most once per interval to save writes
+UPDATE api_tokens
+SET last_used_at = ?
+WHERE id = ? AND (last_used_at IS NULL OR last_used_at < ?);
+
+-- name: DeleteUserAPIToken :execrows
+DELETE FROM api_tokens
+WHERE id = ? AND user_id = ?;
+
+-- name: DeleteExpiredAPITokens :exec
+DELETE FROM api_tokens
+WHERE expires_at IS NOT NULL AND expires_at < CURRENT_TIMESTAMP;
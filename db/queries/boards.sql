@@ -1,6 +1,6 @@
 -- name: CreateBoard :one
 INSERT INTO boards (
-    project_id, name, position
+    project_id, name, rank
 ) VALUES (
     ?, ?, ?
 )
@@ -13,7 +13,7 @@ WHERE id = ? LIMIT 1;
 -- name: ListBoardsByProject :many
 SELECT * FROM boards
 WHERE project_id = ?
-ORDER BY position ASC, created_at ASC;
+ORDER BY rank ASC, id ASC;
 
 -- name: UpdateBoard :one
 UPDATE boards
@@ -23,10 +23,10 @@ SET
 WHERE id = ?
 RETURNING *;
 
--- name: UpdateBoardPosition :exec
+-- name: UpdateBoardRank :exec
 UPDATE boards
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
@@ -1,6 +1,6 @@
 -- name: CreateChecklistItem :one
 INSERT INTO checklist_items (
-    task_id, content, position
+    task_id, content, rank
 ) VALUES (
     ?, ?, ?
 )
@@ -13,7 +13,14 @@ WHERE id = ? LIMIT 1;
 -- name: ListChecklistItemsByTask :many
 SELECT * FROM checklist_items
 WHERE task_id = ?
-ORDER BY position ASC, created_at ASC;
+ORDER BY rank ASC, id ASC;
+
+-- name: GetChecklistProgress :one
+SELECT
+    COUNT(*) AS total,
+    CAST(COALESCE(SUM(completed), 0) AS INTEGER) AS completed
+FROM checklist_items
+WHERE task_id = ?;
 
 -- name: UpdateChecklistItem :one
 UPDATE checklist_items
@@ -31,10 +38,10 @@ SET
 WHERE id = ?
 RETURNING *;
 
--- name: UpdateChecklistItemPosition :exec
+-- name: UpdateChecklistItemRank :exec
 UPDATE checklist_items
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
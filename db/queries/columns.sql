@@ -1,6 +1,6 @@
 -- name: CreateColumn :one
 INSERT INTO columns (
-    board_id, name, position, color, wip_limit
+    board_id, name, rank, color, wip_limit
 ) VALUES (
     ?, ?, ?, ?, ?
 )
@@ -10,10 +10,15 @@ RETURNING *;
 SELECT * FROM columns
 WHERE id = ? LIMIT 1;
 
+-- name: GetProjectIDByColumn :one
+SELECT b.project_id FROM columns c
+JOIN boards b ON c.board_id = b.id
+WHERE c.id = ? LIMIT 1;
+
 -- name: ListColumnsByBoard :many
 SELECT * FROM columns
 WHERE board_id = ?
-ORDER BY position ASC, created_at ASC;
+ORDER BY rank ASC, id ASC;
 
 -- name: UpdateColumn :one
 UPDATE columns
@@ -25,10 +30,10 @@ SET
 WHERE id = ?
 RETURNING *;
 
--- name: UpdateColumnPosition :exec
+-- name: UpdateColumnRank :exec
 UPDATE columns
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
@@ -33,6 +33,10 @@ JOIN users u ON c.user_id = u.id
 WHERE c.task_id = ?
 ORDER BY c.created_at ASC;
 
+-- name: CountCommentsByTask :one
+SELECT COUNT(*) FROM comments
+WHERE task_id = ?;
+
 -- name: UpdateComment :one
 UPDATE comments
 SET 
@@ -0,0 +1,14 @@
+-- name: ListEmailPreferences :many
+SELECT * FROM email_preferences
+WHERE user_id = ?
+ORDER BY "type" ASC;
+
+-- name: UpsertEmailPreference :exec
+INSERT INTO email_preferences (
+    user_id, type, delivery
+) VALUES (
+    ?, ?, ?
+)
+ON CONFLICT (user_id, type) DO UPDATE SET
+    delivery = excluded.delivery,
+    updated_at = CURRENT_TIMESTAMP;
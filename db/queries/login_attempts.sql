@@ -0,0 +1,33 @@
+-- name: GetLoginAttempt :one
+SELECT * FROM login_attempts
+WHERE attempt_key = ? LIMIT 1;
+
+-- name: AddLoginFailure :one
+-- Counts a failure, starting over when the last one was before since
+INSERT INTO login_attempts (
+    attempt_key, failures, last_failed_at
+) VALUES (
+    ?, 1, ?
+)
+ON CONFLICT (attempt_key) DO UPDATE SET
+    failures = CASE
+        WHEN login_attempts.last_failed_at < sqlc.arg(since) THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failed_at = excluded.last_failed_at
+RETURNING failures;
+
+-- name: BlockLoginAttempts :exec
+UPDATE login_attempts
+SET blocked_until = ?
+WHERE attempt_key = ?;
+
+-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts
+WHERE attempt_key = ?;
+
+-- name: DeleteStaleLoginAttempts :exec
+-- Deletes failures older than before that no longer block logins
+DELETE FROM login_attempts
+WHERE last_failed_at < sqlc.arg(before)
+    AND (blocked_until IS NULL OR blocked_until < CURRENT_TIMESTAMP);
@@ -0,0 +1,23 @@
+-- name: CreateLoginChallenge :exec
+INSERT INTO login_challenges (
+    token_hash, user_id, expires_at
+) VALUES (
+    ?, ?, ?
+);
+
+-- name: GetLoginChallenge :one
+SELECT * FROM login_challenges
+WHERE token_hash = ? LIMIT 1;
+
+-- name: IncrementLoginChallengeAttempts :exec
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE token_hash = ?;
+
+-- name: DeleteLoginChallenge :exec
+DELETE FROM login_challenges
+WHERE token_hash = ?;
+
+-- name: DeleteExpiredLoginChallenges :exec
+DELETE FROM login_challenges
+WHERE expires_at < CURRENT_TIMESTAMP;
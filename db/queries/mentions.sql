@@ -0,0 +1,15 @@
+-- name: CreateMention :exec
+INSERT OR IGNORE INTO mentions (
+    task_id, comment_id, user_id
+) VALUES (
+    ?, ?, ?
+);
+
+-- name: ListMentionedUserIDs :many
+SELECT user_id FROM mentions
+WHERE task_id = ? AND comment_id IS ?
+ORDER BY id ASC;
+
+-- name: DeleteMention :exec
+DELETE FROM mentions
+WHERE task_id = ? AND comment_id IS ? AND user_id = ?;
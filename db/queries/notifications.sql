@@ -0,0 +1,118 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (
+    user_id, actor_id, project_id, task_id, comment_id, type
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetNotificationWithDetails :one
+SELECT
+    n.*,
+    a.name as actor_name,
+    a.email as actor_email,
+    a.avatar_url as actor_avatar_url,
+    p.name as project_name,
+    t.title as task_title
+FROM notifications n
+LEFT JOIN users a ON n.actor_id = a.id
+LEFT JOIN projects p ON n.project_id = p.id
+LEFT JOIN tasks t ON n.task_id = t.id
+WHERE n.id = ? AND n.user_id = ? LIMIT 1;
+
+-- name: ListNotificationsByUser :many
+SELECT
+    n.*,
+    a.name as actor_name,
+    a.email as actor_email,
+    a.avatar_url as actor_avatar_url,
+    p.name as project_name,
+    t.title as task_title
+FROM notifications n
+LEFT JOIN users a ON n.actor_id = a.id
+LEFT JOIN projects p ON n.project_id = p.id
+LEFT JOIN tasks t ON n.task_id = t.id
+WHERE n.user_id = ?
+ORDER BY n.created_at DESC, n.id DESC
+LIMIT ? OFFSET ?;
+
+-- name: ListUnreadNotificationsByUser :many
+SELECT
+    n.*,
+    a.name as actor_name,
+    a.email as actor_email,
+    a.avatar_url as actor_avatar_url,
+    p.name as project_name,
+    t.title as task_title
+FROM notifications n
+LEFT JOIN users a ON n.actor_id = a.id
+LEFT JOIN projects p ON n.project_id = p.id
+LEFT JOIN tasks t ON n.task_id = t.id
+WHERE n.user_id = ? AND n.read_at IS NULL
+ORDER BY n.created_at DESC, n.id DESC
+LIMIT ? OFFSET ?;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = ? AND read_at IS NULL;
+
+-- name: MarkNotificationRead :exec
+UPDATE notifications
+SET read_at = CURRENT_TIMESTAMP
+WHERE id = ? AND user_id = ? AND read_at IS NULL;
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = CURRENT_TIMESTAMP
+WHERE user_id = ? AND read_at IS NULL;
+
+-- name: ListImmediateEmailNotifications :many
+-- Notifications of emailed types not yet sent to users who want them
+-- immediately
+SELECT
+    n.*,
+    u.name as user_name,
+    u.email as user_email,
+    a.name as actor_name,
+    p.name as project_name,
+    t.title as task_title
+FROM notifications n
+JOIN users u ON n.user_id = u.id
+LEFT JOIN email_preferences ep ON ep.user_id = n.user_id AND ep.type = n.type
+LEFT JOIN users a ON n.actor_id = a.id
+LEFT JOIN projects p ON n.project_id = p.id
+LEFT JOIN tasks t ON n.task_id = t.id
+WHERE n.emailed_at IS NULL
+  AND n.type IN ('assigned', 'mentioned', 'due_soon')
+  AND COALESCE(ep.delivery, 'immediate') = 'immediate'
+  AND n.created_at >= ?
+ORDER BY n.id ASC
+LIMIT ?;
+
+-- name: ListDigestEmailNotifications :many
+-- Unread notifications of emailed types not yet sent to users who want a
+-- daily digest, created before the end of the digest period
+SELECT
+    n.*,
+    u.name as user_name,
+    u.email as user_email,
+    a.name as actor_name,
+    p.name as project_name,
+    t.title as task_title
+FROM notifications n
+JOIN users u ON n.user_id = u.id
+JOIN email_preferences ep ON ep.user_id = n.user_id AND ep.type = n.type
+LEFT JOIN users a ON n.actor_id = a.id
+LEFT JOIN projects p ON n.project_id = p.id
+LEFT JOIN tasks t ON n.task_id = t.id
+WHERE n.emailed_at IS NULL
+  AND n.read_at IS NULL
+  AND n.type IN ('assigned', 'mentioned', 'due_soon')
+  AND ep.delivery = 'digest'
+  AND n.created_at < sqlc.arg(period_end)
+ORDER BY n.user_id ASC, n.id ASC;
+
+-- name: MarkNotificationEmailed :exec
+UPDATE notifications
+SET emailed_at = CURRENT_TIMESTAMP
+WHERE id = ?;
@@ -22,6 +22,14 @@ JOIN users u ON pm.user_id = u.id
 WHERE pm.project_id = ?
 ORDER BY pm.joined_at ASC;
 
+-- name: UpsertProjectMember :exec
+INSERT INTO project_members (
+    project_id, user_id, role
+) VALUES (
+    ?, ?, ?
+)
+ON CONFLICT (project_id, user_id) DO UPDATE SET role = excluded.role;
+
 -- name: UpdateProjectMemberRole :exec
 UPDATE project_members
 SET role = ?
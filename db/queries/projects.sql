@@ -25,6 +25,17 @@ SELECT * FROM projects
 WHERE owner_id = ? AND archived = FALSE
 ORDER BY created_at DESC;
 
+-- name: ListArchivedProjectsByOwner :many
+SELECT * FROM projects
+WHERE owner_id = ? AND archived = TRUE
+ORDER BY updated_at DESC;
+
+-- name: ListArchivedProjectsByMember :many
+SELECT p.* FROM projects p
+JOIN project_members pm ON p.id = pm.project_id
+WHERE pm.user_id = ? AND p.archived = TRUE
+ORDER BY p.updated_at DESC;
+
 -- name: ListProjectsByMember :many
 SELECT p.* FROM projects p
 JOIN project_members pm ON p.id = pm.project_id
@@ -55,6 +66,13 @@ SET
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ? AND owner_id = ?;
 
+-- name: TransferProjectOwnership :exec
+UPDATE projects
+SET 
+    owner_id = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
 -- name: DeleteProject :exec
 DELETE FROM projects
 WHERE id = ? AND owner_id = ?;
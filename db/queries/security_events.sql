@@ -0,0 +1,11 @@
+-- name: CreateSecurityEvent :exec
+INSERT INTO security_events (
+    user_id, event, ip_address, details
+) VALUES (
+    ?, ?, ?, ?
+);
+
+-- name: ListSecurityEvents :many
+SELECT * FROM security_events
+ORDER BY id DESC
+LIMIT ? OFFSET ?;
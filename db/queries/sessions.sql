@@ -1,24 +1,42 @@
 -- name: CreateSession :one
 INSERT INTO sessions (
-    id, user_id, expires_at
+    token_hash, user_id, user_agent, ip_address, last_seen_at, expires_at
 ) VALUES (
-    ?, ?, ?
+    ?, ?, ?, ?, ?, ?
 )
 RETURNING *;
 
--- name: GetSession :one
+-- name: GetSessionByTokenHash :one
 SELECT * FROM sessions
-WHERE id = ? LIMIT 1;
+WHERE token_hash = ? LIMIT 1;
+
+-- name: ListUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = ?
+ORDER BY last_seen_at DESC, id DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = ?
+WHERE id = ?;
 
 -- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = ?;
 
+-- name: DeleteUserSession :execrows
+DELETE FROM sessions
+WHERE id = ? AND user_id = ?;
+
 -- name: DeleteExpiredSessions :exec
+-- Deletes sessions past their absolute expiry or idle since idle_since
 DELETE FROM sessions
-WHERE expires_at < CURRENT_TIMESTAMP;
+WHERE expires_at < CURRENT_TIMESTAMP OR last_seen_at < sqlc.arg(idle_since);
 
 -- name: DeleteUserSessions :exec
 DELETE FROM sessions
 WHERE user_id = ?;
 
+-- name: DeleteOtherUserSessions :exec
+DELETE FROM sessions
+WHERE user_id = ? AND id != ?;
@@ -0,0 +1,18 @@
+-- name: CreateSSOLoginState :exec
+INSERT INTO sso_login_states (
+    state_hash, nonce, code_verifier, link_user_id, expires_at
+) VALUES (
+    ?, ?, ?, ?, ?
+);
+
+-- name: GetSSOLoginState :one
+SELECT * FROM sso_login_states
+WHERE state_hash = ? LIMIT 1;
+
+-- name: DeleteSSOLoginState :execrows
+DELETE FROM sso_login_states
+WHERE state_hash = ?;
+
+-- name: DeleteExpiredSSOLoginStates :exec
+DELETE FROM sso_login_states
+WHERE expires_at < CURRENT_TIMESTAMP;
@@ -0,0 +1,62 @@
+-- name: WatchTask :exec
+INSERT OR IGNORE INTO task_watchers (
+    task_id, user_id
+) VALUES (
+    ?, ?
+);
+
+-- name: UnwatchTask :exec
+DELETE FROM task_watchers
+WHERE task_id = ? AND user_id = ?;
+
+-- name: IsWatchingTask :one
+SELECT EXISTS(
+    SELECT 1 FROM task_watchers
+    WHERE task_id = ? AND user_id = ?
+) as is_watching;
+
+-- name: DeleteProjectTaskWatchers :exec
+-- Deletes a user's watches on the tasks of a project
+DELETE FROM task_watchers
+WHERE user_id = ?
+  AND task_id IN (
+      SELECT t.id FROM tasks t
+      JOIN columns c ON t.column_id = c.id
+      JOIN boards b ON c.board_id = b.id
+      WHERE b.project_id = ?
+  );
+
+-- name: ListTaskWatcherIDs :many
+-- Watchers who are still members of the task's project
+SELECT tw.user_id
+FROM task_watchers tw
+JOIN tasks t ON tw.task_id = t.id
+JOIN columns c ON t.column_id = c.id
+JOIN boards b ON c.board_id = b.id
+JOIN project_members pm ON pm.project_id = b.project_id AND pm.user_id = tw.user_id
+WHERE tw.task_id = ?
+ORDER BY tw.created_at ASC, tw.user_id ASC;
+
+-- name: ListDueSoonTaskWatchers :many
+-- Watchers of open tasks due today or tomorrow (UTC) who are still
+-- members of the project and have not been reminded of the task since
+-- yesterday
+SELECT
+    tw.task_id,
+    tw.user_id,
+    b.project_id
+FROM task_watchers tw
+JOIN tasks t ON tw.task_id = t.id
+JOIN columns c ON t.column_id = c.id
+JOIN boards b ON c.board_id = b.id
+JOIN project_members pm ON pm.project_id = b.project_id AND pm.user_id = tw.user_id
+WHERE t.completed_at IS NULL
+  AND date(t.due_date) BETWEEN date('now') AND date('now', '+1 day')
+  AND NOT EXISTS (
+      SELECT 1 FROM notifications n
+      WHERE n.user_id = tw.user_id
+        AND n.task_id = tw.task_id
+        AND n.type = 'due_soon'
+        AND n.created_at >= datetime('now', 'start of day', '-1 day')
+  )
+ORDER BY tw.task_id ASC, tw.user_id ASC;
@@ -1,6 +1,6 @@
 -- name: CreateTask :one
 INSERT INTO tasks (
-    column_id, created_by, title, description, position, priority, due_date
+    column_id, created_by, title, description, rank, priority, due_date
 ) VALUES (
     ?, ?, ?, ?, ?, ?, ?
 )
@@ -10,6 +10,17 @@ RETURNING *;
 SELECT * FROM tasks
 WHERE id = ? LIMIT 1;
 
+-- name: GetBoardIDByTask :one
+SELECT c.board_id FROM tasks t
+JOIN columns c ON t.column_id = c.id
+WHERE t.id = ? LIMIT 1;
+
+-- name: GetProjectIDByTask :one
+SELECT b.project_id FROM tasks t
+JOIN columns c ON t.column_id = c.id
+JOIN boards b ON c.board_id = b.id
+WHERE t.id = ? LIMIT 1;
+
 -- name: GetTaskWithCreator :one
 SELECT 
     t.*,
@@ -24,7 +35,28 @@ WHERE t.id = ? LIMIT 1;
 -- name: ListTasksByColumn :many
 SELECT * FROM tasks
 WHERE column_id = ? AND completed_at IS NULL
-ORDER BY position ASC, created_at ASC;
+ORDER BY rank ASC, id ASC;
+
+-- name: ListOpenTasksByBoard :many
+SELECT t.* FROM tasks t
+JOIN columns c ON t.column_id = c.id
+WHERE c.board_id = ? AND t.completed_at IS NULL
+ORDER BY t.column_id ASC, t.rank ASC, t.id ASC;
+
+-- name: ListTaskRanksByColumn :many
+SELECT id, rank FROM tasks
+WHERE column_id = ?
+ORDER BY rank ASC, id ASC;
+
+-- name: GetLastTaskRankByColumn :one
+SELECT rank FROM tasks
+WHERE column_id = ?
+ORDER BY rank DESC, id DESC
+LIMIT 1;
+
+-- name: CountOpenTasksByColumn :one
+SELECT COUNT(*) FROM tasks
+WHERE column_id = ? AND completed_at IS NULL;
 
 -- name: ListTasksByUser :many
 SELECT t.* FROM tasks t
@@ -54,15 +86,15 @@ RETURNING *;
 UPDATE tasks
 SET 
     column_id = ?,
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
 RETURNING *;
 
--- name: UpdateTaskPosition :exec
+-- name: UpdateTaskRank :exec
 UPDATE tasks
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
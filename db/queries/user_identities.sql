@@ -0,0 +1,11 @@
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities
+WHERE issuer = ? AND subject = ? LIMIT 1;
+
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (
+    user_id, issuer, subject, email
+) VALUES (
+    ?, ?, ?, ?
+)
+RETURNING *;
@@ -0,0 +1,34 @@
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = ? LIMIT 1;
+
+-- name: UpsertUserTOTP :exec
+-- Starts a new enrollment, replacing an unconfirmed one
+INSERT INTO user_totp (
+    user_id, secret
+) VALUES (
+    ?, ?
+)
+ON CONFLICT (user_id) DO UPDATE SET
+    secret = excluded.secret,
+    confirmed_at = NULL,
+    last_used_step = 0,
+    created_at = CURRENT_TIMESTAMP;
+
+-- name: ConfirmUserTOTP :exec
+UPDATE user_totp
+SET
+    confirmed_at = CURRENT_TIMESTAMP,
+    last_used_step = ?
+WHERE user_id = ?;
+
+-- name: UseTOTPStep :execrows
+-- Records the time step of a code used, failing if it or a later one was
+-- used already
+UPDATE user_totp
+SET last_used_step = ?
+WHERE user_id = ? AND last_used_step < ?;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = ?;
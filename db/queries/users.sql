@@ -30,3 +30,11 @@ SET
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?;
 
+
+-- name: MarkUserEmailVerified :one
+UPDATE users
+SET
+    email_verified = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING *;
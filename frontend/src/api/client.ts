@@ -1,5 +1,5 @@
-import { ofetch } from 'ofetch'
-import type { ApiResponse } from './types'
+import { FetchError, ofetch, type FetchOptions } from 'ofetch'
+import type { ApiErrorResponse, ApiResponse } from './types'
 
 /**
  * API Client Configuration
@@ -8,11 +8,55 @@ import type { ApiResponse } from './types'
  * Base URL is configured via environment variable (VITE_API_URL).
  */
 
-export const apiClient = ofetch.create({
-  baseURL: import.meta.env.VITE_API_URL || '/api',
+const baseURL = import.meta.env.VITE_API_URL || '/api'
+
+/**
+ * CSRF token of the current session, sent with requests that change data.
+ * It changes with the session, so it is cleared on login and logout.
+ */
+let csrfToken: string | null = null
+
+export function clearCsrfToken() {
+  csrfToken = null
+}
+
+async function getCsrfToken(): Promise<string | null> {
+  if (!csrfToken) {
+    try {
+      const response = await ofetch<ApiResponse<{ csrf_token: string }>>('/auth/csrf', {
+        baseURL,
+        credentials: 'include',
+        redirect: 'manual',
+      })
+      if (response?.success) {
+        csrfToken = response.data.csrf_token
+      }
+    } catch {
+      // Not signed in, so no token is needed
+    }
+  }
+  return csrfToken
+}
+
+const fetchClient = ofetch.create({
+  baseURL,
   credentials: 'include', // Include cookies for session-based auth
   retry: 1, // Retry once on failure
 
+  // Send the CSRF token with requests that change data
+  async onRequest({ options }) {
+    const method = (options.method || 'GET').toUpperCase()
+    if (['GET', 'HEAD', 'OPTIONS'].includes(method)) {
+      return
+    }
+    const token = await getCsrfToken()
+    if (token) {
+      const headers = new Headers(options.headers)
+      headers.set('X-CSRF-Token', token)
+      options.headers = headers
+    }
+  },
+
   // Global response interceptor
   onResponseError({ response }) {
     // Log API errors for debugging
@@ -24,6 +68,34 @@ export const apiClient = ofetch.create({
   },
 })
 
+/**
+ * Whether a request was rejected for its CSRF token, as when the session
+ * changed in another tab after the token was fetched
+ */
+function isCsrfError(error: unknown): boolean {
+  return (
+    error instanceof FetchError &&
+    error.status === 403 &&
+    (error.data as ApiErrorResponse | undefined)?.error?.code === 'CSRF_INVALID'
+  )
+}
+
+/**
+ * Sends an API request. A request rejected for its CSRF token is retried
+ * once with a freshly fetched token.
+ */
+export async function apiClient<T>(request: string, options?: FetchOptions<'json'>): Promise<T> {
+  try {
+    return await fetchClient<T>(request, options)
+  } catch (error) {
+    if (!isCsrfError(error)) {
+      throw error
+    }
+    clearCsrfToken()
+    return fetchClient<T>(request, options)
+  }
+}
+
 /**
  * Helper function to handle API responses consistently
  */
@@ -47,18 +119,18 @@ export const api = {
       apiClient<ApiResponse<any>>('/auth/login', {
         method: 'POST',
         body: { email, password },
-      }),
+      }).finally(clearCsrfToken),
 
     register: (email: string, password: string, name: string) =>
       apiClient<ApiResponse<any>>('/auth/register', {
         method: 'POST',
         body: { email, password, name },
-      }),
+      }).finally(clearCsrfToken),
 
     logout: () =>
       apiClient<ApiResponse<void>>('/auth/logout', {
         method: 'POST',
-      }),
+      }).finally(clearCsrfToken),
 
     me: () =>
       apiClient<ApiResponse<any>>('/auth/me', {
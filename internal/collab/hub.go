@@ -0,0 +1,212 @@
+package collab
+
+import (
+	"sync"
+)
+
+// Message types sent to board clients besides board events
+const (
+	PresenceJoined  = "presence.joined"
+	PresenceLeft    = "presence.left"
+	PresenceUpdated = "presence.updated"
+	Typing          = "typing"
+)
+
+// clientBuffer is how many messages may queue up for a client before it is
+// considered too slow and dropped
+const clientBuffer = 64
+
+// Viewer is a user connected to a board. A user with several tabs open is
+// one viewer per connection.
+type Viewer struct {
+	ClientID  uint64
+	UserID    int64
+	Name      string
+	AvatarURL string
+
+	// TaskID is the card the viewer has open, or zero
+	TaskID int64
+	// Editing is set while the viewer edits the open card
+	Editing bool
+}
+
+// TypingIndicator reports that a viewer is typing a comment on a task
+type TypingIndicator struct {
+	ClientID uint64
+	UserID   int64
+	Name     string
+	TaskID   int64
+}
+
+// Message is a presence message for a client. Data is a Viewer or a
+// TypingIndicator.
+type Message struct {
+	Type string
+	Data interface{}
+}
+
+// Hub tracks who is connected to each board and relays presence between
+// them
+type Hub struct {
+	mu     sync.Mutex
+	nextID uint64
+	boards map[int64]map[*Client]struct{}
+}
+
+// Client is one connection to a board
+type Client struct {
+	hub     *Hub
+	boardID int64
+	viewer  Viewer
+	send    chan Message
+}
+
+// NewHub creates a new presence hub
+func NewHub() *Hub {
+	return &Hub{
+		boards: make(map[int64]map[*Client]struct{}),
+	}
+}
+
+// Join connects a viewer to a board. It returns the new client and the
+// viewers that were already connected.
+func (h *Hub) Join(boardID int64, viewer Viewer) (*Client, []Viewer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	viewer.ClientID = h.nextID
+	viewer.TaskID = 0
+	viewer.Editing = false
+
+	clients, ok := h.boards[boardID]
+	if !ok {
+		clients = make(map[*Client]struct{})
+		h.boards[boardID] = clients
+	}
+
+	others := make([]Viewer, 0, len(clients))
+	for c := range clients {
+		others = append(others, c.viewer)
+	}
+
+	client := &Client{
+		hub:     h,
+		boardID: boardID,
+		viewer:  viewer,
+		send:    make(chan Message, clientBuffer),
+	}
+	clients[client] = struct{}{}
+
+	h.broadcast(client, Message{Type: PresenceJoined, Data: viewer})
+
+	return client, others
+}
+
+// Viewer returns the client's current presence
+func (c *Client) Viewer() Viewer {
+	c.hub.mu.Lock()
+	defer c.hub.mu.Unlock()
+	return c.viewer
+}
+
+// Messages returns the channel presence messages are delivered on. It is
+// closed when the client falls too far behind or leaves.
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// SetViewing records which card the viewer has open, if any, and whether
+// they are editing it
+func (c *Client) SetViewing(taskID int64, editing bool) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if taskID == 0 {
+		editing = false
+	}
+	if !h.registered(c) || (c.viewer.TaskID == taskID && c.viewer.Editing == editing) {
+		return
+	}
+
+	c.viewer.TaskID = taskID
+	c.viewer.Editing = editing
+	h.broadcast(c, Message{Type: PresenceUpdated, Data: c.viewer})
+}
+
+// Typing tells the other viewers that this viewer is typing a comment.
+// Clients hide the indicator when it is not repeated for a few seconds.
+func (c *Client) Typing(taskID int64) {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.registered(c) {
+		return
+	}
+	h.broadcast(c, Message{Type: Typing, Data: TypingIndicator{
+		ClientID: c.viewer.ClientID,
+		UserID:   c.viewer.UserID,
+		Name:     c.viewer.Name,
+		TaskID:   taskID,
+	}})
+}
+
+// Leave disconnects the client and tells the remaining viewers
+func (c *Client) Leave() {
+	h := c.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.remove(c) {
+		return
+	}
+	h.broadcast(c, Message{Type: PresenceLeft, Data: c.viewer})
+}
+
+// broadcast sends a message to every client of the sender's board except
+// the sender. Clients that fall behind are dropped, which in turn tells
+// the others they left. Callers must hold h.mu.
+func (h *Hub) broadcast(sender *Client, msg Message) {
+	var dropped []*Client
+	for c := range h.boards[sender.boardID] {
+		if c == sender {
+			continue
+		}
+		select {
+		case c.send <- msg:
+		default:
+			dropped = append(dropped, c)
+		}
+	}
+
+	for _, c := range dropped {
+		if h.remove(c) {
+			h.broadcast(c, Message{Type: PresenceLeft, Data: c.viewer})
+		}
+	}
+}
+
+// registered reports whether a client is still connected. Callers must
+// hold h.mu.
+func (h *Hub) registered(c *Client) bool {
+	_, ok := h.boards[c.boardID][c]
+	return ok
+}
+
+// remove unregisters a client and closes its channel. It reports whether
+// the client was still registered. Callers must hold h.mu.
+func (h *Hub) remove(c *Client) bool {
+	if !h.registered(c) {
+		return false
+	}
+
+	clients := h.boards[c.boardID]
+	delete(clients, c)
+	close(c.send)
+	if len(clients) == 0 {
+		delete(h.boards, c.boardID)
+	}
+	return true
+}
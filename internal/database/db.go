@@ -22,7 +22,9 @@ func New(dataSourceName string) (*DB, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", dataSourceName+"?_foreign_keys=1&_journal_mode=WAL")
+	// Transactions start with BEGIN IMMEDIATE so concurrent writers are
+	// serialized up front instead of failing when upgrading a read lock
+	db, err := sql.Open("sqlite3", dataSourceName+"?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -103,7 +103,7 @@ SELECT
 FROM activities a
 JOIN users u ON a.user_id = u.id
 WHERE a.project_id = ?
-ORDER BY a.created_at DESC
+ORDER BY a.created_at DESC, a.id DESC
 LIMIT ? OFFSET ?
 `
 
@@ -172,9 +172,16 @@ SELECT
 FROM activities a
 JOIN users u ON a.user_id = u.id
 WHERE a.task_id = ?
-ORDER BY a.created_at DESC
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT ? OFFSET ?
 `
 
+type ListActivitiesByTaskParams struct {
+	TaskID sql.NullInt64 `json:"task_id"`
+	Limit  int64         `json:"limit"`
+	Offset int64         `json:"offset"`
+}
+
 type ListActivitiesByTaskRow struct {
 	ID            int64          `json:"id"`
 	ProjectID     int64          `json:"project_id"`
@@ -189,8 +196,8 @@ type ListActivitiesByTaskRow struct {
 	UserAvatarUrl sql.NullString `json:"user_avatar_url"`
 }
 
-func (q *Queries) ListActivitiesByTask(ctx context.Context, taskID sql.NullInt64) ([]ListActivitiesByTaskRow, error) {
-	rows, err := q.db.QueryContext(ctx, listActivitiesByTask, taskID)
+func (q *Queries) ListActivitiesByTask(ctx context.Context, arg ListActivitiesByTaskParams) ([]ListActivitiesByTaskRow, error) {
+	rows, err := q.db.QueryContext(ctx, listActivitiesByTask, arg.TaskID, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
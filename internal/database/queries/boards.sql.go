@@ -11,29 +11,29 @@ import (
 
 const createBoard = `-- name: CreateBoard :one
 INSERT INTO boards (
-    project_id, name, position
+    project_id, name, rank
 ) VALUES (
     ?, ?, ?
 )
-RETURNING id, project_id, name, position, created_at, updated_at
+RETURNING id, project_id, name, created_at, updated_at, rank
 `
 
 type CreateBoardParams struct {
 	ProjectID int64  `json:"project_id"`
 	Name      string `json:"name"`
-	Position  int64  `json:"position"`
+	Rank      string `json:"rank"`
 }
 
 func (q *Queries) CreateBoard(ctx context.Context, arg CreateBoardParams) (Board, error) {
-	row := q.db.QueryRowContext(ctx, createBoard, arg.ProjectID, arg.Name, arg.Position)
+	row := q.db.QueryRowContext(ctx, createBoard, arg.ProjectID, arg.Name, arg.Rank)
 	var i Board
 	err := row.Scan(
 		&i.ID,
 		&i.ProjectID,
 		&i.Name,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
@@ -49,7 +49,7 @@ func (q *Queries) DeleteBoard(ctx context.Context, id int64) error {
 }
 
 const getBoard = `-- name: GetBoard :one
-SELECT id, project_id, name, position, created_at, updated_at FROM boards
+SELECT id, project_id, name, created_at, updated_at, rank FROM boards
 WHERE id = ? LIMIT 1
 `
 
@@ -60,17 +60,17 @@ func (q *Queries) GetBoard(ctx context.Context, id int64) (Board, error) {
 		&i.ID,
 		&i.ProjectID,
 		&i.Name,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
 const listBoardsByProject = `-- name: ListBoardsByProject :many
-SELECT id, project_id, name, position, created_at, updated_at FROM boards
+SELECT id, project_id, name, created_at, updated_at, rank FROM boards
 WHERE project_id = ?
-ORDER BY position ASC, created_at ASC
+ORDER BY rank ASC, id ASC
 `
 
 func (q *Queries) ListBoardsByProject(ctx context.Context, projectID int64) ([]Board, error) {
@@ -86,9 +86,9 @@ func (q *Queries) ListBoardsByProject(ctx context.Context, projectID int64) ([]B
 			&i.ID,
 			&i.ProjectID,
 			&i.Name,
-			&i.Position,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -109,7 +109,7 @@ SET
     name = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, project_id, name, position, created_at, updated_at
+RETURNING id, project_id, name, created_at, updated_at, rank
 `
 
 type UpdateBoardParams struct {
@@ -124,27 +124,27 @@ func (q *Queries) UpdateBoard(ctx context.Context, arg UpdateBoardParams) (Board
 		&i.ID,
 		&i.ProjectID,
 		&i.Name,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
-const updateBoardPosition = `-- name: UpdateBoardPosition :exec
+const updateBoardRank = `-- name: UpdateBoardRank :exec
 UPDATE boards
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
 `
 
-type UpdateBoardPositionParams struct {
-	Position int64 `json:"position"`
-	ID       int64 `json:"id"`
+type UpdateBoardRankParams struct {
+	Rank string `json:"rank"`
+	ID   int64  `json:"id"`
 }
 
-func (q *Queries) UpdateBoardPosition(ctx context.Context, arg UpdateBoardPositionParams) error {
-	_, err := q.db.ExecContext(ctx, updateBoardPosition, arg.Position, arg.ID)
+func (q *Queries) UpdateBoardRank(ctx context.Context, arg UpdateBoardRankParams) error {
+	_, err := q.db.ExecContext(ctx, updateBoardRank, arg.Rank, arg.ID)
 	return err
 }
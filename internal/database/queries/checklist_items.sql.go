@@ -11,30 +11,30 @@ import (
 
 const createChecklistItem = `-- name: CreateChecklistItem :one
 INSERT INTO checklist_items (
-    task_id, content, position
+    task_id, content, rank
 ) VALUES (
     ?, ?, ?
 )
-RETURNING id, task_id, content, completed, position, created_at, updated_at
+RETURNING id, task_id, content, completed, created_at, updated_at, rank
 `
 
 type CreateChecklistItemParams struct {
-	TaskID   int64  `json:"task_id"`
-	Content  string `json:"content"`
-	Position int64  `json:"position"`
+	TaskID  int64  `json:"task_id"`
+	Content string `json:"content"`
+	Rank    string `json:"rank"`
 }
 
 func (q *Queries) CreateChecklistItem(ctx context.Context, arg CreateChecklistItemParams) (ChecklistItem, error) {
-	row := q.db.QueryRowContext(ctx, createChecklistItem, arg.TaskID, arg.Content, arg.Position)
+	row := q.db.QueryRowContext(ctx, createChecklistItem, arg.TaskID, arg.Content, arg.Rank)
 	var i ChecklistItem
 	err := row.Scan(
 		&i.ID,
 		&i.TaskID,
 		&i.Content,
 		&i.Completed,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
@@ -50,7 +50,7 @@ func (q *Queries) DeleteChecklistItem(ctx context.Context, id int64) error {
 }
 
 const getChecklistItem = `-- name: GetChecklistItem :one
-SELECT id, task_id, content, completed, position, created_at, updated_at FROM checklist_items
+SELECT id, task_id, content, completed, created_at, updated_at, rank FROM checklist_items
 WHERE id = ? LIMIT 1
 `
 
@@ -62,17 +62,37 @@ func (q *Queries) GetChecklistItem(ctx context.Context, id int64) (ChecklistItem
 		&i.TaskID,
 		&i.Content,
 		&i.Completed,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
+const getChecklistProgress = `-- name: GetChecklistProgress :one
+SELECT
+    COUNT(*) AS total,
+    CAST(COALESCE(SUM(completed), 0) AS INTEGER) AS completed
+FROM checklist_items
+WHERE task_id = ?
+`
+
+type GetChecklistProgressRow struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
+}
+
+func (q *Queries) GetChecklistProgress(ctx context.Context, taskID int64) (GetChecklistProgressRow, error) {
+	row := q.db.QueryRowContext(ctx, getChecklistProgress, taskID)
+	var i GetChecklistProgressRow
+	err := row.Scan(&i.Total, &i.Completed)
+	return i, err
+}
+
 const listChecklistItemsByTask = `-- name: ListChecklistItemsByTask :many
-SELECT id, task_id, content, completed, position, created_at, updated_at FROM checklist_items
+SELECT id, task_id, content, completed, created_at, updated_at, rank FROM checklist_items
 WHERE task_id = ?
-ORDER BY position ASC, created_at ASC
+ORDER BY rank ASC, id ASC
 `
 
 func (q *Queries) ListChecklistItemsByTask(ctx context.Context, taskID int64) ([]ChecklistItem, error) {
@@ -89,9 +109,9 @@ func (q *Queries) ListChecklistItemsByTask(ctx context.Context, taskID int64) ([
 			&i.TaskID,
 			&i.Content,
 			&i.Completed,
-			&i.Position,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -112,7 +132,7 @@ SET
     completed = NOT completed,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, task_id, content, completed, position, created_at, updated_at
+RETURNING id, task_id, content, completed, created_at, updated_at, rank
 `
 
 func (q *Queries) ToggleChecklistItem(ctx context.Context, id int64) (ChecklistItem, error) {
@@ -123,9 +143,9 @@ func (q *Queries) ToggleChecklistItem(ctx context.Context, id int64) (ChecklistI
 		&i.TaskID,
 		&i.Content,
 		&i.Completed,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
@@ -136,7 +156,7 @@ SET
     content = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, task_id, content, completed, position, created_at, updated_at
+RETURNING id, task_id, content, completed, created_at, updated_at, rank
 `
 
 type UpdateChecklistItemParams struct {
@@ -152,27 +172,27 @@ func (q *Queries) UpdateChecklistItem(ctx context.Context, arg UpdateChecklistIt
 		&i.TaskID,
 		&i.Content,
 		&i.Completed,
-		&i.Position,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
-const updateChecklistItemPosition = `-- name: UpdateChecklistItemPosition :exec
+const updateChecklistItemRank = `-- name: UpdateChecklistItemRank :exec
 UPDATE checklist_items
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
 `
 
-type UpdateChecklistItemPositionParams struct {
-	Position int64 `json:"position"`
-	ID       int64 `json:"id"`
+type UpdateChecklistItemRankParams struct {
+	Rank string `json:"rank"`
+	ID   int64  `json:"id"`
 }
 
-func (q *Queries) UpdateChecklistItemPosition(ctx context.Context, arg UpdateChecklistItemPositionParams) error {
-	_, err := q.db.ExecContext(ctx, updateChecklistItemPosition, arg.Position, arg.ID)
+func (q *Queries) UpdateChecklistItemRank(ctx context.Context, arg UpdateChecklistItemRankParams) error {
+	_, err := q.db.ExecContext(ctx, updateChecklistItemRank, arg.Rank, arg.ID)
 	return err
 }
@@ -12,17 +12,17 @@ import (
 
 const createColumn = `-- name: CreateColumn :one
 INSERT INTO columns (
-    board_id, name, position, color, wip_limit
+    board_id, name, rank, color, wip_limit
 ) VALUES (
     ?, ?, ?, ?, ?
 )
-RETURNING id, board_id, name, position, color, wip_limit, created_at, updated_at
+RETURNING id, board_id, name, color, wip_limit, created_at, updated_at, rank
 `
 
 type CreateColumnParams struct {
 	BoardID  int64          `json:"board_id"`
 	Name     string         `json:"name"`
-	Position int64          `json:"position"`
+	Rank     string         `json:"rank"`
 	Color    sql.NullString `json:"color"`
 	WipLimit sql.NullInt64  `json:"wip_limit"`
 }
@@ -31,7 +31,7 @@ func (q *Queries) CreateColumn(ctx context.Context, arg CreateColumnParams) (Col
 	row := q.db.QueryRowContext(ctx, createColumn,
 		arg.BoardID,
 		arg.Name,
-		arg.Position,
+		arg.Rank,
 		arg.Color,
 		arg.WipLimit,
 	)
@@ -40,11 +40,11 @@ func (q *Queries) CreateColumn(ctx context.Context, arg CreateColumnParams) (Col
 		&i.ID,
 		&i.BoardID,
 		&i.Name,
-		&i.Position,
 		&i.Color,
 		&i.WipLimit,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
@@ -60,7 +60,7 @@ func (q *Queries) DeleteColumn(ctx context.Context, id int64) error {
 }
 
 const getColumn = `-- name: GetColumn :one
-SELECT id, board_id, name, position, color, wip_limit, created_at, updated_at FROM columns
+SELECT id, board_id, name, color, wip_limit, created_at, updated_at, rank FROM columns
 WHERE id = ? LIMIT 1
 `
 
@@ -71,19 +71,32 @@ func (q *Queries) GetColumn(ctx context.Context, id int64) (Column, error) {
 		&i.ID,
 		&i.BoardID,
 		&i.Name,
-		&i.Position,
 		&i.Color,
 		&i.WipLimit,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
+const getProjectIDByColumn = `-- name: GetProjectIDByColumn :one
+SELECT b.project_id FROM columns c
+JOIN boards b ON c.board_id = b.id
+WHERE c.id = ? LIMIT 1
+`
+
+func (q *Queries) GetProjectIDByColumn(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getProjectIDByColumn, id)
+	var project_id int64
+	err := row.Scan(&project_id)
+	return project_id, err
+}
+
 const listColumnsByBoard = `-- name: ListColumnsByBoard :many
-SELECT id, board_id, name, position, color, wip_limit, created_at, updated_at FROM columns
+SELECT id, board_id, name, color, wip_limit, created_at, updated_at, rank FROM columns
 WHERE board_id = ?
-ORDER BY position ASC, created_at ASC
+ORDER BY rank ASC, id ASC
 `
 
 func (q *Queries) ListColumnsByBoard(ctx context.Context, boardID int64) ([]Column, error) {
@@ -99,11 +112,11 @@ func (q *Queries) ListColumnsByBoard(ctx context.Context, boardID int64) ([]Colu
 			&i.ID,
 			&i.BoardID,
 			&i.Name,
-			&i.Position,
 			&i.Color,
 			&i.WipLimit,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -126,7 +139,7 @@ SET
     wip_limit = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, board_id, name, position, color, wip_limit, created_at, updated_at
+RETURNING id, board_id, name, color, wip_limit, created_at, updated_at, rank
 `
 
 type UpdateColumnParams struct {
@@ -148,29 +161,29 @@ func (q *Queries) UpdateColumn(ctx context.Context, arg UpdateColumnParams) (Col
 		&i.ID,
 		&i.BoardID,
 		&i.Name,
-		&i.Position,
 		&i.Color,
 		&i.WipLimit,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
-const updateColumnPosition = `-- name: UpdateColumnPosition :exec
+const updateColumnRank = `-- name: UpdateColumnRank :exec
 UPDATE columns
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
 `
 
-type UpdateColumnPositionParams struct {
-	Position int64 `json:"position"`
-	ID       int64 `json:"id"`
+type UpdateColumnRankParams struct {
+	Rank string `json:"rank"`
+	ID   int64  `json:"id"`
 }
 
-func (q *Queries) UpdateColumnPosition(ctx context.Context, arg UpdateColumnPositionParams) error {
-	_, err := q.db.ExecContext(ctx, updateColumnPosition, arg.Position, arg.ID)
+func (q *Queries) UpdateColumnRank(ctx context.Context, arg UpdateColumnRankParams) error {
+	_, err := q.db.ExecContext(ctx, updateColumnRank, arg.Rank, arg.ID)
 	return err
 }
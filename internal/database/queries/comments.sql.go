@@ -10,6 +10,18 @@ import (
 	"database/sql"
 )
 
+const countCommentsByTask = `-- name: CountCommentsByTask :one
+SELECT COUNT(*) FROM comments
+WHERE task_id = ?
+`
+
+func (q *Queries) CountCommentsByTask(ctx context.Context, taskID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countCommentsByTask, taskID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createComment = `-- name: CreateComment :one
 INSERT INTO comments (
     task_id, user_id, content
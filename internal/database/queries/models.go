@@ -19,13 +19,25 @@ type Activity struct {
 	CreatedAt sql.NullTime   `json:"created_at"`
 }
 
+type ApiToken struct {
+	ID         int64        `json:"id"`
+	UserID     int64        `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	TokenHash  string       `json:"token_hash"`
+	Scopes     string       `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+}
+
 type Board struct {
 	ID        int64        `json:"id"`
 	ProjectID int64        `json:"project_id"`
 	Name      string       `json:"name"`
-	Position  int64        `json:"position"`
 	CreatedAt sql.NullTime `json:"created_at"`
 	UpdatedAt sql.NullTime `json:"updated_at"`
+	Rank      string       `json:"rank"`
 }
 
 type ChecklistItem struct {
@@ -33,20 +45,20 @@ type ChecklistItem struct {
 	TaskID    int64        `json:"task_id"`
 	Content   string       `json:"content"`
 	Completed sql.NullBool `json:"completed"`
-	Position  int64        `json:"position"`
 	CreatedAt sql.NullTime `json:"created_at"`
 	UpdatedAt sql.NullTime `json:"updated_at"`
+	Rank      string       `json:"rank"`
 }
 
 type Column struct {
 	ID        int64          `json:"id"`
 	BoardID   int64          `json:"board_id"`
 	Name      string         `json:"name"`
-	Position  int64          `json:"position"`
 	Color     sql.NullString `json:"color"`
 	WipLimit  sql.NullInt64  `json:"wip_limit"`
 	CreatedAt sql.NullTime   `json:"created_at"`
 	UpdatedAt sql.NullTime   `json:"updated_at"`
+	Rank      string         `json:"rank"`
 }
 
 type Comment struct {
@@ -58,6 +70,21 @@ type Comment struct {
 	UpdatedAt sql.NullTime `json:"updated_at"`
 }
 
+type EmailPreference struct {
+	UserID    int64        `json:"user_id"`
+	Type      string       `json:"type"`
+	Delivery  string       `json:"delivery"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+type EmailVerificationToken struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
 type Label struct {
 	ID        int64        `json:"id"`
 	ProjectID int64        `json:"project_id"`
@@ -66,6 +93,51 @@ type Label struct {
 	CreatedAt sql.NullTime `json:"created_at"`
 }
 
+type LoginChallenge struct {
+	TokenHash string       `json:"token_hash"`
+	UserID    int64        `json:"user_id"`
+	Attempts  int64        `json:"attempts"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	AttemptKey   string       `json:"attempt_key"`
+	Failures     int64        `json:"failures"`
+	BlockedUntil sql.NullTime `json:"blocked_until"`
+	LastFailedAt time.Time    `json:"last_failed_at"`
+}
+
+type Mention struct {
+	ID        int64         `json:"id"`
+	TaskID    int64         `json:"task_id"`
+	CommentID sql.NullInt64 `json:"comment_id"`
+	UserID    int64         `json:"user_id"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+}
+
+type Notification struct {
+	ID        int64         `json:"id"`
+	UserID    int64         `json:"user_id"`
+	ActorID   sql.NullInt64 `json:"actor_id"`
+	ProjectID sql.NullInt64 `json:"project_id"`
+	TaskID    sql.NullInt64 `json:"task_id"`
+	CommentID sql.NullInt64 `json:"comment_id"`
+	Type      string        `json:"type"`
+	ReadAt    sql.NullTime  `json:"read_at"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	EmailedAt sql.NullTime  `json:"emailed_at"`
+}
+
+type PasswordResetToken struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
 type Project struct {
 	ID          int64          `json:"id"`
 	OwnerID     int64          `json:"owner_id"`
@@ -84,25 +156,55 @@ type ProjectMember struct {
 	JoinedAt  sql.NullTime `json:"joined_at"`
 }
 
-type Session struct {
-	ID        string       `json:"id"`
+type RecoveryCode struct {
+	ID        int64        `json:"id"`
 	UserID    int64        `json:"user_id"`
-	ExpiresAt time.Time    `json:"expires_at"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
 	CreatedAt sql.NullTime `json:"created_at"`
 }
 
+type SecurityEvent struct {
+	ID        int64         `json:"id"`
+	UserID    sql.NullInt64 `json:"user_id"`
+	Event     string        `json:"event"`
+	IpAddress string        `json:"ip_address"`
+	Details   string        `json:"details"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+}
+
+type Session struct {
+	ID         int64        `json:"id"`
+	TokenHash  string       `json:"token_hash"`
+	UserID     int64        `json:"user_id"`
+	UserAgent  string       `json:"user_agent"`
+	IpAddress  string       `json:"ip_address"`
+	LastSeenAt time.Time    `json:"last_seen_at"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+}
+
+type SsoLoginState struct {
+	StateHash    string        `json:"state_hash"`
+	Nonce        string        `json:"nonce"`
+	CodeVerifier string        `json:"code_verifier"`
+	LinkUserID   sql.NullInt64 `json:"link_user_id"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	CreatedAt    sql.NullTime  `json:"created_at"`
+}
+
 type Task struct {
 	ID          int64          `json:"id"`
 	ColumnID    int64          `json:"column_id"`
 	CreatedBy   int64          `json:"created_by"`
 	Title       string         `json:"title"`
 	Description sql.NullString `json:"description"`
-	Position    int64          `json:"position"`
 	Priority    sql.NullString `json:"priority"`
 	DueDate     sql.NullTime   `json:"due_date"`
 	CompletedAt sql.NullTime   `json:"completed_at"`
 	CreatedAt   sql.NullTime   `json:"created_at"`
 	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	Rank        string         `json:"rank"`
 }
 
 type TaskAssignee struct {
@@ -116,12 +218,36 @@ type TaskLabel struct {
 	LabelID int64 `json:"label_id"`
 }
 
+type TaskWatcher struct {
+	TaskID    int64        `json:"task_id"`
+	UserID    int64        `json:"user_id"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
 type User struct {
-	ID           int64          `json:"id"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Name         string         `json:"name"`
-	AvatarUrl    sql.NullString `json:"avatar_url"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID            int64          `json:"id"`
+	Email         string         `json:"email"`
+	PasswordHash  string         `json:"password_hash"`
+	Name          string         `json:"name"`
+	AvatarUrl     sql.NullString `json:"avatar_url"`
+	CreatedAt     sql.NullTime   `json:"created_at"`
+	UpdatedAt     sql.NullTime   `json:"updated_at"`
+	EmailVerified bool           `json:"email_verified"`
+}
+
+type UserIdentity struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	Issuer    string       `json:"issuer"`
+	Subject   string       `json:"subject"`
+	Email     string       `json:"email"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
+type UserTotp struct {
+	UserID       int64        `json:"user_id"`
+	Secret       string       `json:"secret"`
+	ConfirmedAt  sql.NullTime `json:"confirmed_at"`
+	LastUsedStep int64        `json:"last_used_step"`
+	CreatedAt    sql.NullTime `json:"created_at"`
 }
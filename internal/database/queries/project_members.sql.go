@@ -165,3 +165,23 @@ func (q *Queries) UpdateProjectMemberRole(ctx context.Context, arg UpdateProject
 	_, err := q.db.ExecContext(ctx, updateProjectMemberRole, arg.Role, arg.ProjectID, arg.UserID)
 	return err
 }
+
+const upsertProjectMember = `-- name: UpsertProjectMember :exec
+INSERT INTO project_members (
+    project_id, user_id, role
+) VALUES (
+    ?, ?, ?
+)
+ON CONFLICT (project_id, user_id) DO UPDATE SET role = excluded.role
+`
+
+type UpsertProjectMemberParams struct {
+	ProjectID int64  `json:"project_id"`
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"`
+}
+
+func (q *Queries) UpsertProjectMember(ctx context.Context, arg UpsertProjectMemberParams) error {
+	_, err := q.db.ExecContext(ctx, upsertProjectMember, arg.ProjectID, arg.UserID, arg.Role)
+	return err
+}
@@ -145,6 +145,83 @@ func (q *Queries) GetProjectWithOwner(ctx context.Context, id int64) (GetProject
 	return i, err
 }
 
+const listArchivedProjectsByMember = `-- name: ListArchivedProjectsByMember :many
+SELECT p.id, p.owner_id, p.name, p.description, p.color, p.archived, p.created_at, p.updated_at FROM projects p
+JOIN project_members pm ON p.id = pm.project_id
+WHERE pm.user_id = ? AND p.archived = TRUE
+ORDER BY p.updated_at DESC
+`
+
+func (q *Queries) ListArchivedProjectsByMember(ctx context.Context, userID int64) ([]Project, error) {
+	rows, err := q.db.QueryContext(ctx, listArchivedProjectsByMember, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Name,
+			&i.Description,
+			&i.Color,
+			&i.Archived,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listArchivedProjectsByOwner = `-- name: ListArchivedProjectsByOwner :many
+SELECT id, owner_id, name, description, color, archived, created_at, updated_at FROM projects
+WHERE owner_id = ? AND archived = TRUE
+ORDER BY updated_at DESC
+`
+
+func (q *Queries) ListArchivedProjectsByOwner(ctx context.Context, ownerID int64) ([]Project, error) {
+	rows, err := q.db.QueryContext(ctx, listArchivedProjectsByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Project
+	for rows.Next() {
+		var i Project
+		if err := rows.Scan(
+			&i.ID,
+			&i.OwnerID,
+			&i.Name,
+			&i.Description,
+			&i.Color,
+			&i.Archived,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listProjectsByMember = `-- name: ListProjectsByMember :many
 SELECT p.id, p.owner_id, p.name, p.description, p.color, p.archived, p.created_at, p.updated_at FROM projects p
 JOIN project_members pm ON p.id = pm.project_id
@@ -222,6 +299,24 @@ func (q *Queries) ListProjectsByOwner(ctx context.Context, ownerID int64) ([]Pro
 	return items, nil
 }
 
+const transferProjectOwnership = `-- name: TransferProjectOwnership :exec
+UPDATE projects
+SET 
+    owner_id = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`
+
+type TransferProjectOwnershipParams struct {
+	OwnerID int64 `json:"owner_id"`
+	ID      int64 `json:"id"`
+}
+
+func (q *Queries) TransferProjectOwnership(ctx context.Context, arg TransferProjectOwnershipParams) error {
+	_, err := q.db.ExecContext(ctx, transferProjectOwnership, arg.OwnerID, arg.ID)
+	return err
+}
+
 const unarchiveProject = `-- name: UnarchiveProject :exec
 UPDATE projects
 SET 
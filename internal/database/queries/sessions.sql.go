@@ -12,25 +12,39 @@ import (
 
 const createSession = `-- name: CreateSession :one
 INSERT INTO sessions (
-    id, user_id, expires_at
+    token_hash, user_id, user_agent, ip_address, last_seen_at, expires_at
 ) VALUES (
-    ?, ?, ?
+    ?, ?, ?, ?, ?, ?
 )
-RETURNING id, user_id, expires_at, created_at
+RETURNING id, token_hash, user_id, user_agent, ip_address, last_seen_at, expires_at, created_at
 `
 
 type CreateSessionParams struct {
-	ID        string    `json:"id"`
-	UserID    int64     `json:"user_id"`
-	ExpiresAt time.Time `json:"expires_at"`
+	TokenHash  string    `json:"token_hash"`
+	UserID     int64     `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IpAddress  string    `json:"ip_address"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
-	row := q.db.QueryRowContext(ctx, createSession, arg.ID, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.TokenHash,
+		arg.UserID,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.LastSeenAt,
+		arg.ExpiresAt,
+	)
 	var i Session
 	err := row.Scan(
 		&i.ID,
+		&i.TokenHash,
 		&i.UserID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastSeenAt,
 		&i.ExpiresAt,
 		&i.CreatedAt,
 	)
@@ -39,11 +53,27 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 
 const deleteExpiredSessions = `-- name: DeleteExpiredSessions :exec
 DELETE FROM sessions
-WHERE expires_at < CURRENT_TIMESTAMP
+WHERE expires_at < CURRENT_TIMESTAMP OR last_seen_at < ?
 `
 
-func (q *Queries) DeleteExpiredSessions(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteExpiredSessions)
+// Deletes sessions past their absolute expiry or idle since idle_since
+func (q *Queries) DeleteExpiredSessions(ctx context.Context, idleSince time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredSessions, idleSince)
+	return err
+}
+
+const deleteOtherUserSessions = `-- name: DeleteOtherUserSessions :exec
+DELETE FROM sessions
+WHERE user_id = ? AND id != ?
+`
+
+type DeleteOtherUserSessionsParams struct {
+	UserID int64 `json:"user_id"`
+	ID     int64 `json:"id"`
+}
+
+func (q *Queries) DeleteOtherUserSessions(ctx context.Context, arg DeleteOtherUserSessionsParams) error {
+	_, err := q.db.ExecContext(ctx, deleteOtherUserSessions, arg.UserID, arg.ID)
 	return err
 }
 
@@ -52,11 +82,29 @@ DELETE FROM sessions
 WHERE id = ?
 `
 
-func (q *Queries) DeleteSession(ctx context.Context, id string) error {
+func (q *Queries) DeleteSession(ctx context.Context, id int64) error {
 	_, err := q.db.ExecContext(ctx, deleteSession, id)
 	return err
 }
 
+const deleteUserSession = `-- name: DeleteUserSession :execrows
+DELETE FROM sessions
+WHERE id = ? AND user_id = ?
+`
+
+type DeleteUserSessionParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteUserSession(ctx context.Context, arg DeleteUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUserSessions = `-- name: DeleteUserSessions :exec
 DELETE FROM sessions
 WHERE user_id = ?
@@ -67,19 +115,77 @@ func (q *Queries) DeleteUserSessions(ctx context.Context, userID int64) error {
 	return err
 }
 
-const getSession = `-- name: GetSession :one
-SELECT id, user_id, expires_at, created_at FROM sessions
-WHERE id = ? LIMIT 1
+const getSessionByTokenHash = `-- name: GetSessionByTokenHash :one
+SELECT id, token_hash, user_id, user_agent, ip_address, last_seen_at, expires_at, created_at FROM sessions
+WHERE token_hash = ? LIMIT 1
 `
 
-func (q *Queries) GetSession(ctx context.Context, id string) (Session, error) {
-	row := q.db.QueryRowContext(ctx, getSession, id)
+func (q *Queries) GetSessionByTokenHash(ctx context.Context, tokenHash string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, getSessionByTokenHash, tokenHash)
 	var i Session
 	err := row.Scan(
 		&i.ID,
+		&i.TokenHash,
 		&i.UserID,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastSeenAt,
 		&i.ExpiresAt,
 		&i.CreatedAt,
 	)
 	return i, err
 }
+
+const listUserSessions = `-- name: ListUserSessions :many
+SELECT id, token_hash, user_id, user_agent, ip_address, last_seen_at, expires_at, created_at FROM sessions
+WHERE user_id = ?
+ORDER BY last_seen_at DESC, id DESC
+`
+
+func (q *Queries) ListUserSessions(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := q.db.QueryContext(ctx, listUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Session
+	for rows.Next() {
+		var i Session
+		if err := rows.Scan(
+			&i.ID,
+			&i.TokenHash,
+			&i.UserID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastSeenAt,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const touchSession = `-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = ?
+WHERE id = ?
+`
+
+type TouchSessionParams struct {
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ID         int64     `json:"id"`
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.ExecContext(ctx, touchSession, arg.LastSeenAt, arg.ID)
+	return err
+}
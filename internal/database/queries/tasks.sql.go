@@ -23,13 +23,25 @@ func (q *Queries) CompleteTask(ctx context.Context, id int64) error {
 	return err
 }
 
+const countOpenTasksByColumn = `-- name: CountOpenTasksByColumn :one
+SELECT COUNT(*) FROM tasks
+WHERE column_id = ? AND completed_at IS NULL
+`
+
+func (q *Queries) CountOpenTasksByColumn(ctx context.Context, columnID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOpenTasksByColumn, columnID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createTask = `-- name: CreateTask :one
 INSERT INTO tasks (
-    column_id, created_by, title, description, position, priority, due_date
+    column_id, created_by, title, description, rank, priority, due_date
 ) VALUES (
     ?, ?, ?, ?, ?, ?, ?
 )
-RETURNING id, column_id, created_by, title, description, position, priority, due_date, completed_at, created_at, updated_at
+RETURNING id, column_id, created_by, title, description, priority, due_date, completed_at, created_at, updated_at, rank
 `
 
 type CreateTaskParams struct {
@@ -37,7 +49,7 @@ type CreateTaskParams struct {
 	CreatedBy   int64          `json:"created_by"`
 	Title       string         `json:"title"`
 	Description sql.NullString `json:"description"`
-	Position    int64          `json:"position"`
+	Rank        string         `json:"rank"`
 	Priority    sql.NullString `json:"priority"`
 	DueDate     sql.NullTime   `json:"due_date"`
 }
@@ -48,7 +60,7 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		arg.CreatedBy,
 		arg.Title,
 		arg.Description,
-		arg.Position,
+		arg.Rank,
 		arg.Priority,
 		arg.DueDate,
 	)
@@ -59,12 +71,12 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Task, e
 		&i.CreatedBy,
 		&i.Title,
 		&i.Description,
-		&i.Position,
 		&i.Priority,
 		&i.DueDate,
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
@@ -79,8 +91,49 @@ func (q *Queries) DeleteTask(ctx context.Context, id int64) error {
 	return err
 }
 
+const getBoardIDByTask = `-- name: GetBoardIDByTask :one
+SELECT c.board_id FROM tasks t
+JOIN columns c ON t.column_id = c.id
+WHERE t.id = ? LIMIT 1
+`
+
+func (q *Queries) GetBoardIDByTask(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getBoardIDByTask, id)
+	var board_id int64
+	err := row.Scan(&board_id)
+	return board_id, err
+}
+
+const getLastTaskRankByColumn = `-- name: GetLastTaskRankByColumn :one
+SELECT rank FROM tasks
+WHERE column_id = ?
+ORDER BY rank DESC, id DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastTaskRankByColumn(ctx context.Context, columnID int64) (string, error) {
+	row := q.db.QueryRowContext(ctx, getLastTaskRankByColumn, columnID)
+	var rank string
+	err := row.Scan(&rank)
+	return rank, err
+}
+
+const getProjectIDByTask = `-- name: GetProjectIDByTask :one
+SELECT b.project_id FROM tasks t
+JOIN columns c ON t.column_id = c.id
+JOIN boards b ON c.board_id = b.id
+WHERE t.id = ? LIMIT 1
+`
+
+func (q *Queries) GetProjectIDByTask(ctx context.Context, id int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getProjectIDByTask, id)
+	var project_id int64
+	err := row.Scan(&project_id)
+	return project_id, err
+}
+
 const getTask = `-- name: GetTask :one
-SELECT id, column_id, created_by, title, description, position, priority, due_date, completed_at, created_at, updated_at FROM tasks
+SELECT id, column_id, created_by, title, description, priority, due_date, completed_at, created_at, updated_at, rank FROM tasks
 WHERE id = ? LIMIT 1
 `
 
@@ -93,19 +146,19 @@ func (q *Queries) GetTask(ctx context.Context, id int64) (Task, error) {
 		&i.CreatedBy,
 		&i.Title,
 		&i.Description,
-		&i.Position,
 		&i.Priority,
 		&i.DueDate,
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
 const getTaskWithCreator = `-- name: GetTaskWithCreator :one
 SELECT 
-    t.id, t.column_id, t.created_by, t.title, t.description, t.position, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at,
+    t.id, t.column_id, t.created_by, t.title, t.description, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at, t.rank,
     u.id as creator_id,
     u.name as creator_name,
     u.email as creator_email,
@@ -121,12 +174,12 @@ type GetTaskWithCreatorRow struct {
 	CreatedBy        int64          `json:"created_by"`
 	Title            string         `json:"title"`
 	Description      sql.NullString `json:"description"`
-	Position         int64          `json:"position"`
 	Priority         sql.NullString `json:"priority"`
 	DueDate          sql.NullTime   `json:"due_date"`
 	CompletedAt      sql.NullTime   `json:"completed_at"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	Rank             string         `json:"rank"`
 	CreatorID        int64          `json:"creator_id"`
 	CreatorName      string         `json:"creator_name"`
 	CreatorEmail     string         `json:"creator_email"`
@@ -142,12 +195,12 @@ func (q *Queries) GetTaskWithCreator(ctx context.Context, id int64) (GetTaskWith
 		&i.CreatedBy,
 		&i.Title,
 		&i.Description,
-		&i.Position,
 		&i.Priority,
 		&i.DueDate,
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 		&i.CreatorID,
 		&i.CreatorName,
 		&i.CreatorEmail,
@@ -156,10 +209,86 @@ func (q *Queries) GetTaskWithCreator(ctx context.Context, id int64) (GetTaskWith
 	return i, err
 }
 
+const listOpenTasksByBoard = `-- name: ListOpenTasksByBoard :many
+SELECT t.id, t.column_id, t.created_by, t.title, t.description, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at, t.rank FROM tasks t
+JOIN columns c ON t.column_id = c.id
+WHERE c.board_id = ? AND t.completed_at IS NULL
+ORDER BY t.column_id ASC, t.rank ASC, t.id ASC
+`
+
+func (q *Queries) ListOpenTasksByBoard(ctx context.Context, boardID int64) ([]Task, error) {
+	rows, err := q.db.QueryContext(ctx, listOpenTasksByBoard, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Task
+	for rows.Next() {
+		var i Task
+		if err := rows.Scan(
+			&i.ID,
+			&i.ColumnID,
+			&i.CreatedBy,
+			&i.Title,
+			&i.Description,
+			&i.Priority,
+			&i.DueDate,
+			&i.CompletedAt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaskRanksByColumn = `-- name: ListTaskRanksByColumn :many
+SELECT id, rank FROM tasks
+WHERE column_id = ?
+ORDER BY rank ASC, id ASC
+`
+
+type ListTaskRanksByColumnRow struct {
+	ID   int64  `json:"id"`
+	Rank string `json:"rank"`
+}
+
+func (q *Queries) ListTaskRanksByColumn(ctx context.Context, columnID int64) ([]ListTaskRanksByColumnRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTaskRanksByColumn, columnID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTaskRanksByColumnRow
+	for rows.Next() {
+		var i ListTaskRanksByColumnRow
+		if err := rows.Scan(&i.ID, &i.Rank); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTasksByColumn = `-- name: ListTasksByColumn :many
-SELECT id, column_id, created_by, title, description, position, priority, due_date, completed_at, created_at, updated_at FROM tasks
+SELECT id, column_id, created_by, title, description, priority, due_date, completed_at, created_at, updated_at, rank FROM tasks
 WHERE column_id = ? AND completed_at IS NULL
-ORDER BY position ASC, created_at ASC
+ORDER BY rank ASC, id ASC
 `
 
 func (q *Queries) ListTasksByColumn(ctx context.Context, columnID int64) ([]Task, error) {
@@ -177,12 +306,12 @@ func (q *Queries) ListTasksByColumn(ctx context.Context, columnID int64) ([]Task
 			&i.CreatedBy,
 			&i.Title,
 			&i.Description,
-			&i.Position,
 			&i.Priority,
 			&i.DueDate,
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -198,7 +327,7 @@ func (q *Queries) ListTasksByColumn(ctx context.Context, columnID int64) ([]Task
 }
 
 const listTasksByProject = `-- name: ListTasksByProject :many
-SELECT t.id, t.column_id, t.created_by, t.title, t.description, t.position, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at FROM tasks t
+SELECT t.id, t.column_id, t.created_by, t.title, t.description, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at, t.rank FROM tasks t
 JOIN columns c ON t.column_id = c.id
 JOIN boards b ON c.board_id = b.id
 WHERE b.project_id = ? AND t.completed_at IS NULL
@@ -220,12 +349,12 @@ func (q *Queries) ListTasksByProject(ctx context.Context, projectID int64) ([]Ta
 			&i.CreatedBy,
 			&i.Title,
 			&i.Description,
-			&i.Position,
 			&i.Priority,
 			&i.DueDate,
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -241,7 +370,7 @@ func (q *Queries) ListTasksByProject(ctx context.Context, projectID int64) ([]Ta
 }
 
 const listTasksByUser = `-- name: ListTasksByUser :many
-SELECT t.id, t.column_id, t.created_by, t.title, t.description, t.position, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at FROM tasks t
+SELECT t.id, t.column_id, t.created_by, t.title, t.description, t.priority, t.due_date, t.completed_at, t.created_at, t.updated_at, t.rank FROM tasks t
 JOIN task_assignees ta ON t.id = ta.task_id
 WHERE ta.user_id = ? AND t.completed_at IS NULL
 ORDER BY t.due_date ASC, t.created_at DESC
@@ -262,12 +391,12 @@ func (q *Queries) ListTasksByUser(ctx context.Context, userID int64) ([]Task, er
 			&i.CreatedBy,
 			&i.Title,
 			&i.Description,
-			&i.Position,
 			&i.Priority,
 			&i.DueDate,
 			&i.CompletedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -286,20 +415,20 @@ const moveTask = `-- name: MoveTask :one
 UPDATE tasks
 SET 
     column_id = ?,
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, column_id, created_by, title, description, position, priority, due_date, completed_at, created_at, updated_at
+RETURNING id, column_id, created_by, title, description, priority, due_date, completed_at, created_at, updated_at, rank
 `
 
 type MoveTaskParams struct {
-	ColumnID int64 `json:"column_id"`
-	Position int64 `json:"position"`
-	ID       int64 `json:"id"`
+	ColumnID int64  `json:"column_id"`
+	Rank     string `json:"rank"`
+	ID       int64  `json:"id"`
 }
 
 func (q *Queries) MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error) {
-	row := q.db.QueryRowContext(ctx, moveTask, arg.ColumnID, arg.Position, arg.ID)
+	row := q.db.QueryRowContext(ctx, moveTask, arg.ColumnID, arg.Rank, arg.ID)
 	var i Task
 	err := row.Scan(
 		&i.ID,
@@ -307,12 +436,12 @@ func (q *Queries) MoveTask(ctx context.Context, arg MoveTaskParams) (Task, error
 		&i.CreatedBy,
 		&i.Title,
 		&i.Description,
-		&i.Position,
 		&i.Priority,
 		&i.DueDate,
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
@@ -339,7 +468,7 @@ SET
     due_date = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, column_id, created_by, title, description, position, priority, due_date, completed_at, created_at, updated_at
+RETURNING id, column_id, created_by, title, description, priority, due_date, completed_at, created_at, updated_at, rank
 `
 
 type UpdateTaskParams struct {
@@ -365,30 +494,30 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (Task, e
 		&i.CreatedBy,
 		&i.Title,
 		&i.Description,
-		&i.Position,
 		&i.Priority,
 		&i.DueDate,
 		&i.CompletedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Rank,
 	)
 	return i, err
 }
 
-const updateTaskPosition = `-- name: UpdateTaskPosition :exec
+const updateTaskRank = `-- name: UpdateTaskRank :exec
 UPDATE tasks
 SET 
-    position = ?,
+    rank = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
 `
 
-type UpdateTaskPositionParams struct {
-	Position int64 `json:"position"`
-	ID       int64 `json:"id"`
+type UpdateTaskRankParams struct {
+	Rank string `json:"rank"`
+	ID   int64  `json:"id"`
 }
 
-func (q *Queries) UpdateTaskPosition(ctx context.Context, arg UpdateTaskPositionParams) error {
-	_, err := q.db.ExecContext(ctx, updateTaskPosition, arg.Position, arg.ID)
+func (q *Queries) UpdateTaskRank(ctx context.Context, arg UpdateTaskRankParams) error {
+	_, err := q.db.ExecContext(ctx, updateTaskRank, arg.Rank, arg.ID)
 	return err
 }
@@ -16,7 +16,7 @@ INSERT INTO users (
 ) VALUES (
     ?, ?, ?, ?
 )
-RETURNING id, email, password_hash, name, avatar_url, created_at, updated_at
+RETURNING id, email, password_hash, name, avatar_url, created_at, updated_at, email_verified
 `
 
 type CreateUserParams struct {
@@ -42,12 +42,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.AvatarUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, email, password_hash, name, avatar_url, created_at, updated_at FROM users
+SELECT id, email, password_hash, name, avatar_url, created_at, updated_at, email_verified FROM users
 WHERE id = ? LIMIT 1
 `
 
@@ -62,12 +63,13 @@ func (q *Queries) GetUser(ctx context.Context, id int64) (User, error) {
 		&i.AvatarUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, name, avatar_url, created_at, updated_at FROM users
+SELECT id, email, password_hash, name, avatar_url, created_at, updated_at, email_verified FROM users
 WHERE email = ? LIMIT 1
 `
 
@@ -82,6 +84,32 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.AvatarUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+	)
+	return i, err
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+UPDATE users
+SET
+    email_verified = TRUE,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+RETURNING id, email, password_hash, name, avatar_url, created_at, updated_at, email_verified
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRowContext(ctx, markUserEmailVerified, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.AvatarUrl,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
 	)
 	return i, err
 }
@@ -93,7 +121,7 @@ SET
     avatar_url = ?,
     updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
-RETURNING id, email, password_hash, name, avatar_url, created_at, updated_at
+RETURNING id, email, password_hash, name, avatar_url, created_at, updated_at, email_verified
 `
 
 type UpdateUserParams struct {
@@ -113,6 +141,7 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.AvatarUrl,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
 	)
 	return i, err
 }
@@ -0,0 +1,320 @@
+package events
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Board event types
+const (
+	BoardUpdated = "board.updated"
+	BoardMoved   = "board.moved"
+	BoardDeleted = "board.deleted"
+
+	ColumnCreated = "column.created"
+	ColumnUpdated = "column.updated"
+	ColumnMoved   = "column.moved"
+	ColumnDeleted = "column.deleted"
+
+	TaskCreated      = "task.created"
+	TaskUpdated      = "task.updated"
+	TaskMoved        = "task.moved"
+	TaskCompleted    = "task.completed"
+	TaskUncompleted  = "task.uncompleted"
+	TaskDeleted      = "task.deleted"
+	TaskAssigned     = "task.assigned"
+	TaskUnassigned   = "task.unassigned"
+	TaskLabelAdded   = "task.label_added"
+	TaskLabelRemoved = "task.label_removed"
+
+	CommentCreated = "comment.created"
+	CommentUpdated = "comment.updated"
+	CommentDeleted = "comment.deleted"
+)
+
+// User event types
+const (
+	NotificationCreated = "notification.created"
+)
+
+const (
+	// historySize is how many recent events of a board are kept so that
+	// reconnecting clients can catch up
+	historySize = 256
+
+	// historyTTL is how long the history of a board nobody watches is kept
+	historyTTL = 10 * time.Minute
+
+	// subscriberBuffer is how many events may queue up for a subscriber
+	// before it is considered too slow and dropped
+	subscriberBuffer = 64
+)
+
+// Event is a committed change to a board
+type Event struct {
+	ID      string
+	BoardID int64
+	Type    string
+	UserID  int64
+	Data    interface{}
+
+	seq uint64
+}
+
+// Refs names the entities an event refers to when there is no row left to
+// send, e.g. after a delete. Zero IDs are unset.
+type Refs struct {
+	BoardID   int64
+	ColumnID  int64
+	TaskID    int64
+	UserID    int64
+	LabelID   int64
+	CommentID int64
+}
+
+// Broker fans out board events to subscribers within the process. Event
+// IDs are unique to the process, so IDs from before a restart are never
+// mistaken for recent ones. It also delivers events addressed to a single
+// user, such as notifications, to that user's open connections.
+type Broker struct {
+	epoch string
+
+	mu     sync.Mutex
+	seq    uint64
+	boards map[int64]*boardStream
+	users  map[int64]map[*Subscription]struct{}
+	pruned time.Time
+}
+
+// boardStream is the recent history and the subscribers of one board
+type boardStream struct {
+	history     []Event
+	evicted     uint64
+	updated     time.Time
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the events of one board, or those addressed to
+// one user
+type Subscription struct {
+	broker  *Broker
+	boardID int64
+	userID  int64
+	events  chan Event
+}
+
+// NewBroker creates a new event broker
+func NewBroker() *Broker {
+	return &Broker{
+		epoch:  strconv.FormatInt(time.Now().UnixNano(), 36),
+		boards: make(map[int64]*boardStream),
+		users:  make(map[int64]map[*Subscription]struct{}),
+		pruned: time.Now(),
+	}
+}
+
+// Publish sends an event to everyone watching a board. It must only be
+// called once the change is committed.
+func (b *Broker) Publish(boardID int64, eventType string, userID int64, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.stream(boardID)
+
+	b.seq++
+	event := Event{
+		ID:      b.epoch + "-" + strconv.FormatUint(b.seq, 10),
+		BoardID: boardID,
+		Type:    eventType,
+		UserID:  userID,
+		Data:    data,
+		seq:     b.seq,
+	}
+
+	if len(stream.history) == historySize {
+		stream.evicted = stream.history[0].seq
+		stream.history = append(stream.history[:0], stream.history[1:]...)
+	}
+	stream.history = append(stream.history, event)
+	stream.updated = time.Now()
+
+	for sub := range stream.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			// Drop subscribers that fall behind; they resume from their
+			// last event when they reconnect
+			delete(stream.subscribers, sub)
+			close(sub.events)
+		}
+	}
+
+	b.prune()
+}
+
+// Subscribe starts watching a board. If lastEventID is set, the events
+// published after it are returned to replay first. The returned bool is
+// false if those events are no longer known, in which case the client has
+// to reload the board.
+func (b *Broker) Subscribe(boardID int64, lastEventID string) (*Subscription, []Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream := b.stream(boardID)
+	sub := &Subscription{
+		broker:  b,
+		boardID: boardID,
+		events:  make(chan Event, subscriberBuffer),
+	}
+	stream.subscribers[sub] = struct{}{}
+
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	seq, ok := b.parseID(lastEventID)
+	if !ok || seq < stream.evicted {
+		return sub, nil, false
+	}
+
+	var replay []Event
+	for _, e := range stream.history {
+		if e.seq > seq {
+			replay = append(replay, e)
+		}
+	}
+	return sub, replay, true
+}
+
+// PublishToUser sends an event to the open connections of one user. Unlike
+// board events these are not kept for replay; the data they carry is
+// stored elsewhere and reloaded by reconnecting clients.
+func (b *Broker) PublishToUser(recipientID int64, eventType string, userID int64, data interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := Event{
+		ID:     b.epoch + "-" + strconv.FormatUint(b.seq, 10),
+		Type:   eventType,
+		UserID: userID,
+		Data:   data,
+		seq:    b.seq,
+	}
+
+	subscribers := b.users[recipientID]
+	for sub := range subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(subscribers, sub)
+			close(sub.events)
+		}
+	}
+	if len(subscribers) == 0 {
+		delete(b.users, recipientID)
+	}
+}
+
+// SubscribeUser starts receiving the events addressed to a user
+func (b *Broker) SubscribeUser(userID int64) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &Subscription{
+		broker: b,
+		userID: userID,
+		events: make(chan Event, subscriberBuffer),
+	}
+	subscribers, ok := b.users[userID]
+	if !ok {
+		subscribers = make(map[*Subscription]struct{})
+		b.users[userID] = subscribers
+	}
+	subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Events returns the channel events are delivered on. It is closed when
+// the subscriber falls too far behind or the subscription is closed.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if s.userID != 0 {
+		subscribers := b.users[s.userID]
+		if _, ok := subscribers[s]; ok {
+			delete(subscribers, s)
+			close(s.events)
+		}
+		if len(subscribers) == 0 {
+			delete(b.users, s.userID)
+		}
+		return
+	}
+
+	stream, ok := b.boards[s.boardID]
+	if !ok {
+		return
+	}
+	if _, ok := stream.subscribers[s]; ok {
+		delete(stream.subscribers, s)
+		close(s.events)
+	}
+}
+
+// stream returns the stream of a board, creating it if needed. Callers
+// must hold b.mu.
+func (b *Broker) stream(boardID int64) *boardStream {
+	stream, ok := b.boards[boardID]
+	if !ok {
+		// Earlier events of the board may have been pruned, so none of
+		// them can be replayed
+		stream = &boardStream{
+			evicted:     b.seq,
+			updated:     time.Now(),
+			subscribers: make(map[*Subscription]struct{}),
+		}
+		b.boards[boardID] = stream
+	}
+	return stream
+}
+
+// parseID returns the sequence number of an event ID issued by this
+// broker. Callers must hold b.mu.
+func (b *Broker) parseID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || n > b.seq {
+		return 0, false
+	}
+	return n, true
+}
+
+// prune forgets the history of boards nobody has watched or changed for a
+// while. It runs at most once per historyTTL. Callers must hold b.mu.
+func (b *Broker) prune() {
+	now := time.Now()
+	if now.Sub(b.pruned) < historyTTL {
+		return
+	}
+	b.pruned = now
+
+	for id, stream := range b.boards {
+		if len(stream.subscribers) == 0 && now.Sub(stream.updated) > historyTTL {
+			delete(b.boards, id)
+		}
+	}
+}
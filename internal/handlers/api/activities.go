@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+const (
+	// defaultPageLimit is the page size when no limit is given
+	defaultPageLimit = 50
+
+	// maxPageLimit is the largest page size a client may request
+	maxPageLimit = 100
+)
+
+// APIActivityHandlers handles activity log API routes
+type APIActivityHandlers struct {
+	activityService *services.ActivityService
+}
+
+// NewAPIActivityHandlers creates a new API activity handlers instance
+func NewAPIActivityHandlers(activityService *services.ActivityService) *APIActivityHandlers {
+	return &APIActivityHandlers{
+		activityService: activityService,
+	}
+}
+
+// Request/Response types
+
+// ActivityUserResponse represents the user who performed an activity
+type ActivityUserResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// ActivityResponse represents an activity log entry in API responses
+type ActivityResponse struct {
+	ID        string               `json:"id"`
+	ProjectID string               `json:"project_id"`
+	TaskID    string               `json:"task_id"`
+	Action    string               `json:"action"`
+	Details   json.RawMessage      `json:"details"`
+	User      ActivityUserResponse `json:"user"`
+	CreatedAt string               `json:"created_at"`
+}
+
+// PaginationResponse describes the page returned by a paginated endpoint
+type PaginationResponse struct {
+	Limit   int64 `json:"limit"`
+	Offset  int64 `json:"offset"`
+	HasMore bool  `json:"has_more"`
+}
+
+// Helper functions
+
+// activityToResponse converts an activity entry to API response format
+func activityToResponse(entry *services.ActivityEntry) ActivityResponse {
+	a := &entry.Activity
+
+	taskID := ""
+	if a.TaskID.Valid {
+		taskID = formatID(a.TaskID.Int64)
+	}
+
+	details := json.RawMessage("null")
+	if a.Details.Valid {
+		details = json.RawMessage(a.Details.String)
+	}
+
+	return ActivityResponse{
+		ID:        formatID(a.ID),
+		ProjectID: formatID(a.ProjectID),
+		TaskID:    taskID,
+		Action:    a.Action,
+		Details:   details,
+		User: ActivityUserResponse{
+			ID:        formatID(a.UserID),
+			Name:      entry.UserName,
+			Email:     entry.UserEmail,
+			AvatarURL: entry.UserAvatarURL,
+		},
+		CreatedAt: formatTime(a.CreatedAt),
+	}
+}
+
+// parsePagination reads the limit and offset query parameters
+func parsePagination(r *http.Request) (limit, offset int64, ok bool) {
+	limit, offset = defaultPageLimit, 0
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			return 0, 0, false
+		}
+		limit = n
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false
+		}
+		offset = n
+	}
+
+	return limit, offset, true
+}
+
+// sendActivityPage responds with a page of activity entries
+func sendActivityPage(w http.ResponseWriter, page *services.ActivityPage, limit, offset int64) {
+	response := make([]ActivityResponse, 0, len(page.Entries))
+	for i := range page.Entries {
+		response = append(response, activityToResponse(&page.Entries[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"activities": response,
+		"pagination": PaginationResponse{
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: page.HasMore,
+		},
+	})
+}
+
+// API Handlers
+
+// HandleListProjectActivity returns the activity log of a project, newest
+// first
+func (h *APIActivityHandlers) HandleListProjectActivity(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		sendError(w, http.StatusBadRequest, "Invalid limit or offset", "INVALID_PAGINATION")
+		return
+	}
+
+	page, err := h.activityService.ListProjectActivity(r.Context(), access.Project.ID, limit, offset)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendActivityPage(w, page, limit, offset)
+}
+
+// HandleListTaskActivity returns the activity log of a task, newest first
+func (h *APIActivityHandlers) HandleListTaskActivity(w http.ResponseWriter, r *http.Request) {
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		sendError(w, http.StatusBadRequest, "Invalid limit or offset", "INVALID_PAGINATION")
+		return
+	}
+
+	page, err := h.activityService.ListTaskActivity(r.Context(), taskID, limit, offset)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendActivityPage(w, page, limit, offset)
+}
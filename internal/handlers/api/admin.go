@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/jobs"
+	"github.com/erickhilda/vugo/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIAdminHandlers handles admin API routes
+type APIAdminHandlers struct {
+	scheduler    *jobs.Scheduler
+	loginLimiter *services.LoginLimiter
+}
+
+// NewAPIAdminHandlers creates a new API admin handlers instance
+func NewAPIAdminHandlers(scheduler *jobs.Scheduler, loginLimiter *services.LoginLimiter) *APIAdminHandlers {
+	return &APIAdminHandlers{
+		scheduler:    scheduler,
+		loginLimiter: loginLimiter,
+	}
+}
+
+// Request/Response types
+
+// JobStatusResponse represents a background job and its last run in API
+// responses. Times are empty until the job has run.
+type JobStatusResponse struct {
+	Name            string `json:"name"`
+	IntervalSeconds int64  `json:"interval_seconds"`
+	Running         bool   `json:"running"`
+	Runs            int    `json:"runs"`
+	Failures        int    `json:"failures"`
+	LastStartedAt   string `json:"last_started_at"`
+	LastFinishedAt  string `json:"last_finished_at"`
+	LastDurationMs  int64  `json:"last_duration_ms"`
+	LastError       string `json:"last_error"`
+	NextRunAt       string `json:"next_run_at"`
+}
+
+// SecurityEventResponse represents a security audit event in API
+// responses
+type SecurityEventResponse struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Event     string          `json:"event"`
+	IPAddress string          `json:"ip_address"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// Helper functions
+
+// jobStatusToResponse converts a job status to API response format
+func jobStatusToResponse(status *jobs.Status) JobStatusResponse {
+	return JobStatusResponse{
+		Name:            status.Name,
+		IntervalSeconds: int64(status.Interval / time.Second),
+		Running:         status.Running,
+		Runs:            status.Runs,
+		Failures:        status.Failures,
+		LastStartedAt:   formatInstant(status.LastStartedAt),
+		LastFinishedAt:  formatInstant(status.LastFinishedAt),
+		LastDurationMs:  status.LastDuration.Milliseconds(),
+		LastError:       status.LastError,
+		NextRunAt:       formatInstant(status.NextRunAt),
+	}
+}
+
+// securityEventToResponse converts a database security event to API
+// response format
+func securityEventToResponse(event *queries.SecurityEvent) SecurityEventResponse {
+	response := SecurityEventResponse{
+		ID:        formatID(event.ID),
+		Event:     event.Event,
+		IPAddress: event.IpAddress,
+		Details:   json.RawMessage(event.Details),
+		CreatedAt: formatTime(event.CreatedAt),
+	}
+	if event.UserID.Valid {
+		response.UserID = formatID(event.UserID.Int64)
+	}
+	return response
+}
+
+// API Handlers
+
+// HandleListJobs returns the status of the background jobs
+func (h *APIAdminHandlers) HandleListJobs(w http.ResponseWriter, r *http.Request) {
+	statuses := h.scheduler.Status()
+
+	response := make([]JobStatusResponse, 0, len(statuses))
+	for i := range statuses {
+		response = append(response, jobStatusToResponse(&statuses[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"jobs": response,
+	})
+}
+
+// HandleRunJob starts a background job now, without waiting for it to
+// finish
+func (h *APIAdminHandlers) HandleRunJob(w http.ResponseWriter, r *http.Request) {
+	if err := h.scheduler.RunNow(chi.URLParam(r, "name")); err != nil {
+		switch {
+		case errors.Is(err, jobs.ErrJobNotFound):
+			sendError(w, http.StatusNotFound, err.Error(), "JOB_NOT_FOUND")
+		case errors.Is(err, jobs.ErrJobRunning):
+			sendError(w, http.StatusConflict, err.Error(), "JOB_RUNNING")
+		default:
+			sendError(w, http.StatusServiceUnavailable, err.Error(), "JOBS_NOT_RUNNING")
+		}
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Job started",
+	})
+}
+
+// HandleListSecurityEvents returns a page of security audit events, such
+// as login lockouts, most recent first
+func (h *APIAdminHandlers) HandleListSecurityEvents(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		sendError(w, http.StatusBadRequest, "Invalid limit or offset", "INVALID_PAGINATION")
+		return
+	}
+
+	events, err := h.loginLimiter.ListSecurityEvents(r.Context(), limit, offset)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]SecurityEventResponse, 0, len(events))
+	for i := range events {
+		response = append(response, securityEventToResponse(&events[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"events": response,
+	})
+}
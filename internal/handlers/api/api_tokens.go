@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// Request/Response types
+
+// CreateAPITokenRequest represents a request for a new API token. A zero
+// expires_in_days creates a token that does not expire.
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// APITokenResponse represents an API token in API responses. The secret
+// itself is only returned when the token is created.
+type APITokenResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// Helper functions
+
+// apiTokenToResponse converts a database API token to API response format
+func apiTokenToResponse(token *queries.ApiToken) APITokenResponse {
+	scopes := services.ParseStoredScopes(token.Scopes)
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+
+	return APITokenResponse{
+		ID:         formatID(token.ID),
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     names,
+		ExpiresAt:  formatTime(token.ExpiresAt),
+		LastUsedAt: formatTime(token.LastUsedAt),
+		CreatedAt:  formatTime(token.CreatedAt),
+	}
+}
+
+// API Handlers
+
+// HandleListAPITokens returns the current user's API tokens
+func (h *APIAuthHandlers) HandleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	tokens, err := h.authService.ListAPITokens(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]APITokenResponse, 0, len(tokens))
+	for i := range tokens {
+		response = append(response, apiTokenToResponse(&tokens[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"tokens": response,
+	})
+}
+
+// HandleCreateAPIToken creates an API token for the current user and
+// returns its secret, which is not shown again
+func (h *APIAuthHandlers) HandleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req CreateAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	created, err := h.authService.CreateAPIToken(r.Context(), user.ID, services.APITokenInput{
+		Name:          req.Name,
+		Scopes:        req.Scopes,
+		ExpiresInDays: req.ExpiresInDays,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]interface{}{
+		"token":  apiTokenToResponse(&created.Token),
+		"secret": created.Secret,
+	})
+}
+
+// HandleRevokeAPIToken deletes one of the current user's API tokens
+func (h *APIAuthHandlers) HandleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	tokenID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid token ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.authService.RevokeAPIToken(r.Context(), user.ID, tokenID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "API token revoked",
+	})
+}
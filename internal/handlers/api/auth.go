@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"net"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/erickhilda/vugo/internal/database/queries"
 	"github.com/erickhilda/vugo/internal/middleware"
@@ -61,13 +63,45 @@ type RegisterRequest struct {
 	Name     string `json:"name"`
 }
 
+// ForgotPasswordRequest represents a request for a password reset email
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents a password reset with an emailed token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// UpdateProfileRequest represents a profile update. Fields left out are
+// not changed; an empty avatar_url removes the avatar.
+type UpdateProfileRequest struct {
+	Name      *string `json:"name"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+// ChangePasswordRequest represents a password change by a signed in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// VerifyEmailRequest represents an email verification with an emailed
+// token
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
 // UserResponse represents a user in API responses
 type UserResponse struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	Name      string `json:"name"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	AvatarURL     string `json:"avatar_url"`
+	CreatedAt     string `json:"created_at"`
+	UpdatedAt     string `json:"updated_at"`
 }
 
 // AuthResponse represents authentication response data
@@ -75,6 +109,13 @@ type AuthResponse struct {
 	User UserResponse `json:"user"`
 }
 
+// TwoFactorChallengeResponse is returned by login instead of the user when
+// a code is needed to complete it
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	Challenge         string `json:"challenge"`
+}
+
 // Helper functions
 
 // sendSuccess sends a successful JSON response
@@ -102,24 +143,72 @@ func sendError(w http.ResponseWriter, statusCode int, message, code string) {
 	})
 }
 
-// userToResponse converts a database user to API response format
-func userToResponse(user *queries.User) UserResponse {
-	createdAt := ""
-	if user.CreatedAt.Valid {
-		createdAt = user.CreatedAt.Time.Format("2006-01-02T15:04:05Z")
-	}
+// sendErrorWithDetails sends an error JSON response with extra details
+func sendErrorWithDetails(w http.ResponseWriter, statusCode int, message, code string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(ApiErrorResponse{
+		Success: false,
+		Data:    nil,
+		Error: ErrorDetail{
+			Message: message,
+			Code:    code,
+			Details: details,
+		},
+	})
+}
 
-	updatedAt := ""
-	if user.UpdatedAt.Valid {
-		updatedAt = user.UpdatedAt.Time.Format("2006-01-02T15:04:05Z")
+// setSessionCookie sets the cookie that keeps a session signed in. The
+// cookie lasts until the session's absolute expiry; the idle timeout is
+// enforced by the server.
+func setSessionCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   os.Getenv("ENV") == "production",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+	})
+}
+
+// clearSessionCookie deletes the session cookie
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session_id",
+		Value:    "",
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   os.Getenv("ENV") == "production",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+}
+
+// sessionClient describes the browser a request comes from, for the
+// session it signs in
+func sessionClient(r *http.Request) services.SessionClient {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
 	}
+	return services.SessionClient{
+		UserAgent: r.UserAgent(),
+		IPAddress: ip,
+	}
+}
 
+// userToResponse converts a database user to API response format
+func userToResponse(user *queries.User) UserResponse {
 	return UserResponse{
-		ID:        fmt.Sprintf("%d", user.ID),
-		Email:     user.Email,
-		Name:      user.Name,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
+		ID:            formatID(user.ID),
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Name:          user.Name,
+		AvatarURL:     user.AvatarUrl.String,
+		CreatedAt:     formatTime(user.CreatedAt),
+		UpdatedAt:     formatTime(user.UpdatedAt),
 	}
 }
 
@@ -146,22 +235,27 @@ func (h *APIAuthHandlers) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Login user
-	result, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	result, err := h.authService.Login(r.Context(), req.Email, req.Password, sessionClient(r))
+	if errors.Is(err, services.ErrPasswordLoginDisabled) || errors.Is(err, services.ErrRateLimited) {
+		sendServiceError(w, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusUnauthorized, err.Error(), "AUTH_INVALID_CREDENTIALS")
 		return
 	}
 
+	// The session is created once the second factor is verified
+	if result.TwoFactorRequired {
+		sendSuccess(w, TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			Challenge:         result.Challenge,
+		})
+		return
+	}
+
 	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    result.Session.ID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   os.Getenv("ENV") == "production",
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   7 * 24 * 60 * 60, // 7 days
-	})
+	setSessionCookie(w, result.SessionToken, result.Session.ExpiresAt)
 
 	// Send success response
 	sendSuccess(w, AuthResponse{
@@ -190,22 +284,18 @@ func (h *APIAuthHandlers) HandleRegister(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Register user
-	result, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Name)
+	result, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Name, sessionClient(r))
+	if errors.Is(err, services.ErrPasswordLoginDisabled) {
+		sendServiceError(w, err)
+		return
+	}
 	if err != nil {
 		sendError(w, http.StatusBadRequest, err.Error(), "REGISTRATION_ERROR")
 		return
 	}
 
 	// Set session cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    result.Session.ID,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   os.Getenv("ENV") == "production",
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   7 * 24 * 60 * 60, // 7 days
-	})
+	setSessionCookie(w, result.SessionToken, result.Session.ExpiresAt)
 
 	// Send success response
 	sendSuccess(w, AuthResponse{
@@ -228,15 +318,7 @@ func (h *APIAuthHandlers) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Delete cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   os.Getenv("ENV") == "production",
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   -1,
-	})
+	clearSessionCookie(w)
 
 	// Send success response
 	sendSuccess(w, map[string]string{
@@ -263,3 +345,174 @@ func (h *APIAuthHandlers) HandleMe(w http.ResponseWriter, r *http.Request) {
 		User: userToResponse(user),
 	})
 }
+
+// HandleUpdateMe changes the current user's name and avatar
+func (h *APIAuthHandlers) HandleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	updated, err := h.authService.UpdateProfile(r.Context(), user.ID, services.ProfileUpdate{
+		Name:      req.Name,
+		AvatarURL: req.AvatarURL,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, AuthResponse{
+		User: userToResponse(updated),
+	})
+}
+
+// HandleChangePassword changes the current user's password after checking
+// the current one, and signs out all of their other sessions
+func (h *APIAuthHandlers) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		sendError(w, http.StatusBadRequest, "Current and new password are required", "VALIDATION_ERROR")
+		return
+	}
+
+	// RequireSession has checked the request was made with a session
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	if err := h.authService.ChangePassword(r.Context(), user.ID, session.ID, req.CurrentPassword, req.NewPassword); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Password changed successfully",
+	})
+}
+
+// HandleForgotPassword emails a password reset link. The response is the
+// same whether or not the email is registered.
+func (h *APIAuthHandlers) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Email == "" {
+		sendError(w, http.StatusBadRequest, "Email is required", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "If an account exists for that email, a password reset link has been sent",
+	})
+}
+
+// HandleResetPassword sets a new password with a token from a reset email
+// and signs the user out of all sessions
+func (h *APIAuthHandlers) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Token == "" || req.Password == "" {
+		sendError(w, http.StatusBadRequest, "Token and password are required", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Password reset successfully",
+	})
+}
+
+// HandleVerifyEmail marks an email address as verified with the token from
+// a verification email
+func (h *APIAuthHandlers) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Token == "" {
+		sendError(w, http.StatusBadRequest, "Token is required", "VALIDATION_ERROR")
+		return
+	}
+
+	user, err := h.authService.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, AuthResponse{
+		User: userToResponse(user),
+	})
+}
+
+// HandleResendVerification emails the current user a new verification link
+func (h *APIAuthHandlers) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	if err := h.authService.ResendVerification(r.Context(), user); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Verification email sent",
+	})
+}
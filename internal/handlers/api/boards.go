@@ -0,0 +1,430 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// APIBoardHandlers handles board and column API routes
+type APIBoardHandlers struct {
+	boardService *services.BoardService
+}
+
+// NewAPIBoardHandlers creates a new API board handlers instance
+func NewAPIBoardHandlers(boardService *services.BoardService) *APIBoardHandlers {
+	return &APIBoardHandlers{
+		boardService: boardService,
+	}
+}
+
+// Request/Response types
+
+// BoardRequest represents a create or update board request
+type BoardRequest struct {
+	Name string `json:"name"`
+}
+
+// PositionRequest represents a request to move an item to an index among
+// its siblings
+type PositionRequest struct {
+	Position int64 `json:"position"`
+}
+
+// ColumnRequest represents a create or update column request
+type ColumnRequest struct {
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	WipLimit *int64 `json:"wip_limit"`
+}
+
+// BoardResponse represents a board in API responses
+type BoardResponse struct {
+	ID        string `json:"id"`
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+	Rank      string `json:"rank"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ColumnResponse represents a column in API responses
+type ColumnResponse struct {
+	ID        string `json:"id"`
+	BoardID   string `json:"board_id"`
+	Name      string `json:"name"`
+	Rank      string `json:"rank"`
+	Color     string `json:"color"`
+	WipLimit  *int64 `json:"wip_limit"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// BoardColumnResponse represents a column with its open tasks
+type BoardColumnResponse struct {
+	ColumnResponse
+	TaskCount    int            `json:"task_count"`
+	OverWipLimit bool           `json:"over_wip_limit"`
+	Tasks        []TaskResponse `json:"tasks"`
+}
+
+// FullBoardResponse represents a board with its columns and tasks
+type FullBoardResponse struct {
+	BoardResponse
+	Columns []BoardColumnResponse `json:"columns"`
+}
+
+// Helper functions
+
+// boardToResponse converts a database board to API response format
+func boardToResponse(board *queries.Board) BoardResponse {
+	return BoardResponse{
+		ID:        formatID(board.ID),
+		ProjectID: formatID(board.ProjectID),
+		Name:      board.Name,
+		Rank:      board.Rank,
+		CreatedAt: formatTime(board.CreatedAt),
+		UpdatedAt: formatTime(board.UpdatedAt),
+	}
+}
+
+// columnToResponse converts a database column to API response format
+func columnToResponse(column *queries.Column) ColumnResponse {
+	var wipLimit *int64
+	if column.WipLimit.Valid {
+		limit := column.WipLimit.Int64
+		wipLimit = &limit
+	}
+
+	return ColumnResponse{
+		ID:        formatID(column.ID),
+		BoardID:   formatID(column.BoardID),
+		Name:      column.Name,
+		Rank:      column.Rank,
+		Color:     column.Color.String,
+		WipLimit:  wipLimit,
+		CreatedAt: formatTime(column.CreatedAt),
+		UpdatedAt: formatTime(column.UpdatedAt),
+	}
+}
+
+// API Handlers
+
+// HandleListBoards returns the boards of a project
+func (h *APIBoardHandlers) HandleListBoards(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	boards, err := h.boardService.ListBoards(r.Context(), access.Project.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]BoardResponse, 0, len(boards))
+	for i := range boards {
+		response = append(response, boardToResponse(&boards[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"boards": response,
+	})
+}
+
+// HandleCreateBoard creates a new board in a project
+func (h *APIBoardHandlers) HandleCreateBoard(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse JSON request
+	var req BoardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	board, err := h.boardService.CreateBoard(r.Context(), access, req.Name)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"board": boardToResponse(board),
+	})
+}
+
+// HandleGetBoard returns a board with its columns and their open tasks so
+// the client can render it in a single round trip
+func (h *APIBoardHandlers) HandleGetBoard(w http.ResponseWriter, r *http.Request) {
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	full, err := h.boardService.GetFullBoard(r.Context(), boardID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := FullBoardResponse{
+		BoardResponse: boardToResponse(&full.Board),
+		Columns:       make([]BoardColumnResponse, 0, len(full.Columns)),
+	}
+	for i := range full.Columns {
+		col := &full.Columns[i]
+		tasks := make([]TaskResponse, 0, len(col.Tasks))
+		for j := range col.Tasks {
+			tasks = append(tasks, taskToResponse(&col.Tasks[j]))
+		}
+		response.Columns = append(response.Columns, BoardColumnResponse{
+			ColumnResponse: columnToResponse(&col.Column),
+			TaskCount:      len(col.Tasks),
+			OverWipLimit:   col.OverWipLimit(),
+			Tasks:          tasks,
+		})
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"board": response,
+	})
+}
+
+// HandleUpdateBoard renames a board
+func (h *APIBoardHandlers) HandleUpdateBoard(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req BoardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	board, err := h.boardService.UpdateBoard(r.Context(), access, boardID, req.Name)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"board": boardToResponse(board),
+	})
+}
+
+// HandleUpdateBoardPosition changes where a board is shown in its project
+func (h *APIBoardHandlers) HandleUpdateBoardPosition(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := h.boardService.UpdateBoardPosition(r.Context(), access, boardID, req.Position); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Board position updated",
+	})
+}
+
+// HandleDeleteBoard deletes a board
+func (h *APIBoardHandlers) HandleDeleteBoard(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.boardService.DeleteBoard(r.Context(), access, boardID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Board deleted",
+	})
+}
+
+// HandleListColumns returns the columns of a board
+func (h *APIBoardHandlers) HandleListColumns(w http.ResponseWriter, r *http.Request) {
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	columns, err := h.boardService.ListColumns(r.Context(), boardID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]ColumnResponse, 0, len(columns))
+	for i := range columns {
+		response = append(response, columnToResponse(&columns[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"columns": response,
+	})
+}
+
+// HandleCreateColumn creates a new column on a board
+func (h *APIBoardHandlers) HandleCreateColumn(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req ColumnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	column, err := h.boardService.CreateColumn(r.Context(), access, boardID, services.ColumnInput{
+		Name:     req.Name,
+		Color:    req.Color,
+		WipLimit: req.WipLimit,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"column": columnToResponse(column),
+	})
+}
+
+// HandleUpdateColumn updates a column's name, color and WIP limit
+func (h *APIBoardHandlers) HandleUpdateColumn(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	columnID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid column ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req ColumnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	column, err := h.boardService.UpdateColumn(r.Context(), access, columnID, services.ColumnInput{
+		Name:     req.Name,
+		Color:    req.Color,
+		WipLimit: req.WipLimit,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"column": columnToResponse(column),
+	})
+}
+
+// HandleUpdateColumnPosition changes where a column is shown on its board
+func (h *APIBoardHandlers) HandleUpdateColumnPosition(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	columnID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid column ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req PositionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if err := h.boardService.UpdateColumnPosition(r.Context(), access, columnID, req.Position); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Column position updated",
+	})
+}
+
+// HandleDeleteColumn deletes a column
+func (h *APIBoardHandlers) HandleDeleteColumn(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	columnID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid column ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.boardService.DeleteColumn(r.Context(), access, columnID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Column deleted",
+	})
+}
@@ -0,0 +1,318 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/collab"
+	"github.com/erickhilda/vugo/internal/events"
+	"github.com/erickhilda/vugo/internal/services"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// socketWriteWait is how long a single write to a client may take
+	socketWriteWait = 10 * time.Second
+
+	// socketPongWait is how long a client may stay silent before it is
+	// considered gone
+	socketPongWait = 60 * time.Second
+
+	// socketPingPeriod is how often clients are pinged; it must be shorter
+	// than socketPongWait
+	socketPingPeriod = socketPongWait * 9 / 10
+
+	// socketMaxMessageSize is the largest message accepted from a client
+	socketMaxMessageSize = 4096
+)
+
+// APICollabHandlers handles the board collaboration WebSocket
+type APICollabHandlers struct {
+	authService *services.AuthService
+	authorizer  *services.ProjectAuthorizer
+	broker      *events.Broker
+	hub         *collab.Hub
+	upgrader    websocket.Upgrader
+}
+
+// NewAPICollabHandlers creates a new API collaboration handlers instance.
+// Browsers may only connect from the server's own origin or one of
+// allowedOrigins.
+func NewAPICollabHandlers(authService *services.AuthService, authorizer *services.ProjectAuthorizer, broker *events.Broker, hub *collab.Hub, allowedOrigins []string) *APICollabHandlers {
+	h := &APICollabHandlers{
+		authService: authService,
+		authorizer:  authorizer,
+		broker:      broker,
+		hub:         hub,
+	}
+	h.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			return checkSocketOrigin(r, allowedOrigins)
+		},
+	}
+	return h
+}
+
+// Request/Response types
+
+// CollabRequest represents a message sent by a board client. Types are
+// "presence.update" with task_id and editing, and "typing" with task_id.
+type CollabRequest struct {
+	Type    string `json:"type"`
+	TaskID  string `json:"task_id"`
+	Editing bool   `json:"editing"`
+}
+
+// CollabMessage represents a message sent to a board client. Board events
+// carry their event ID.
+type CollabMessage struct {
+	Type string      `json:"type"`
+	ID   string      `json:"id,omitempty"`
+	Data interface{} `json:"data"`
+}
+
+// ViewerResponse represents a connected viewer of a board
+type ViewerResponse struct {
+	ClientID  string `json:"client_id"`
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+	TaskID    string `json:"task_id"`
+	Editing   bool   `json:"editing"`
+}
+
+// TypingResponse represents a typing indicator
+type TypingResponse struct {
+	ClientID string `json:"client_id"`
+	UserID   string `json:"user_id"`
+	Name     string `json:"name"`
+	TaskID   string `json:"task_id"`
+}
+
+// Helper functions
+
+// checkSocketOrigin accepts same-origin requests and requests from the
+// allowed origins. Requests without an Origin header do not come from a
+// browser and are accepted too.
+func checkSocketOrigin(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && u.Host == r.Host {
+		return true
+	}
+
+	for _, allowed := range allowedOrigins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalID formats an optional ID, where zero means none
+func optionalID(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return formatID(id)
+}
+
+// viewerToResponse converts a board viewer to API response format
+func viewerToResponse(v *collab.Viewer) ViewerResponse {
+	return ViewerResponse{
+		ClientID:  strconv.FormatUint(v.ClientID, 10),
+		UserID:    formatID(v.UserID),
+		Name:      v.Name,
+		AvatarURL: v.AvatarURL,
+		TaskID:    optionalID(v.TaskID),
+		Editing:   v.Editing,
+	}
+}
+
+// presenceToMessage converts a presence message to the format sent to
+// clients
+func presenceToMessage(msg *collab.Message) CollabMessage {
+	var data interface{}
+	switch d := msg.Data.(type) {
+	case collab.Viewer:
+		data = viewerToResponse(&d)
+	case collab.TypingIndicator:
+		data = TypingResponse{
+			ClientID: strconv.FormatUint(d.ClientID, 10),
+			UserID:   formatID(d.UserID),
+			Name:     d.Name,
+			TaskID:   optionalID(d.TaskID),
+		}
+	}
+	return CollabMessage{Type: msg.Type, Data: data}
+}
+
+// API Handlers
+
+// HandleBoardSocket upgrades to a WebSocket carrying a board's events,
+// who else is viewing the board, comment typing indicators and the user's
+// new notifications. The session is checked during the handshake since
+// browsers cannot follow the login redirect of RequireAuth on a WebSocket.
+func (h *APICollabHandlers) HandleBoardSocket(w http.ResponseWriter, r *http.Request) {
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+	user, _, err := h.authService.GetUserBySession(r.Context(), cookie.Value)
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	projectID, err := h.authorizer.ProjectIDForBoard(r.Context(), boardID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+	if _, err := h.authorizer.Authorize(r.Context(), projectID, user.ID, services.PermViewProject); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Upgrade writes its own error response
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	sub, _, _ := h.broker.Subscribe(boardID, "")
+	defer sub.Close()
+
+	notifications := h.broker.SubscribeUser(user.ID)
+	defer notifications.Close()
+
+	client, viewers := h.hub.Join(boardID, collab.Viewer{
+		UserID:    user.ID,
+		Name:      user.Name,
+		AvatarURL: user.AvatarUrl.String,
+	})
+	defer client.Leave()
+
+	self := client.Viewer()
+	others := make([]ViewerResponse, 0, len(viewers))
+	for i := range viewers {
+		others = append(others, viewerToResponse(&viewers[i]))
+	}
+	hello := CollabMessage{
+		Type: "hello",
+		Data: map[string]interface{}{
+			"client_id": strconv.FormatUint(self.ClientID, 10),
+			"viewers":   others,
+		},
+	}
+
+	done := make(chan struct{})
+	go h.writeSocket(conn, sub, notifications, client, hello, done)
+	h.readSocket(conn, client)
+	close(done)
+}
+
+// readSocket handles messages from a client until it disconnects
+func (h *APICollabHandlers) readSocket(conn *websocket.Conn, client *collab.Client) {
+	conn.SetReadLimit(socketMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(socketPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		// Malformed messages are ignored
+		var req CollabRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			continue
+		}
+
+		var taskID int64
+		if req.TaskID != "" {
+			id, err := strconv.ParseInt(req.TaskID, 10, 64)
+			if err != nil {
+				continue
+			}
+			taskID = id
+		}
+
+		switch req.Type {
+		case "presence.update":
+			client.SetViewing(taskID, req.Editing)
+		case "typing":
+			if taskID != 0 {
+				client.Typing(taskID)
+			}
+		}
+	}
+}
+
+// writeSocket sends the greeting, then board events, notifications,
+// presence messages and pings to a client until either side stops
+func (h *APICollabHandlers) writeSocket(conn *websocket.Conn, sub, notifications *events.Subscription, client *collab.Client, hello CollabMessage, done <-chan struct{}) {
+	ping := time.NewTicker(socketPingPeriod)
+	defer func() {
+		ping.Stop()
+		// Unblocks readSocket if the write side failed first
+		conn.Close()
+	}()
+
+	write := func(msg interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+		return conn.WriteJSON(msg) == nil
+	}
+
+	if !write(hello) {
+		return
+	}
+
+	for {
+		select {
+		case <-done:
+			conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		case e, ok := <-sub.Events():
+			// A closed channel means the client fell behind; it reconnects
+			// and reloads the board
+			if !ok || !write(CollabMessage{Type: e.Type, ID: e.ID, Data: eventToResponse(&e)}) {
+				return
+			}
+		case e, ok := <-notifications.Events():
+			if !ok || !write(CollabMessage{Type: e.Type, ID: e.ID, Data: eventToResponse(&e)}) {
+				return
+			}
+		case msg, ok := <-client.Messages():
+			if !ok || !write(presenceToMessage(&msg)) {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
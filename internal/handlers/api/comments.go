@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// APICommentHandlers handles task comment API routes
+type APICommentHandlers struct {
+	commentService *services.CommentService
+}
+
+// NewAPICommentHandlers creates a new API comment handlers instance
+func NewAPICommentHandlers(commentService *services.CommentService) *APICommentHandlers {
+	return &APICommentHandlers{
+		commentService: commentService,
+	}
+}
+
+// Request/Response types
+
+// CommentRequest represents a create or update comment request. Content is
+// Markdown.
+type CommentRequest struct {
+	Content string `json:"content"`
+}
+
+// CommentResponse represents a comment in API responses. Content is the
+// Markdown source and HTML its sanitized rendering.
+type CommentResponse struct {
+	ID        string           `json:"id"`
+	TaskID    string           `json:"task_id"`
+	Content   string           `json:"content"`
+	HTML      string           `json:"html"`
+	Author    TaskUserResponse `json:"author"`
+	CreatedAt string           `json:"created_at"`
+	UpdatedAt string           `json:"updated_at"`
+}
+
+// Helper functions
+
+// commentToResponse converts a comment to API response format
+func commentToResponse(detail *services.CommentDetail) CommentResponse {
+	c := &detail.Comment
+	return CommentResponse{
+		ID:      formatID(c.ID),
+		TaskID:  formatID(c.TaskID),
+		Content: c.Content,
+		HTML:    detail.HTML,
+		Author: TaskUserResponse{
+			ID:        formatID(c.UserID),
+			Name:      detail.AuthorName,
+			Email:     detail.AuthorEmail,
+			AvatarURL: detail.AuthorAvatarURL,
+		},
+		CreatedAt: formatTime(c.CreatedAt),
+		UpdatedAt: formatTime(c.UpdatedAt),
+	}
+}
+
+// API Handlers
+
+// HandleListComments returns the comments of a task, oldest first
+func (h *APICommentHandlers) HandleListComments(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	comments, err := h.commentService.ListComments(r.Context(), access, taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]CommentResponse, 0, len(comments))
+	for i := range comments {
+		response = append(response, commentToResponse(&comments[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"comments": response,
+	})
+}
+
+// HandleCreateComment adds a comment to a task
+func (h *APICommentHandlers) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(r.Context(), access, taskID, req.Content)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"comment": commentToResponse(comment),
+	})
+}
+
+// HandleUpdateComment edits a comment. Only the author and project admins
+// may edit it.
+func (h *APICommentHandlers) HandleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	commentID, err := parseIDParam(r, "commentID")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid comment ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req CommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	comment, err := h.commentService.UpdateComment(r.Context(), access, taskID, commentID, req.Content)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"comment": commentToResponse(comment),
+	})
+}
+
+// HandleDeleteComment deletes a comment. Only the author and project admins
+// may delete it.
+func (h *APICommentHandlers) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	commentID, err := parseIDParam(r, "commentID")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid comment ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.commentService.DeleteComment(r.Context(), access, taskID, commentID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Comment deleted",
+	})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// errorMapping is the API response for a sentinel service error
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// serviceErrors maps sentinel service errors to API responses, grouped by
+// domain. Errors are matched in order with errors.Is.
+var serviceErrors = []errorMapping{
+	// Projects and members
+	{services.ErrProjectNotFound, http.StatusNotFound, "PROJECT_NOT_FOUND"},
+	{services.ErrMemberNotFound, http.StatusNotFound, "MEMBER_NOT_FOUND"},
+	{services.ErrUserNotFound, http.StatusNotFound, "USER_NOT_FOUND"},
+	{services.ErrAlreadyMember, http.StatusConflict, "ALREADY_MEMBER"},
+	{services.ErrInsufficientScope, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+	{services.ErrForbidden, http.StatusForbidden, "FORBIDDEN"},
+
+	// Boards and tasks
+	{services.ErrBoardNotFound, http.StatusNotFound, "BOARD_NOT_FOUND"},
+	{services.ErrColumnNotFound, http.StatusNotFound, "COLUMN_NOT_FOUND"},
+	{services.ErrTaskNotFound, http.StatusNotFound, "TASK_NOT_FOUND"},
+	{services.ErrAssigneeNotFound, http.StatusNotFound, "ASSIGNEE_NOT_FOUND"},
+	{services.ErrAlreadyAssigned, http.StatusConflict, "ALREADY_ASSIGNED"},
+	{services.ErrLabelNotFound, http.StatusNotFound, "LABEL_NOT_FOUND"},
+	{services.ErrLabelAlreadyAdded, http.StatusConflict, "LABEL_ALREADY_ADDED"},
+	{services.ErrCommentNotFound, http.StatusNotFound, "COMMENT_NOT_FOUND"},
+
+	// Notifications
+	{services.ErrNotificationNotFound, http.StatusNotFound, "NOTIFICATION_NOT_FOUND"},
+
+	// Accounts
+	{services.ErrIncorrectPassword, http.StatusBadRequest, "INCORRECT_PASSWORD"},
+	{services.ErrInvalidResetToken, http.StatusBadRequest, "INVALID_RESET_TOKEN"},
+	{services.ErrInvalidVerificationToken, http.StatusBadRequest, "INVALID_VERIFICATION_TOKEN"},
+	{services.ErrEmailAlreadyVerified, http.StatusConflict, "EMAIL_ALREADY_VERIFIED"},
+	{services.ErrVerificationThrottled, http.StatusTooManyRequests, "VERIFICATION_THROTTLED"},
+	{services.ErrEmailNotVerified, http.StatusForbidden, "EMAIL_NOT_VERIFIED"},
+	{services.ErrAPITokenNotFound, http.StatusNotFound, "API_TOKEN_NOT_FOUND"},
+	{services.ErrSessionNotFound, http.StatusNotFound, "SESSION_NOT_FOUND"},
+
+	// Two-factor authentication
+	{services.ErrTwoFactorAlreadyEnabled, http.StatusConflict, "TWO_FACTOR_ALREADY_ENABLED"},
+	{services.ErrTwoFactorNotEnabled, http.StatusBadRequest, "TWO_FACTOR_NOT_ENABLED"},
+	{services.ErrInvalidTwoFactorCode, http.StatusUnauthorized, "INVALID_TWO_FACTOR_CODE"},
+	{services.ErrInvalidLoginChallenge, http.StatusUnauthorized, "INVALID_LOGIN_CHALLENGE"},
+
+	// Single sign-on
+	{services.ErrPasswordLoginDisabled, http.StatusForbidden, "PASSWORD_LOGIN_DISABLED"},
+	{services.ErrSSONotConfigured, http.StatusNotFound, "SSO_NOT_CONFIGURED"},
+}
+
+// sendServiceError maps service errors to API error responses
+func sendServiceError(w http.ResponseWriter, err error) {
+	var validationErr *services.ValidationError
+	var wipErr *services.WipLimitError
+
+	switch {
+	case errors.As(err, &validationErr):
+		sendError(w, http.StatusBadRequest, validationErr.Message, "VALIDATION_ERROR")
+		return
+	case errors.As(err, &wipErr):
+		sendErrorWithDetails(w, http.StatusConflict, wipErr.Error(), "WIP_LIMIT_EXCEEDED", map[string]interface{}{
+			"column_id":  formatID(wipErr.ColumnID),
+			"wip_limit":  wipErr.Limit,
+			"task_count": wipErr.Count,
+		})
+		return
+	case errors.Is(err, services.ErrRateLimited):
+		sendRateLimitError(w, err)
+		return
+	case errors.Is(err, services.ErrSSOFailed):
+		// The provider's error is not shown to the user
+		sendError(w, http.StatusBadGateway, services.ErrSSOFailed.Error(), "SSO_FAILED")
+		return
+	}
+
+	for _, m := range serviceErrors {
+		if errors.Is(err, m.err) {
+			sendError(w, m.status, err.Error(), m.code)
+			return
+		}
+	}
+
+	sendError(w, http.StatusInternalServerError, "Internal server error", "INTERNAL_ERROR")
+}
+
+// sendRateLimitError responds to a rate limited request, telling the client
+// when to try again
+func sendRateLimitError(w http.ResponseWriter, err error) {
+	var rateErr *services.RateLimitError
+	if errors.As(err, &rateErr) {
+		seconds := int64(math.Ceil(rateErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	sendError(w, http.StatusTooManyRequests, err.Error(), "RATE_LIMITED")
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+	"github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+const (
+	// eventRetry is the reconnect delay suggested to browsers, in ms
+	eventRetry = 3000
+
+	// eventHeartbeat is how often an idle stream sends a comment so that
+	// proxies keep the connection open
+	eventHeartbeat = 25 * time.Second
+
+	// eventResync tells a client it missed events and must reload the board
+	eventResync = "board.resync"
+)
+
+// APIEventHandlers handles real-time event streams
+type APIEventHandlers struct {
+	broker *events.Broker
+}
+
+// NewAPIEventHandlers creates a new API event handlers instance
+func NewAPIEventHandlers(broker *events.Broker) *APIEventHandlers {
+	return &APIEventHandlers{
+		broker: broker,
+	}
+}
+
+// Request/Response types
+
+// EventResponse is the data of an event sent to clients. Events addressed
+// to a user have no board.
+type EventResponse struct {
+	BoardID string      `json:"board_id,omitempty"`
+	UserID  string      `json:"user_id"`
+	Data    interface{} `json:"data"`
+}
+
+// Helper functions
+
+// eventToResponse converts a board event to the format sent to clients.
+// Rows are sent the same way the REST endpoints return them.
+func eventToResponse(e *events.Event) EventResponse {
+	var data interface{}
+	switch d := e.Data.(type) {
+	case *queries.Board:
+		data = map[string]interface{}{"board": boardToResponse(d)}
+	case *queries.Column:
+		data = map[string]interface{}{"column": columnToResponse(d)}
+	case *queries.Task:
+		data = map[string]interface{}{"task": taskToResponse(d)}
+	case *services.MoveResult:
+		data = map[string]interface{}{
+			"task":    taskToResponse(&d.Task),
+			"columns": columnOrdersToResponse(d.Columns),
+		}
+	case *services.CommentDetail:
+		data = map[string]interface{}{"comment": commentToResponse(d)}
+	case *services.NotificationEntry:
+		data = map[string]interface{}{"notification": notificationToResponse(d)}
+	case events.Refs:
+		refs := map[string]string{}
+		for key, id := range map[string]int64{
+			"board_id":   d.BoardID,
+			"column_id":  d.ColumnID,
+			"task_id":    d.TaskID,
+			"user_id":    d.UserID,
+			"label_id":   d.LabelID,
+			"comment_id": d.CommentID,
+		} {
+			if id != 0 {
+				refs[key] = formatID(id)
+			}
+		}
+		data = refs
+	}
+
+	return EventResponse{
+		BoardID: optionalID(e.BoardID),
+		UserID:  formatID(e.UserID),
+		Data:    data,
+	}
+}
+
+// writeEvent writes a single server-sent event
+func writeEvent(w http.ResponseWriter, id, eventType string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, b)
+	return err
+}
+
+// openEventStream starts a server-sent event response
+func openEventStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+}
+
+// streamEvents writes live events to a server-sent event stream until the
+// client disconnects, with a heartbeat while idle
+func streamEvents(w http.ResponseWriter, r *http.Request, flusher http.Flusher, sub *events.Subscription) {
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				// Dropped for falling behind; the browser reconnects and
+				// catches up
+				return
+			}
+			if err := writeEvent(w, e.ID, e.Type, eventToResponse(&e)); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// API Handlers
+
+// HandleBoardEvents streams the changes to a board as server-sent events.
+// A reconnecting browser sends the Last-Event-ID header and receives the
+// events it missed, or a board.resync event if they are no longer known.
+func (h *APIEventHandlers) HandleBoardEvents(w http.ResponseWriter, r *http.Request) {
+	boardID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid board ID", "INVALID_ID")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, http.StatusInternalServerError, "Streaming not supported", "STREAMING_UNSUPPORTED")
+		return
+	}
+
+	sub, replay, complete := h.broker.Subscribe(boardID, r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	openEventStream(w)
+	if !complete {
+		writeEvent(w, "", eventResync, map[string]string{"board_id": formatID(boardID)})
+	}
+	for i := range replay {
+		if err := writeEvent(w, replay[i].ID, replay[i].Type, eventToResponse(&replay[i])); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	streamEvents(w, r, flusher, sub)
+}
+
+// HandleNotificationEvents streams the current user's new notifications as
+// server-sent events. Missed notifications are not replayed; a
+// reconnecting client reloads them from the notifications endpoint.
+func (h *APIEventHandlers) HandleNotificationEvents(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		sendError(w, http.StatusInternalServerError, "Streaming not supported", "STREAMING_UNSUPPORTED")
+		return
+	}
+
+	sub := h.broker.SubscribeUser(user.ID)
+	defer sub.Close()
+
+	openEventStream(w)
+	flusher.Flush()
+
+	streamEvents(w, r, flusher, sub)
+}
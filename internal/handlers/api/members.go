@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// Request/Response types
+
+// AddMemberRequest represents a request to add a user to a project
+type AddMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// UpdateMemberRequest represents a request to change a member's role
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// TransferProjectRequest represents a request to transfer project ownership
+type TransferProjectRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// MemberResponse represents a project member in API responses
+type MemberResponse struct {
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+	Role      string `json:"role"`
+	JoinedAt  string `json:"joined_at"`
+}
+
+// API Handlers
+
+// HandleListMembers returns the members of a project
+func (h *APIProjectHandlers) HandleListMembers(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := h.projectService.ListMembers(r.Context(), access.Project.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]MemberResponse, 0, len(members))
+	for _, m := range members {
+		response = append(response, MemberResponse{
+			UserID:    formatID(m.UserID),
+			Name:      m.UserName,
+			Email:     m.UserEmail,
+			AvatarURL: m.UserAvatarUrl.String,
+			Role:      m.Role,
+			JoinedAt:  formatTime(m.JoinedAt),
+		})
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"members": response,
+	})
+}
+
+// HandleAddMember adds an existing user to a project by email
+func (h *APIProjectHandlers) HandleAddMember(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse JSON request
+	var req AddMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	if req.Email == "" {
+		sendError(w, http.StatusBadRequest, "Email is required", "VALIDATION_ERROR")
+		return
+	}
+
+	if req.Role == "" {
+		req.Role = string(services.RoleMember)
+	}
+	role, err := services.ParseRole(req.Role)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	member, err := h.projectService.AddMember(r.Context(), access, req.Email, role)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"member": MemberResponse{
+			UserID:    formatID(member.User.ID),
+			Name:      member.User.Name,
+			Email:     member.User.Email,
+			AvatarURL: member.User.AvatarUrl.String,
+			Role:      member.Member.Role,
+			JoinedAt:  formatTime(member.Member.JoinedAt),
+		},
+	})
+}
+
+// HandleUpdateMember changes the role of a project member
+func (h *APIProjectHandlers) HandleUpdateMember(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := parseIDParam(r, "userID")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	role, err := services.ParseRole(req.Role)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	if err := h.projectService.UpdateMemberRole(r.Context(), access, userID, role); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Member role updated",
+	})
+}
+
+// HandleRemoveMember removes a user from a project
+func (h *APIProjectHandlers) HandleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := parseIDParam(r, "userID")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.projectService.RemoveMember(r.Context(), access, userID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Member removed",
+	})
+}
+
+// HandleTransferProject transfers project ownership to another member
+func (h *APIProjectHandlers) HandleTransferProject(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse JSON request
+	var req TransferProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	newOwnerID, err := strconv.ParseInt(req.UserID, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.projectService.TransferOwnership(r.Context(), &access.Project, newOwnerID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Project ownership transferred",
+	})
+}
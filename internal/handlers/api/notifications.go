@@ -0,0 +1,307 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// APINotificationHandlers handles notification and task watching API routes
+type APINotificationHandlers struct {
+	notificationService *services.NotificationService
+}
+
+// NewAPINotificationHandlers creates a new API notification handlers instance
+func NewAPINotificationHandlers(notificationService *services.NotificationService) *APINotificationHandlers {
+	return &APINotificationHandlers{
+		notificationService: notificationService,
+	}
+}
+
+// Request/Response types
+
+// NotificationActorResponse represents the user who caused a notification
+type NotificationActorResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// NotificationResponse represents a notification in API responses. Actor,
+// project and task are omitted when the notification does not refer to
+// one.
+type NotificationResponse struct {
+	ID          string                     `json:"id"`
+	Type        string                     `json:"type"`
+	Actor       *NotificationActorResponse `json:"actor"`
+	ProjectID   string                     `json:"project_id"`
+	ProjectName string                     `json:"project_name"`
+	TaskID      string                     `json:"task_id"`
+	TaskTitle   string                     `json:"task_title"`
+	CommentID   string                     `json:"comment_id"`
+	Read        bool                       `json:"read"`
+	ReadAt      string                     `json:"read_at"`
+	CreatedAt   string                     `json:"created_at"`
+}
+
+// EmailPreferencesRequest sets how notification types are emailed, e.g.
+// {"assigned": "immediate", "mentioned": "digest", "due_soon": "off"}.
+// Types left out keep their current setting.
+type EmailPreferencesRequest map[string]string
+
+// Helper functions
+
+// notificationToResponse converts a notification to API response format
+func notificationToResponse(entry *services.NotificationEntry) NotificationResponse {
+	n := &entry.Notification
+
+	var actor *NotificationActorResponse
+	if n.ActorID.Valid {
+		actor = &NotificationActorResponse{
+			ID:        formatID(n.ActorID.Int64),
+			Name:      entry.ActorName,
+			Email:     entry.ActorEmail,
+			AvatarURL: entry.ActorAvatarURL,
+		}
+	}
+
+	return NotificationResponse{
+		ID:          formatID(n.ID),
+		Type:        n.Type,
+		Actor:       actor,
+		ProjectID:   optionalID(n.ProjectID.Int64),
+		ProjectName: entry.ProjectName,
+		TaskID:      optionalID(n.TaskID.Int64),
+		TaskTitle:   entry.TaskTitle,
+		CommentID:   optionalID(n.CommentID.Int64),
+		Read:        n.ReadAt.Valid,
+		ReadAt:      formatTime(n.ReadAt),
+		CreatedAt:   formatTime(n.CreatedAt),
+	}
+}
+
+// API Handlers
+
+// HandleListNotifications returns the current user's notifications, newest
+// first, with their unread count. ?unread=true returns only unread ones.
+func (h *APINotificationHandlers) HandleListNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	limit, offset, ok := parsePagination(r)
+	if !ok {
+		sendError(w, http.StatusBadRequest, "Invalid limit or offset", "INVALID_PAGINATION")
+		return
+	}
+
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	page, err := h.notificationService.ListNotifications(r.Context(), user.ID, unreadOnly, limit, offset)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]NotificationResponse, 0, len(page.Entries))
+	for i := range page.Entries {
+		response = append(response, notificationToResponse(&page.Entries[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"notifications": response,
+		"unread_count":  page.Unread,
+		"pagination": PaginationResponse{
+			Limit:   limit,
+			Offset:  offset,
+			HasMore: page.HasMore,
+		},
+	})
+}
+
+// HandleUnreadCount returns how many unread notifications the current user
+// has
+func (h *APINotificationHandlers) HandleUnreadCount(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	count, err := h.notificationService.CountUnread(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"unread_count": count,
+	})
+}
+
+// HandleMarkRead marks one of the current user's notifications as read
+func (h *APINotificationHandlers) HandleMarkRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	notificationID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid notification ID", "INVALID_ID")
+		return
+	}
+
+	entry, err := h.notificationService.MarkRead(r.Context(), user.ID, notificationID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"notification": notificationToResponse(entry),
+	})
+}
+
+// HandleMarkAllRead marks all of the current user's notifications as read
+func (h *APINotificationHandlers) HandleMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	marked, err := h.notificationService.MarkAllRead(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"marked":       marked,
+		"unread_count": 0,
+	})
+}
+
+// HandleGetPreferences returns how the current user receives notification
+// emails for each notification type
+func (h *APINotificationHandlers) HandleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	prefs, err := h.notificationService.GetEmailPreferences(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"email": prefs,
+	})
+}
+
+// HandleUpdatePreferences changes how the current user receives
+// notification emails: immediately, in a daily digest or not at all
+func (h *APINotificationHandlers) HandleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req EmailPreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	prefs, err := h.notificationService.UpdateEmailPreferences(r.Context(), user.ID, req)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"email": prefs,
+	})
+}
+
+// HandleGetWatching returns whether the current user watches a task
+func (h *APINotificationHandlers) HandleGetWatching(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	watching, err := h.notificationService.IsWatching(r.Context(), access.UserID, taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"watching": watching,
+	})
+}
+
+// HandleWatchTask makes the current user watch a task
+func (h *APINotificationHandlers) HandleWatchTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.notificationService.WatchTask(r.Context(), access, taskID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"watching": true,
+	})
+}
+
+// HandleUnwatchTask stops the current user watching a task
+func (h *APINotificationHandlers) HandleUnwatchTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.notificationService.UnwatchTask(r.Context(), access, taskID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"watching": false,
+	})
+}
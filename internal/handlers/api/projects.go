@@ -0,0 +1,268 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIProjectHandlers handles project API routes
+type APIProjectHandlers struct {
+	projectService *services.ProjectService
+}
+
+// NewAPIProjectHandlers creates a new API project handlers instance
+func NewAPIProjectHandlers(projectService *services.ProjectService) *APIProjectHandlers {
+	return &APIProjectHandlers{
+		projectService: projectService,
+	}
+}
+
+// Request/Response types
+
+// ProjectRequest represents a create or update project request
+type ProjectRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Color       string `json:"color"`
+}
+
+// ProjectOwnerResponse represents a project owner in API responses
+type ProjectOwnerResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// ProjectResponse represents a project in API responses
+type ProjectResponse struct {
+	ID          string                `json:"id"`
+	OwnerID     string                `json:"owner_id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Color       string                `json:"color"`
+	Archived    bool                  `json:"archived"`
+	Owner       *ProjectOwnerResponse `json:"owner,omitempty"`
+	Role        string                `json:"role,omitempty"`
+	CreatedAt   string                `json:"created_at"`
+	UpdatedAt   string                `json:"updated_at"`
+}
+
+// Helper functions
+
+// formatTime formats a nullable database timestamp for API responses
+func formatTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format("2006-01-02T15:04:05Z")
+}
+
+// formatInstant formats a time for API responses, or the zero time as
+// empty
+func formatInstant(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// formatID formats a database ID for API responses
+func formatID(id int64) string {
+	return fmt.Sprintf("%d", id)
+}
+
+// parseIDParam reads a numeric ID from the URL path
+func parseIDParam(r *http.Request, name string) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, name), 10, 64)
+}
+
+// getProjectAccess returns the project access loaded by the project
+// middleware, sending an error response if it is missing
+func getProjectAccess(w http.ResponseWriter, r *http.Request) (*services.ProjectAccess, bool) {
+	access, ok := middleware.GetProjectAccessFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusNotFound, services.ErrProjectNotFound.Error(), "PROJECT_NOT_FOUND")
+		return nil, false
+	}
+	return access, true
+}
+
+// projectToResponse converts a database project to API response format
+func projectToResponse(project *queries.Project) ProjectResponse {
+	color := project.Color.String
+	if !project.Color.Valid {
+		color = services.DefaultProjectColor
+	}
+
+	return ProjectResponse{
+		ID:          formatID(project.ID),
+		OwnerID:     formatID(project.OwnerID),
+		Name:        project.Name,
+		Description: project.Description.String,
+		Color:       color,
+		Archived:    project.Archived.Valid && project.Archived.Bool,
+		CreatedAt:   formatTime(project.CreatedAt),
+		UpdatedAt:   formatTime(project.UpdatedAt),
+	}
+}
+
+// API Handlers
+
+// HandleListProjects returns the projects of the current user.
+// Pass ?archived=true to list archived projects instead.
+func (h *APIProjectHandlers) HandleListProjects(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	archived := r.URL.Query().Get("archived") == "true"
+
+	projects, err := h.projectService.ListProjects(r.Context(), user.ID, archived)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]ProjectResponse, 0, len(projects))
+	for i := range projects {
+		response = append(response, projectToResponse(&projects[i]))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"projects": response,
+	})
+}
+
+// HandleCreateProject creates a new project owned by the current user
+func (h *APIProjectHandlers) HandleCreateProject(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	project, err := h.projectService.CreateProject(r.Context(), user.ID, services.ProjectInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Color:       req.Color,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"project": projectToResponse(project),
+	})
+}
+
+// HandleGetProject returns a single project with its owner
+func (h *APIProjectHandlers) HandleGetProject(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	detail, err := h.projectService.GetProject(r.Context(), access.Project.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := projectToResponse(&detail.Project)
+	response.Owner = &ProjectOwnerResponse{
+		ID:    formatID(detail.Project.OwnerID),
+		Name:  detail.OwnerName,
+		Email: detail.OwnerEmail,
+	}
+	response.Role = string(access.Role)
+
+	sendSuccess(w, map[string]interface{}{
+		"project": response,
+	})
+}
+
+// HandleUpdateProject updates a project's name, description and color
+func (h *APIProjectHandlers) HandleUpdateProject(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	// Parse JSON request
+	var req ProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	project, err := h.projectService.UpdateProject(r.Context(), &access.Project, services.ProjectInput{
+		Name:        req.Name,
+		Description: req.Description,
+		Color:       req.Color,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"project": projectToResponse(project),
+	})
+}
+
+// HandleArchiveProject archives a project
+func (h *APIProjectHandlers) HandleArchiveProject(w http.ResponseWriter, r *http.Request) {
+	h.handleProjectAction(w, r, h.projectService.ArchiveProject, "Project archived")
+}
+
+// HandleUnarchiveProject restores an archived project
+func (h *APIProjectHandlers) HandleUnarchiveProject(w http.ResponseWriter, r *http.Request) {
+	h.handleProjectAction(w, r, h.projectService.UnarchiveProject, "Project unarchived")
+}
+
+// HandleDeleteProject permanently deletes a project
+func (h *APIProjectHandlers) HandleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	h.handleProjectAction(w, r, h.projectService.DeleteProject, "Project deleted")
+}
+
+// handleProjectAction runs a project action that only returns an error
+func (h *APIProjectHandlers) handleProjectAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	action func(ctx context.Context, project *queries.Project) error,
+	message string,
+) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	if err := action(r.Context(), &access.Project); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": message,
+	})
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// Request/Response types
+
+// SessionResponse represents a signed in session in API responses. Current
+// is true for the session the request was made with.
+type SessionResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	IPAddress  string `json:"ip_address"`
+	Current    bool   `json:"current"`
+	LastSeenAt string `json:"last_seen_at"`
+	ExpiresAt  string `json:"expires_at"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// API Handlers
+
+// HandleListSessions returns the current user's signed in sessions
+func (h *APIAuthHandlers) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+	current, _ := middleware.GetSessionFromContext(r.Context())
+
+	sessions, err := h.authService.ListSessions(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for i := range sessions {
+		response = append(response, h.sessionToResponse(&sessions[i], current))
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"sessions": response,
+	})
+}
+
+// HandleRevokeSession signs out one of the current user's sessions
+func (h *APIAuthHandlers) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	sessionID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid session ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), user.ID, sessionID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Revoking the current session signs this browser out
+	if current, ok := middleware.GetSessionFromContext(r.Context()); ok && current.ID == sessionID {
+		clearSessionCookie(w)
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Session revoked",
+	})
+}
+
+// HandleRevokeAllSessions signs the current user out everywhere, including
+// this browser
+func (h *APIAuthHandlers) HandleRevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	if err := h.authService.RevokeAllSessions(r.Context(), user.ID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Delete cookie
+	clearSessionCookie(w)
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Signed out of all sessions",
+	})
+}
+
+// HandleCSRFToken returns the CSRF token the app sends with requests that
+// change data
+func (h *APIAuthHandlers) HandleCSRFToken(w http.ResponseWriter, r *http.Request) {
+	// RequireSession has checked the request was made with a session
+	cookie, err := r.Cookie("session_id")
+	if err != nil {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"csrf_token": services.CSRFToken(cookie.Value),
+	})
+}
+
+// Helper functions
+
+// sessionToResponse converts a database session to API response format
+func (h *APIAuthHandlers) sessionToResponse(session *queries.Session, current *queries.Session) SessionResponse {
+	return SessionResponse{
+		ID:         formatID(session.ID),
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IpAddress,
+		Current:    current != nil && current.ID == session.ID,
+		LastSeenAt: formatInstant(session.LastSeenAt),
+		ExpiresAt:  formatInstant(h.authService.SessionExpiresAt(session)),
+		CreatedAt:  formatTime(session.CreatedAt),
+	}
+}
@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+// ssoStateCookie keeps the state of a single sign-on login in the browser
+// that started it, so a callback cannot be replayed in another browser
+const ssoStateCookie = "sso_state"
+
+// APISSOHandlers handles single sign-on API routes
+type APISSOHandlers struct {
+	ssoService  *services.SSOService
+	authService *services.AuthService
+}
+
+// NewAPISSOHandlers creates a new API SSO handlers instance
+func NewAPISSOHandlers(ssoService *services.SSOService, authService *services.AuthService) *APISSOHandlers {
+	return &APISSOHandlers{
+		ssoService:  ssoService,
+		authService: authService,
+	}
+}
+
+// Request/Response types
+
+// AuthOptionsResponse tells the login page which ways to sign in are
+// available
+type AuthOptionsResponse struct {
+	PasswordLogin bool   `json:"password_login"`
+	SSO           bool   `json:"sso"`
+	SSOLoginURL   string `json:"sso_login_url"`
+}
+
+// API Handlers
+
+// HandleAuthOptions returns the ways users can sign in
+func (h *APISSOHandlers) HandleAuthOptions(w http.ResponseWriter, r *http.Request) {
+	response := AuthOptionsResponse{
+		PasswordLogin: h.authService.PasswordLoginEnabled(),
+		SSO:           h.ssoService.Enabled(),
+	}
+	if response.SSO {
+		response.SSOLoginURL = "/api/auth/oidc/login"
+	}
+
+	sendSuccess(w, response)
+}
+
+// HandleSSOLogin sends the browser to the identity provider to sign in
+func (h *APISSOHandlers) HandleSSOLogin(w http.ResponseWriter, r *http.Request) {
+	login, err := h.ssoService.BeginLogin(r.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrSSOFailed) {
+			log.Printf("Error starting single sign-on: %v", err)
+		}
+		sendServiceError(w, err)
+		return
+	}
+
+	setSSOStateCookie(w, login.State)
+
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// HandleSSOLink starts linking a provider account to the current user and
+// returns the identity provider URL for the app to send the browser to.
+// Linking signs the user out of their other sessions.
+func (h *APISSOHandlers) HandleSSOLink(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	login, err := h.ssoService.BeginLink(r.Context(), user.ID)
+	if err != nil {
+		if errors.Is(err, services.ErrSSOFailed) {
+			log.Printf("Error starting single sign-on link: %v", err)
+		}
+		sendServiceError(w, err)
+		return
+	}
+
+	setSSOStateCookie(w, login.State)
+
+	sendSuccess(w, map[string]string{
+		"url": login.URL,
+	})
+}
+
+// HandleSSOCallback completes a login when the identity provider sends the
+// browser back, sets the session cookie and redirects to the app. Users
+// with two-factor authentication are sent to the login page with a
+// challenge to complete with POST /api/auth/login/2fa, and failures with an
+// error code.
+func (h *APISSOHandlers) HandleSSOCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	state := query.Get("state")
+
+	// The state cookie is only good for one attempt
+	cookie, cookieErr := r.Cookie(ssoStateCookie)
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    "",
+		Path:     "/api/auth/oidc",
+		HttpOnly: true,
+		Secure:   os.Getenv("ENV") == "production",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	})
+
+	// The user declined or the provider failed before issuing a code
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Printf("Single sign-on returned error %q: %s", providerErr, query.Get("error_description"))
+		redirectLoginError(w, r, "sso_cancelled")
+		return
+	}
+
+	if state == "" || cookieErr != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		redirectLoginError(w, r, "sso_state_invalid")
+		return
+	}
+
+	result, err := h.ssoService.CompleteLogin(r.Context(), state, query.Get("code"), sessionClient(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidSSOState):
+			redirectLoginError(w, r, "sso_state_invalid")
+		case errors.Is(err, services.ErrSSOEmailNotVerified):
+			redirectLoginError(w, r, "sso_email_not_verified")
+		case errors.Is(err, services.ErrSSOAccountNotLinked):
+			redirectLoginError(w, r, "sso_account_not_linked")
+		case errors.Is(err, services.ErrSSOIdentityInUse):
+			redirectLoginError(w, r, "sso_identity_in_use")
+		default:
+			log.Printf("Error completing single sign-on: %v", err)
+			redirectLoginError(w, r, "sso_failed")
+		}
+		return
+	}
+
+	// The challenge goes in the fragment, which browsers do not send to
+	// servers or in the Referer header
+	if result.TwoFactorRequired {
+		http.Redirect(w, r, "/login#challenge="+url.QueryEscape(result.Challenge), http.StatusFound)
+		return
+	}
+
+	// Set session cookie
+	setSessionCookie(w, result.SessionToken, result.Session.ExpiresAt)
+
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// setSSOStateCookie keeps the state of a login in the browser. It is Lax,
+// as the provider sends the user back with a top-level GET.
+func setSSOStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     ssoStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		HttpOnly: true,
+		Secure:   os.Getenv("ENV") == "production",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   10 * 60, // 10 minutes
+	})
+}
+
+// redirectLoginError sends the browser to the login page with an error code
+func redirectLoginError(w http.ResponseWriter, r *http.Request, code string) {
+	http.Redirect(w, r, "/login?error="+url.QueryEscape(code), http.StatusFound)
+}
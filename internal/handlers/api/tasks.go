@@ -0,0 +1,590 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/services"
+	"github.com/erickhilda/vugo/internal/templates"
+)
+
+// APITaskHandlers handles task API routes
+type APITaskHandlers struct {
+	taskService *services.TaskService
+}
+
+// NewAPITaskHandlers creates a new API task handlers instance
+func NewAPITaskHandlers(taskService *services.TaskService) *APITaskHandlers {
+	return &APITaskHandlers{
+		taskService: taskService,
+	}
+}
+
+// Request/Response types
+
+// CreateTaskRequest represents a create task request
+type CreateTaskRequest struct {
+	Title            string `json:"title"`
+	Description      string `json:"description"`
+	Priority         string `json:"priority"`
+	DueDate          string `json:"due_date"`
+	OverrideWipLimit bool   `json:"override_wip_limit"`
+}
+
+// UpdateTaskRequest represents an update task request
+type UpdateTaskRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Priority    string `json:"priority"`
+	DueDate     string `json:"due_date"`
+}
+
+// AssignTaskRequest represents a request to assign a user to a task
+type AssignTaskRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// TaskLabelRequest represents a request to add a label to a task
+type TaskLabelRequest struct {
+	LabelID string `json:"label_id"`
+}
+
+// MoveTaskRequest represents a request to move a task to an index in a column
+type MoveTaskRequest struct {
+	ColumnID         string `json:"column_id"`
+	Index            int64  `json:"index"`
+	OverrideWipLimit bool   `json:"override_wip_limit"`
+}
+
+// ColumnOrderResponse represents the task order of a column after a move
+type ColumnOrderResponse struct {
+	ColumnID string   `json:"column_id"`
+	TaskIDs  []string `json:"task_ids"`
+}
+
+// TaskResponse represents a task card in API responses
+type TaskResponse struct {
+	ID          string `json:"id"`
+	ColumnID    string `json:"column_id"`
+	CreatedBy   string `json:"created_by"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Rank        string `json:"rank"`
+	Priority    string `json:"priority"`
+	DueDate     string `json:"due_date"`
+	CompletedAt string `json:"completed_at"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// TaskUserResponse represents a user shown on a task
+type TaskUserResponse struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// TaskAssigneeResponse represents a user assigned to a task
+type TaskAssigneeResponse struct {
+	TaskUserResponse
+	AssignedAt string `json:"assigned_at"`
+}
+
+// LabelResponse represents a label in API responses
+type LabelResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// ChecklistProgressResponse represents how much of a task's checklist is done
+type ChecklistProgressResponse struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
+}
+
+// TaskDetailResponse represents a task with everything shown on its detail
+// view. DescriptionHTML is the sanitized rendering of the Markdown
+// description.
+type TaskDetailResponse struct {
+	TaskResponse
+	DescriptionHTML string                    `json:"description_html"`
+	Creator         TaskUserResponse          `json:"creator"`
+	Assignees       []TaskAssigneeResponse    `json:"assignees"`
+	Labels          []LabelResponse           `json:"labels"`
+	Checklist       ChecklistProgressResponse `json:"checklist"`
+	CommentCount    int64                     `json:"comment_count"`
+}
+
+// Helper functions
+
+// taskToResponse converts a database task to API response format
+func taskToResponse(task *queries.Task) TaskResponse {
+	dueDate := ""
+	if task.DueDate.Valid {
+		dueDate = task.DueDate.Time.Format("2006-01-02")
+	}
+
+	return TaskResponse{
+		ID:          formatID(task.ID),
+		ColumnID:    formatID(task.ColumnID),
+		CreatedBy:   formatID(task.CreatedBy),
+		Title:       task.Title,
+		Description: task.Description.String,
+		Rank:        task.Rank,
+		Priority:    task.Priority.String,
+		DueDate:     dueDate,
+		CompletedAt: formatTime(task.CompletedAt),
+		CreatedAt:   formatTime(task.CreatedAt),
+		UpdatedAt:   formatTime(task.UpdatedAt),
+	}
+}
+
+// columnOrdersToResponse converts column task orders to API response format
+func columnOrdersToResponse(orders []services.ColumnOrder) []ColumnOrderResponse {
+	response := make([]ColumnOrderResponse, 0, len(orders))
+	for _, c := range orders {
+		taskIDs := make([]string, 0, len(c.TaskIDs))
+		for _, id := range c.TaskIDs {
+			taskIDs = append(taskIDs, formatID(id))
+		}
+		response = append(response, ColumnOrderResponse{
+			ColumnID: formatID(c.ColumnID),
+			TaskIDs:  taskIDs,
+		})
+	}
+	return response
+}
+
+// assigneesToResponse converts task assignees to API response format
+func assigneesToResponse(assignees []queries.GetTaskAssigneesRow) []TaskAssigneeResponse {
+	response := make([]TaskAssigneeResponse, 0, len(assignees))
+	for _, a := range assignees {
+		response = append(response, TaskAssigneeResponse{
+			TaskUserResponse: TaskUserResponse{
+				ID:        formatID(a.UserID),
+				Name:      a.UserName,
+				Email:     a.UserEmail,
+				AvatarURL: a.UserAvatarUrl.String,
+			},
+			AssignedAt: formatTime(a.AssignedAt),
+		})
+	}
+	return response
+}
+
+// taskLabelsToResponse converts task labels to API response format
+func taskLabelsToResponse(labels []queries.GetTaskLabelsRow) []LabelResponse {
+	response := make([]LabelResponse, 0, len(labels))
+	for _, l := range labels {
+		response = append(response, LabelResponse{
+			ID:    formatID(l.LabelID),
+			Name:  l.LabelName,
+			Color: l.LabelColor,
+		})
+	}
+	return response
+}
+
+// API Handlers
+
+// HandleCreateTask creates a task at the end of a column
+func (h *APITaskHandlers) HandleCreateTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	columnID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid column ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req CreateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	task, err := h.taskService.CreateTask(r.Context(), access, columnID, services.TaskInput{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		DueDate:     req.DueDate,
+	}, req.OverrideWipLimit)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"task": taskToResponse(task),
+	})
+}
+
+// HandleMoveTask moves a task to an index in a column and returns the new
+// task order of every affected column
+func (h *APITaskHandlers) HandleMoveTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req MoveTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	columnID, err := strconv.ParseInt(req.ColumnID, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid column ID", "INVALID_ID")
+		return
+	}
+
+	result, err := h.taskService.MoveTask(r.Context(), access, taskID, columnID, req.Index, req.OverrideWipLimit)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"task":    taskToResponse(&result.Task),
+		"columns": columnOrdersToResponse(result.Columns),
+	})
+}
+
+// HandleGetTask returns a task with its rendered description, creator,
+// assignees, labels, checklist progress and comment count
+func (h *APITaskHandlers) HandleGetTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	detail, err := h.taskService.GetTaskDetail(r.Context(), access, taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"task": TaskDetailResponse{
+			TaskResponse:    taskToResponse(&detail.Task),
+			DescriptionHTML: detail.DescriptionHTML,
+			Creator: TaskUserResponse{
+				ID:        formatID(detail.Task.CreatedBy),
+				Name:      detail.CreatorName,
+				Email:     detail.CreatorEmail,
+				AvatarURL: detail.CreatorAvatarURL,
+			},
+			Assignees: assigneesToResponse(detail.Assignees),
+			Labels:    taskLabelsToResponse(detail.Labels),
+			Checklist: ChecklistProgressResponse{
+				Total:     detail.ChecklistTotal,
+				Completed: detail.ChecklistCompleted,
+			},
+			CommentCount: detail.CommentCount,
+		},
+	})
+}
+
+// HandleTaskReport returns a printable HTML report of a task
+func (h *APITaskHandlers) HandleTaskReport(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	detail, err := h.taskService.GetTaskDetail(r.Context(), access, taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Render fully before writing so a failure can still send an error
+	page, err := templates.Render("pages/task_report.html", map[string]interface{}{
+		"Title":       detail.Task.Title,
+		"Project":     access.Project.Name,
+		"Detail":      detail,
+		"GeneratedAt": time.Now(),
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
+
+// HandleUpdateTask updates a task's title, description, priority and due date
+func (h *APITaskHandlers) HandleUpdateTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req UpdateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	task, err := h.taskService.UpdateTask(r.Context(), access, taskID, services.TaskInput{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		DueDate:     req.DueDate,
+	})
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"task": taskToResponse(task),
+	})
+}
+
+// HandleDeleteTask deletes a task
+func (h *APITaskHandlers) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.taskService.DeleteTask(r.Context(), access, taskID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]string{
+		"message": "Task deleted",
+	})
+}
+
+// HandleCompleteTask marks a task as completed
+func (h *APITaskHandlers) HandleCompleteTask(w http.ResponseWriter, r *http.Request) {
+	h.handleTaskAction(w, r, h.taskService.CompleteTask)
+}
+
+// HandleUncompleteTask reopens a completed task
+func (h *APITaskHandlers) HandleUncompleteTask(w http.ResponseWriter, r *http.Request) {
+	h.handleTaskAction(w, r, h.taskService.UncompleteTask)
+}
+
+// handleTaskAction runs a task state change and returns the updated task
+func (h *APITaskHandlers) handleTaskAction(w http.ResponseWriter, r *http.Request, action func(context.Context, *services.ProjectAccess, int64) (*queries.Task, error)) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	task, err := action(r.Context(), access, taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"task": taskToResponse(task),
+	})
+}
+
+// HandleAssignTask assigns a project member to a task and returns the
+// task's assignees
+func (h *APITaskHandlers) HandleAssignTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req AssignTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	userID, err := strconv.ParseInt(req.UserID, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.taskService.AssignTask(r.Context(), access, taskID, userID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	h.sendAssignees(w, r, taskID)
+}
+
+// HandleUnassignTask removes a user from a task and returns the task's
+// remaining assignees
+func (h *APITaskHandlers) HandleUnassignTask(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	userID, err := parseIDParam(r, "userID")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid user ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.taskService.UnassignTask(r.Context(), access, taskID, userID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	h.sendAssignees(w, r, taskID)
+}
+
+// sendAssignees responds with the current assignees of a task
+func (h *APITaskHandlers) sendAssignees(w http.ResponseWriter, r *http.Request, taskID int64) {
+	assignees, err := h.taskService.ListAssignees(r.Context(), taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"assignees": assigneesToResponse(assignees),
+	})
+}
+
+// HandleAddTaskLabel adds a project label to a task and returns the
+// task's labels
+func (h *APITaskHandlers) HandleAddTaskLabel(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	// Parse JSON request
+	var req TaskLabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	labelID, err := strconv.ParseInt(req.LabelID, 10, 64)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid label ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.taskService.AddTaskLabel(r.Context(), access, taskID, labelID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	h.sendTaskLabels(w, r, taskID)
+}
+
+// HandleRemoveTaskLabel removes a label from a task and returns the task's
+// remaining labels
+func (h *APITaskHandlers) HandleRemoveTaskLabel(w http.ResponseWriter, r *http.Request) {
+	access, ok := getProjectAccess(w, r)
+	if !ok {
+		return
+	}
+
+	taskID, err := parseIDParam(r, "id")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid task ID", "INVALID_ID")
+		return
+	}
+
+	labelID, err := parseIDParam(r, "labelID")
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid label ID", "INVALID_ID")
+		return
+	}
+
+	if err := h.taskService.RemoveTaskLabel(r.Context(), access, taskID, labelID); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	h.sendTaskLabels(w, r, taskID)
+}
+
+// sendTaskLabels responds with the current labels of a task
+func (h *APITaskHandlers) sendTaskLabels(w http.ResponseWriter, r *http.Request, taskID int64) {
+	labels, err := h.taskService.ListTaskLabels(r.Context(), taskID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	sendSuccess(w, map[string]interface{}{
+		"labels": taskLabelsToResponse(labels),
+	})
+}
@@ -0,0 +1,226 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/erickhilda/vugo/internal/middleware"
+)
+
+// Request/Response types
+
+// TwoFactorLoginRequest completes a login with the challenge returned by
+// login and an authenticator or recovery code
+type TwoFactorLoginRequest struct {
+	Challenge string `json:"challenge"`
+	Code      string `json:"code"`
+}
+
+// TwoFactorCodeRequest represents a request confirmed with an
+// authenticator or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DisableTwoFactorRequest represents a request to turn off two-factor
+// authentication
+type DisableTwoFactorRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorStatusResponse represents a user's two-factor authentication
+// settings
+type TwoFactorStatusResponse struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// TwoFactorEnrollmentResponse holds the secret to add to an authenticator
+// app
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// RecoveryCodesResponse holds newly created recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// API Handlers
+
+// HandleTwoFactorLogin completes a login for a user with two-factor
+// authentication and sets the session cookie
+func (h *APIAuthHandlers) HandleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	// Parse JSON request
+	var req TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Challenge == "" || req.Code == "" {
+		sendError(w, http.StatusBadRequest, "Challenge and code are required", "VALIDATION_ERROR")
+		return
+	}
+
+	result, err := h.authService.CompleteLogin(r.Context(), req.Challenge, req.Code, sessionClient(r))
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Set session cookie
+	setSessionCookie(w, result.SessionToken, result.Session.ExpiresAt)
+
+	// Send success response
+	sendSuccess(w, AuthResponse{
+		User: userToResponse(&result.User),
+	})
+}
+
+// HandleTwoFactorStatus returns whether the current user has two-factor
+// authentication enabled
+func (h *APIAuthHandlers) HandleTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	status, err := h.authService.TwoFactorStatus(r.Context(), user.ID)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, TwoFactorStatusResponse{
+		Enabled:           status.Enabled,
+		RecoveryCodesLeft: status.RecoveryCodesLeft,
+	})
+}
+
+// HandleEnrollTwoFactor starts enabling two-factor authentication and
+// returns the secret as an otpauth:// URI to show as a QR code
+func (h *APIAuthHandlers) HandleEnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	enrollment, err := h.authService.EnrollTwoFactor(r.Context(), user)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, TwoFactorEnrollmentResponse{
+		Secret: enrollment.Secret,
+		URI:    enrollment.URI,
+	})
+}
+
+// HandleConfirmTwoFactor enables two-factor authentication with a first
+// code from the authenticator app and returns the recovery codes
+func (h *APIAuthHandlers) HandleConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Code == "" {
+		sendError(w, http.StatusBadRequest, "Code is required", "VALIDATION_ERROR")
+		return
+	}
+
+	codes, err := h.authService.ConfirmTwoFactor(r.Context(), user.ID, req.Code)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
+
+// HandleDisableTwoFactor turns off two-factor authentication for the
+// current user
+func (h *APIAuthHandlers) HandleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Password == "" || req.Code == "" {
+		sendError(w, http.StatusBadRequest, "Password and code are required", "VALIDATION_ERROR")
+		return
+	}
+
+	if err := h.authService.DisableTwoFactor(r.Context(), user.ID, req.Password, req.Code); err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// HandleRegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *APIAuthHandlers) HandleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		sendError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+		return
+	}
+
+	// Parse JSON request
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request body", "INVALID_REQUEST_BODY")
+		return
+	}
+
+	// Validate
+	if req.Code == "" {
+		sendError(w, http.StatusBadRequest, "Code is required", "VALIDATION_ERROR")
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), user.ID, req.Code)
+	if err != nil {
+		sendServiceError(w, err)
+		return
+	}
+
+	// Send success response
+	sendSuccess(w, RecoveryCodesResponse{
+		RecoveryCodes: codes,
+	})
+}
@@ -0,0 +1,252 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned when running a job that is not registered
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when running a job that has not finished
+	// its previous run
+	ErrJobRunning = errors.New("job is already running")
+	// ErrNotStarted is returned when running a job before the scheduler is
+	// started or after it is stopped
+	ErrNotStarted = errors.New("scheduler is not running")
+)
+
+// jitterFraction is how far each wait may be moved from the interval, so
+// jobs started together do not keep running at the same moment
+const jitterFraction = 10 // 10%
+
+// Job is a task run every Interval. Run is given a context that is
+// cancelled when the scheduler stops.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Status describes a job and its last run. Times are zero until the job
+// has run.
+type Status struct {
+	Name           string
+	Interval       time.Duration
+	Running        bool
+	Runs           int
+	Failures       int
+	LastStartedAt  time.Time
+	LastFinishedAt time.Time
+	LastDuration   time.Duration
+	LastError      string
+	NextRunAt      time.Time
+}
+
+// entry is a registered job with its status
+type entry struct {
+	job    Job
+	status Status
+}
+
+// Scheduler runs registered jobs in the background, each on its own
+// schedule. A job never runs twice at the same time; a run that comes up
+// while the previous one is still going is skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	ctx     context.Context
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		entries: make(map[string]*entry),
+	}
+}
+
+// Register adds jobs to the scheduler. Jobs must be registered before
+// Start.
+func (s *Scheduler) Register(jobs ...Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range jobs {
+		if _, ok := s.entries[job.Name]; ok {
+			panic(fmt.Sprintf("jobs: %q registered twice", job.Name))
+		}
+		if job.Interval <= 0 || job.Run == nil {
+			panic(fmt.Sprintf("jobs: %q needs an interval and a run function", job.Name))
+		}
+		s.entries[job.Name] = &entry{
+			job: job,
+			status: Status{
+				Name:     job.Name,
+				Interval: job.Interval,
+			},
+		}
+	}
+}
+
+// Start runs the registered jobs until ctx is cancelled. Each job first
+// runs after a random part of its interval.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ctx = ctx
+	for _, e := range s.entries {
+		first := randomDuration(e.job.Interval / jitterFraction)
+		e.status.NextRunAt = time.Now().Add(first)
+
+		s.wg.Add(1)
+		go s.loop(e, first)
+	}
+}
+
+// Wait blocks until the scheduler has stopped and running jobs have
+// returned
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// RunNow starts a job outside its schedule without waiting for it to
+// finish
+func (s *Scheduler) RunNow(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if s.ctx == nil || s.ctx.Err() != nil {
+		return ErrNotStarted
+	}
+	if e.status.Running {
+		return ErrJobRunning
+	}
+
+	e.status.Running = true
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(e)
+	}()
+	return nil
+}
+
+// Status returns the status of every job, by name
+func (s *Scheduler) Status() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// loop runs a job on its schedule until the scheduler stops
+func (s *Scheduler) loop(e *entry, wait time.Duration) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if s.claim(e) {
+			s.run(e)
+		}
+
+		wait = jitter(e.job.Interval)
+		s.mu.Lock()
+		e.status.NextRunAt = time.Now().Add(wait)
+		s.mu.Unlock()
+		timer.Reset(wait)
+	}
+}
+
+// claim marks a job as running, unless it already is
+func (s *Scheduler) claim(e *entry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e.status.Running {
+		return false
+	}
+	e.status.Running = true
+	return true
+}
+
+// run runs a claimed job once and records the result. Panics are recovered
+// and recorded as failures so one bad run does not stop the server.
+func (s *Scheduler) run(e *entry) {
+	started := time.Now()
+	s.mu.Lock()
+	e.status.LastStartedAt = started
+	s.mu.Unlock()
+
+	err := s.call(e)
+
+	finished := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.status.Running = false
+	e.status.Runs++
+	e.status.LastFinishedAt = finished
+	e.status.LastDuration = finished.Sub(started)
+	e.status.LastError = ""
+	if err != nil {
+		// A run cut short by shutdown is not a failure
+		if s.ctx.Err() != nil {
+			return
+		}
+		e.status.Failures++
+		e.status.LastError = err.Error()
+		log.Printf("Job %s failed: %v", e.job.Name, err)
+	}
+}
+
+// call runs a job's function, turning a panic into an error
+func (s *Scheduler) call(e *entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return e.job.Run(s.ctx)
+}
+
+// jitter returns interval moved randomly by up to jitterFraction either way
+func jitter(interval time.Duration) time.Duration {
+	spread := interval / jitterFraction
+	return interval - spread + randomDuration(2*spread)
+}
+
+// randomDuration returns a random duration below max, or zero if max is not
+// positive
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return rand.N(max)
+}
@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"strconv"
+)
+
+// Message is an email to a single recipient. Body is HTML.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// FromEnv returns an SMTP mailer when SMTP_HOST is set and a log mailer
+// otherwise. SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD and
+// SMTP_FROM configure the SMTP mailer.
+func FromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return NewLogMailer()
+	}
+
+	port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil || port == 0 {
+		port = 587
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "vugo@localhost"
+	}
+
+	return NewSMTPMailer(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	})
+}
@@ -0,0 +1,77 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
+)
+
+// renderer converts GitHub flavored Markdown to HTML. Raw HTML in the
+// source is dropped rather than passed through.
+var renderer = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(parser.WithInlineParsers(
+		util.Prioritized(mentionParser{}, 500),
+	)),
+	goldmark.WithRendererOptions(html.WithHardWraps()),
+)
+
+// policy strips anything from the rendered HTML that could run script or
+// break out of the comment, as a second line of defence behind the
+// renderer. Links get rel="nofollow noopener" and open in a new tab.
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	// Task list items render as disabled checkboxes
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	// Mentions are links marked with the mentioned user
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^mention$`)).OnElements("a")
+	p.AllowAttrs("data-user-id").Matching(regexp.MustCompile(`^[0-9]+$`)).OnElements("a")
+	return p
+}()
+
+// Render converts Markdown to sanitized HTML that is safe to insert into a
+// page as is
+func Render(source string) (string, error) {
+	html, _, err := RenderMentions(source, nil)
+	return html, err
+}
+
+// RenderMentions is Render with @mentions resolved by resolve. Resolved
+// mentions become links to the user and are returned, each user once.
+// Mentions in code are not resolved. A nil resolve leaves all mentions as
+// text.
+func RenderMentions(source string, resolve MentionResolver) (string, []Mention, error) {
+	ctx := parser.NewContext()
+	var state *mentionState
+	if resolve != nil {
+		state = &mentionState{resolve: resolve, seen: make(map[int64]bool)}
+		ctx.Set(mentionKey, state)
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf, parser.WithContext(ctx)); err != nil {
+		return "", nil, err
+	}
+
+	var found []Mention
+	if state != nil {
+		found = state.found
+	}
+	return policy.Sanitize(buf.String()), found, nil
+}
+
+// Mentions returns the users mentioned in Markdown, each once, without
+// rendering it
+func Mentions(source string, resolve MentionResolver) ([]Mention, error) {
+	_, found, err := RenderMentions(source, resolve)
+	return found, err
+}
@@ -0,0 +1,85 @@
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// Mention is a user referred to with @name or @email
+type Mention struct {
+	UserID int64
+	Name   string
+}
+
+// MentionResolver returns the user a mention handle (the text after the @)
+// refers to, if any
+type MentionResolver func(handle string) (Mention, bool)
+
+// mentionHandle matches an email address or a name. Names may contain dots
+// and dashes but do not end in one, so punctuation after a mention is not
+// part of it.
+var mentionHandle = regexp.MustCompile(`^(?:[\p{L}\p{N}._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+|[\p{L}\p{N}_]+(?:[.-][\p{L}\p{N}_]+)*)`)
+
+// mentionKey holds the *mentionState of a render in the parser context
+var mentionKey = parser.NewContextKey()
+
+// mentionState resolves the mentions of a single render and collects the
+// users found, each once
+type mentionState struct {
+	resolve MentionResolver
+	seen    map[int64]bool
+	found   []Mention
+}
+
+// mentionParser turns resolved @mentions into links to the user. Mentions
+// that do not resolve are left as text.
+type mentionParser struct{}
+
+func (mentionParser) Trigger() []byte {
+	return []byte{'@'}
+}
+
+func (mentionParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	state, _ := pc.Get(mentionKey).(*mentionState)
+	if state == nil {
+		return nil
+	}
+
+	// An @ inside a word, such as in an email address, is not a mention
+	prev := block.PrecendingCharacter()
+	if unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '_' {
+		return nil
+	}
+
+	line, segment := block.PeekLine()
+	handle := mentionHandle.Find(line[1:])
+	if handle == nil {
+		return nil
+	}
+
+	mention, ok := state.resolve(string(handle))
+	if !ok {
+		return nil
+	}
+	if !state.seen[mention.UserID] {
+		state.seen[mention.UserID] = true
+		state.found = append(state.found, mention)
+	}
+
+	n := 1 + len(handle)
+	block.Advance(n)
+
+	id := strconv.FormatInt(mention.UserID, 10)
+	link := ast.NewLink()
+	link.Destination = []byte("/users/" + id)
+	link.Title = []byte(mention.Name)
+	link.SetAttributeString("class", []byte("mention"))
+	link.SetAttributeString("data-user-id", []byte(id))
+	link.AppendChild(link, ast.NewTextSegment(segment.WithStop(segment.Start+n)))
+	return link
+}
@@ -2,7 +2,10 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/erickhilda/vugo/internal/database/queries"
 	"github.com/erickhilda/vugo/internal/services"
@@ -14,23 +17,59 @@ type contextKey string
 const (
 	// UserContextKey is the key for storing user in context
 	UserContextKey contextKey = "user"
+	// SessionContextKey is the key for storing the session a request was
+	// made with
+	SessionContextKey contextKey = "session"
+	// TokenScopesContextKey is the key for storing the scopes of the API
+	// token a request was made with
+	TokenScopesContextKey contextKey = "token_scopes"
 )
 
-// AuthMiddleware wraps the auth service
+// CSRFHeader is the header cookie-authenticated requests send their CSRF
+// token in
+const CSRFHeader = "X-CSRF-Token"
+
+// AuthMiddleware wraps the auth service. admins are the emails of the
+// users who may use admin routes.
 type AuthMiddleware struct {
 	authService *services.AuthService
+	admins      map[string]bool
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authService *services.AuthService) *AuthMiddleware {
+func NewAuthMiddleware(authService *services.AuthService, adminEmails []string) *AuthMiddleware {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
 	return &AuthMiddleware{
 		authService: authService,
+		admins:      admins,
 	}
 }
 
-// RequireAuth is a middleware that checks if user is authenticated
+// RequireAuth is a middleware that checks if user is authenticated, either
+// with a session cookie or an API token in the Authorization header
 func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// API tokens get JSON errors, as they are not used from a browser
+		if token, ok := bearerToken(r); ok {
+			ctx, err := m.withAPIToken(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidAPIToken) {
+					sendJSONError(w, http.StatusUnauthorized, err.Error(), "INVALID_API_TOKEN")
+					return
+				}
+				sendJSONError(w, http.StatusInternalServerError, "Internal server error", "INTERNAL_ERROR")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// Get session ID from cookie
 		cookie, err := r.Cookie("session_id")
 		if err != nil {
@@ -40,15 +79,16 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		}
 
 		// Get user from session
-		user, err := m.authService.GetUserBySession(r.Context(), cookie.Value)
+		user, session, err := m.authService.GetUserBySession(r.Context(), cookie.Value)
 		if err != nil {
 			// Invalid or expired session, redirect to login
 			http.Redirect(w, r, "/login", http.StatusSeeOther)
 			return
 		}
 
-		// Inject user into context
+		// Inject user and session into context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
+		ctx = context.WithValue(ctx, SessionContextKey, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -56,6 +96,15 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 // OptionalAuth is a middleware that optionally loads user if session exists
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := bearerToken(r); ok {
+			// Invalid token, continue without user
+			if ctx, err := m.withAPIToken(r.Context(), token); err == nil {
+				r = r.WithContext(ctx)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Get session ID from cookie
 		cookie, err := r.Cookie("session_id")
 		if err != nil {
@@ -65,21 +114,125 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 		}
 
 		// Get user from session (ignore errors)
-		user, err := m.authService.GetUserBySession(r.Context(), cookie.Value)
+		user, session, err := m.authService.GetUserBySession(r.Context(), cookie.Value)
 		if err != nil {
 			// Invalid session, continue without user
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Inject user into context
+		// Inject user and session into context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
+		ctx = context.WithValue(ctx, SessionContextKey, session)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScope rejects requests made with an API token that lacks scope.
+// Session requests are not limited by scopes. Must run after RequireAuth.
+func (m *AuthMiddleware) RequireScope(scope services.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := GetTokenScopesFromContext(r.Context()); ok && !scopes.Has(scope) {
+				sendAccessError(w, services.ErrInsufficientScope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests made with an API token, for account
+// routes such as changing the password or managing tokens. Must run after
+// RequireAuth.
+func (m *AuthMiddleware) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetTokenScopesFromContext(r.Context()); ok {
+			sendJSONError(w, http.StatusForbidden, "This action cannot be performed with an API token", "SESSION_REQUIRED")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin rejects users who are not admins. Admins need a verified
+// email, so nobody can claim an admin's email before they register. Must
+// run after RequireAuth.
+func (m *AuthMiddleware) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := GetUserFromContext(r.Context())
+		if !ok || !user.EmailVerified || !m.admins[strings.ToLower(user.Email)] {
+			sendJSONError(w, http.StatusForbidden, "Admin access required", "ADMIN_REQUIRED")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireCSRF rejects requests that change data with a session cookie but
+// without the session's CSRF token in the X-CSRF-Token header. Requests
+// made with an API token are exempt, as browsers do not send those on
+// their own. Must run after RequireAuth.
+func (m *AuthMiddleware) RequireCSRF(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := GetSessionFromContext(r.Context()); !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie("session_id")
+		token := r.Header.Get(CSRFHeader)
+		if err != nil || token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(services.CSRFToken(cookie.Value))) != 1 {
+			sendJSONError(w, http.StatusForbidden, "Missing or invalid CSRF token", "CSRF_INVALID")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withAPIToken validates an API token and returns ctx with its user and
+// scopes
+func (m *AuthMiddleware) withAPIToken(ctx context.Context, token string) (context.Context, error) {
+	user, scopes, err := m.authService.GetUserByAPIToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, UserContextKey, user)
+	return context.WithValue(ctx, TokenScopesContextKey, scopes), nil
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
 // GetUserFromContext retrieves the user from request context
 func GetUserFromContext(ctx context.Context) (*queries.User, bool) {
 	user, ok := ctx.Value(UserContextKey).(*queries.User)
 	return user, ok
 }
+
+// GetSessionFromContext retrieves the session a request was made with.
+// ok is false for API token requests.
+func GetSessionFromContext(ctx context.Context) (*queries.Session, bool) {
+	session, ok := ctx.Value(SessionContextKey).(*queries.Session)
+	return session, ok
+}
+
+// GetTokenScopesFromContext retrieves the scopes of the API token a
+// request was made with. ok is false for session requests.
+func GetTokenScopesFromContext(ctx context.Context) (services.Scopes, bool) {
+	scopes, ok := ctx.Value(TokenScopesContextKey).(services.Scopes)
+	return scopes, ok
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/services"
+)
+
+func TestRequireCSRF(t *testing.T) {
+	const sessionToken = "session-token"
+	valid := services.CSRFToken(sessionToken)
+
+	tests := []struct {
+		name   string
+		method string
+		// auth is how the request is authenticated, as RequireAuth would
+		// leave it: "session" with the session cookie, "bearer" with an
+		// API token next to a session cookie the browser sent along
+		auth       string
+		csrfToken  string
+		wantStatus int
+	}{
+		{"POST without token", http.MethodPost, "session", "", http.StatusForbidden},
+		{"POST with wrong token", http.MethodPost, "session", "wrong", http.StatusForbidden},
+		{"POST with another session's token", http.MethodPost, "session", services.CSRFToken("other-session"), http.StatusForbidden},
+		{"POST with the session token itself", http.MethodPost, "session", sessionToken, http.StatusForbidden},
+		{"POST with valid token", http.MethodPost, "session", valid, http.StatusOK},
+		{"PATCH with valid token", http.MethodPatch, "session", valid, http.StatusOK},
+		{"DELETE without token", http.MethodDelete, "session", "", http.StatusForbidden},
+		{"GET without token", http.MethodGet, "session", "", http.StatusOK},
+		{"HEAD without token", http.MethodHead, "session", "", http.StatusOK},
+		{"OPTIONS without token", http.MethodOptions, "session", "", http.StatusOK},
+		{"POST with API token", http.MethodPost, "bearer", "", http.StatusOK},
+	}
+
+	m := NewAuthMiddleware(nil, nil)
+	handler := m.RequireCSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/projects", nil)
+			r.AddCookie(&http.Cookie{Name: "session_id", Value: sessionToken})
+			if tt.csrfToken != "" {
+				r.Header.Set(CSRFHeader, tt.csrfToken)
+			}
+
+			ctx := context.WithValue(r.Context(), UserContextKey, &queries.User{ID: 1})
+			switch tt.auth {
+			case "session":
+				ctx = context.WithValue(ctx, SessionContextKey, &queries.Session{ID: 1, UserID: 1})
+			case "bearer":
+				r.Header.Set("Authorization", "Bearer vugo_pat_token")
+				ctx = context.WithValue(ctx, TokenScopesContextKey, services.Scopes{})
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r.WithContext(ctx))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			// The frontend fetches a new token when it sees this code
+			if tt.wantStatus == http.StatusForbidden && !strings.Contains(w.Body.String(), `"CSRF_INVALID"`) {
+				t.Errorf("body = %s, want code CSRF_INVALID", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireCSRFWithoutCookie(t *testing.T) {
+	// A session in the context without the cookie it came from is not
+	// trusted
+	m := NewAuthMiddleware(nil, nil)
+	handler := m.RequireCSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/projects", nil)
+	r.Header.Set(CSRFHeader, services.CSRFToken(""))
+	ctx := context.WithValue(r.Context(), SessionContextKey, &queries.Session{ID: 1, UserID: 1})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r.WithContext(ctx))
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
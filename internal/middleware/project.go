@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/erickhilda/vugo/internal/services"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// ProjectAccessContextKey is the key for storing project access in context
+	ProjectAccessContextKey contextKey = "project_access"
+)
+
+// errInvalidID is returned by resolvers when the URL contains a malformed ID
+var errInvalidID = errors.New("invalid ID")
+
+// ProjectIDResolver finds the ID of the project a request targets
+type ProjectIDResolver func(r *http.Request) (int64, error)
+
+// ProjectMiddleware loads project access and enforces project permissions
+type ProjectMiddleware struct {
+	authorizer *services.ProjectAuthorizer
+}
+
+// NewProjectMiddleware creates a new project middleware
+func NewProjectMiddleware(authorizer *services.ProjectAuthorizer) *ProjectMiddleware {
+	return &ProjectMiddleware{
+		authorizer: authorizer,
+	}
+}
+
+// ProjectFromURL resolves the project ID from a URL parameter
+func ProjectFromURL(param string) ProjectIDResolver {
+	return func(r *http.Request) (int64, error) {
+		return parseURLID(r, param)
+	}
+}
+
+// ProjectFromBoard resolves the project ID from a board ID URL parameter
+func (m *ProjectMiddleware) ProjectFromBoard(param string) ProjectIDResolver {
+	return func(r *http.Request) (int64, error) {
+		boardID, err := parseURLID(r, param)
+		if err != nil {
+			return 0, err
+		}
+		return m.authorizer.ProjectIDForBoard(r.Context(), boardID)
+	}
+}
+
+// ProjectFromColumn resolves the project ID from a column ID URL parameter
+func (m *ProjectMiddleware) ProjectFromColumn(param string) ProjectIDResolver {
+	return func(r *http.Request) (int64, error) {
+		columnID, err := parseURLID(r, param)
+		if err != nil {
+			return 0, err
+		}
+		return m.authorizer.ProjectIDForColumn(r.Context(), columnID)
+	}
+}
+
+// ProjectFromTask resolves the project ID from a task ID URL parameter
+func (m *ProjectMiddleware) ProjectFromTask(param string) ProjectIDResolver {
+	return func(r *http.Request) (int64, error) {
+		taskID, err := parseURLID(r, param)
+		if err != nil {
+			return 0, err
+		}
+		return m.authorizer.ProjectIDForTask(r.Context(), taskID)
+	}
+}
+
+// LoadProject resolves the target project and the current user's role in
+// it. Requests from non-members are rejected with 404. Must run after
+// RequireAuth.
+func (m *ProjectMiddleware) LoadProject(resolve ProjectIDResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := GetUserFromContext(r.Context())
+			if !ok {
+				sendJSONError(w, http.StatusUnauthorized, "Not authenticated", "NOT_AUTHENTICATED")
+				return
+			}
+
+			projectID, err := resolve(r)
+			if err != nil {
+				if errors.Is(err, errInvalidID) {
+					sendJSONError(w, http.StatusBadRequest, "Invalid ID", "INVALID_ID")
+					return
+				}
+				sendAccessError(w, err)
+				return
+			}
+
+			access, err := m.authorizer.Access(r.Context(), projectID, user.ID)
+			if err != nil {
+				sendAccessError(w, err)
+				return
+			}
+			if scopes, ok := GetTokenScopesFromContext(r.Context()); ok {
+				access.Scopes = scopes
+			}
+
+			// Inject project access into context
+			ctx := context.WithValue(r.Context(), ProjectAccessContextKey, access)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequirePermission rejects requests whose project role does not grant
+// perm. Must run after LoadProject.
+func (m *ProjectMiddleware) RequirePermission(perm services.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			access, ok := GetProjectAccessFromContext(r.Context())
+			if !ok {
+				sendJSONError(w, http.StatusNotFound, services.ErrProjectNotFound.Error(), "PROJECT_NOT_FOUND")
+				return
+			}
+
+			if access.Scopes != nil && !access.Scopes.Allow(perm) {
+				sendAccessError(w, services.ErrInsufficientScope)
+				return
+			}
+			if !access.Can(perm) {
+				sendAccessError(w, services.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetProjectAccessFromContext retrieves the project access from request context
+func GetProjectAccessFromContext(ctx context.Context) (*services.ProjectAccess, bool) {
+	access, ok := ctx.Value(ProjectAccessContextKey).(*services.ProjectAccess)
+	return access, ok
+}
+
+// parseURLID reads a numeric ID from a URL parameter
+func parseURLID(r *http.Request, param string) (int64, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, param), 10, 64)
+	if err != nil {
+		return 0, errInvalidID
+	}
+	return id, nil
+}
+
+// sendAccessError maps authorization errors to JSON error responses
+func sendAccessError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrProjectNotFound):
+		sendJSONError(w, http.StatusNotFound, err.Error(), "PROJECT_NOT_FOUND")
+	case errors.Is(err, services.ErrForbidden):
+		sendJSONError(w, http.StatusForbidden, err.Error(), "FORBIDDEN")
+	case errors.Is(err, services.ErrInsufficientScope):
+		sendJSONError(w, http.StatusForbidden, err.Error(), "INSUFFICIENT_SCOPE")
+	default:
+		sendJSONError(w, http.StatusInternalServerError, "Internal server error", "INTERNAL_ERROR")
+	}
+}
+
+// errorResponse mirrors the API error envelope
+type errorResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data"`
+	Error   errorDetail `json:"error"`
+}
+
+// errorDetail mirrors the API error detail
+type errorDetail struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// sendJSONError sends an error in the same envelope as the API handlers
+func sendJSONError(w http.ResponseWriter, statusCode int, message, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(errorResponse{
+		Success: false,
+		Data:    nil,
+		Error: errorDetail{
+			Message: message,
+			Code:    code,
+		},
+	})
+}
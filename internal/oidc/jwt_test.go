@@ -0,0 +1,343 @@
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/oidc/oidctest"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testClientID = "vugo"
+	testNonce    = "nonce-123"
+)
+
+// testJWKS serves signing keys that can be rotated, counting fetches
+type testJWKS struct {
+	mu      sync.Mutex
+	keys    []*oidctest.Key
+	fetches int
+}
+
+func (s *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetches++
+	keys := make([]map[string]string, len(s.keys))
+	for i, key := range s.keys {
+		keys[i] = key.JWK()
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+}
+
+// rotate replaces the served keys
+func (s *testJWKS) rotate(keys ...*oidctest.Key) {
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+}
+
+// fetchCount returns how many times the keys were fetched
+func (s *testJWKS) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+// newTestVerifier returns a provider and the metadata pointing it at a
+// JWKS server serving key
+func newTestVerifier(t *testing.T, key *oidctest.Key) (*Provider, *metadata, *testJWKS) {
+	t.Helper()
+
+	jwks := &testJWKS{keys: []*oidctest.Key{key}}
+	srv := httptest.NewServer(jwks)
+	t.Cleanup(srv.Close)
+
+	p := NewProvider(Config{IssuerURL: testIssuer, ClientID: testClientID})
+	return p, &metadata{Issuer: testIssuer, JWKSURI: srv.URL}, jwks
+}
+
+// newTestKey returns a new signing key
+func newTestKey(t *testing.T) *oidctest.Key {
+	t.Helper()
+
+	key, err := oidctest.NewKey()
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	return key
+}
+
+// testClaims returns valid ID token claims, changed by edit
+func testClaims(edit func(claims map[string]interface{})) map[string]interface{} {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss":            testIssuer,
+		"sub":            "user-1",
+		"aud":            testClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          testNonce,
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"name":           "Alice",
+	}
+	if edit != nil {
+		edit(claims)
+	}
+	return claims
+}
+
+// sign returns claims signed with key
+func sign(t *testing.T, key *oidctest.Key, claims map[string]interface{}) string {
+	t.Helper()
+
+	token, err := key.Sign(claims)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+// forge returns a token with a header of its own and a signature made by
+// mac, or no signature when mac is nil
+func forge(t *testing.T, header, claims map[string]interface{}, mac func(signed string) []byte) string {
+	t.Helper()
+
+	signed, err := oidctest.Encode(header, claims)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if mac == nil {
+		return signed + "."
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac(signed))
+}
+
+func TestVerify(t *testing.T) {
+	key := newTestKey(t)
+	other := newTestKey(t)
+	// A key of its own published under the provider's key ID
+	impostor := newTestKey(t)
+	impostor.ID = key.ID
+
+	// The public key as an attacker would find it, used as an HMAC secret
+	der, err := x509.MarshalPKIXPublicKey(&key.Private.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	hs256 := func(signed string) []byte {
+		h := hmac.New(sha256.New, publicPEM)
+		h.Write([]byte(signed))
+		return h.Sum(nil)
+	}
+
+	tampered := func() string {
+		parts := strings.Split(sign(t, key, testClaims(nil)), ".")
+		forged, err := oidctest.Encode(map[string]interface{}{}, testClaims(func(c map[string]interface{}) {
+			c["email"] = "mallory@example.com"
+		}))
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		parts[1] = strings.Split(forged, ".")[1]
+		return strings.Join(parts, ".")
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			token: sign(t, key, testClaims(nil)),
+		},
+		{
+			name: "audience list with azp",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["aud"] = []string{testClientID, "other"}
+				c["azp"] = testClientID
+			})),
+		},
+		{
+			name: "expired within clock skew",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+			})),
+		},
+		{
+			name:    "malformed",
+			token:   "not-a-jwt",
+			wantErr: "malformed",
+		},
+		{
+			name:    "tampered claims",
+			token:   tampered(),
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "signed by another key with the same ID",
+			token:   sign(t, impostor, testClaims(nil)),
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "signed by an unknown key",
+			token:   sign(t, other, testClaims(nil)),
+			wantErr: "unknown signing key",
+		},
+		{
+			name:    "alg none",
+			token:   forge(t, map[string]interface{}{"alg": "none", "kid": key.ID}, testClaims(nil), nil),
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name:    "HS256 with the public key as secret",
+			token:   forge(t, map[string]interface{}{"alg": "HS256", "kid": key.ID}, testClaims(nil), hs256),
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name: "wrong issuer",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["iss"] = "https://evil.example.com"
+			})),
+			wantErr: "issuer",
+		},
+		{
+			name: "wrong audience",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["aud"] = "other"
+			})),
+			wantErr: "not issued for this client",
+		},
+		{
+			name: "audience list without azp",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["aud"] = []string{testClientID, "other"}
+			})),
+			wantErr: "azp",
+		},
+		{
+			name: "wrong azp",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["aud"] = []string{testClientID, "other"}
+				c["azp"] = "other"
+			})),
+			wantErr: "azp",
+		},
+		{
+			name: "expired",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["exp"] = time.Now().Add(-2 * clockSkew).Unix()
+			})),
+			wantErr: "expired",
+		},
+		{
+			name: "issued in the future",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["iat"] = time.Now().Add(2 * clockSkew).Unix()
+			})),
+			wantErr: "issued in the future",
+		},
+		{
+			name: "nonce mismatch",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				c["nonce"] = "other-nonce"
+			})),
+			wantErr: "nonce does not match",
+		},
+		{
+			name: "missing subject",
+			token: sign(t, key, testClaims(func(c map[string]interface{}) {
+				delete(c, "sub")
+			})),
+			wantErr: "missing subject",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, meta, _ := newTestVerifier(t, key)
+
+			claims, err := p.verify(context.Background(), meta, tt.token, testNonce)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verify: %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+
+			want := Claims{
+				Issuer:        testIssuer,
+				Subject:       "user-1",
+				Email:         "alice@example.com",
+				EmailVerified: true,
+				Name:          "Alice",
+			}
+			if *claims != want {
+				t.Errorf("claims = %+v, want %+v", *claims, want)
+			}
+		})
+	}
+}
+
+func TestVerifyRefreshesKeys(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := newTestKey(t), newTestKey(t)
+	p, meta, jwks := newTestVerifier(t, oldKey)
+
+	if _, err := p.verify(ctx, meta, sign(t, oldKey, testClaims(nil)), testNonce); err != nil {
+		t.Fatalf("verify with the first key: %v", err)
+	}
+	if n := jwks.fetchCount(); n != 1 {
+		t.Fatalf("keys fetched %d times, want 1", n)
+	}
+
+	// The provider rotates its key. An unknown key ID only fetches the
+	// keys again once keyRefreshInterval has passed, so tokens with made
+	// up key IDs cannot make us hammer the provider.
+	jwks.rotate(newKey)
+	token := sign(t, newKey, testClaims(nil))
+	if _, err := p.verify(ctx, meta, token, testNonce); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("verify right after fetching: %v, want unknown signing key", err)
+	}
+	if n := jwks.fetchCount(); n != 1 {
+		t.Fatalf("keys fetched %d times right after fetching, want 1", n)
+	}
+
+	p.mu.Lock()
+	p.keys.fetched = time.Now().Add(-keyRefreshInterval)
+	p.mu.Unlock()
+
+	if _, err := p.verify(ctx, meta, token, testNonce); err != nil {
+		t.Fatalf("verify with the rotated key: %v", err)
+	}
+	if n := jwks.fetchCount(); n != 2 {
+		t.Fatalf("keys fetched %d times, want 2", n)
+	}
+
+	// Known keys are used from the cache
+	if _, err := p.verify(ctx, meta, sign(t, newKey, testClaims(nil)), testNonce); err != nil {
+		t.Fatalf("verify again: %v", err)
+	}
+	if n := jwks.fetchCount(); n != 2 {
+		t.Errorf("keys fetched %d times for a known key, want 2", n)
+	}
+}
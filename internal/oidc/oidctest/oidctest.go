@@ -0,0 +1,81 @@
+// Package oidctest provides the signing key of the mock identity provider
+// in cmd/mockidp, for signing ID tokens in tests
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+)
+
+// Key is an RSA signing key. Its ID is the key's JWK thumbprint, so a
+// provider that is restarted with a new key also has a new key ID.
+type Key struct {
+	ID      string
+	Private *rsa.PrivateKey
+}
+
+// NewKey generates a signing key
+func NewKey() (*Key, error) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Key{Private: private}
+	// The thumbprint hashes the required members in lexical order
+	// (RFC 7638 section 3.2)
+	jwk := k.JWK()
+	thumbprint, err := json.Marshal(map[string]string{"e": jwk["e"], "kty": jwk["kty"], "n": jwk["n"]})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return k, nil
+}
+
+// JWK returns the public key as a JWKS key
+func (k *Key) JWK() map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": k.ID,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(k.Private.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.Private.E)).Bytes()),
+	}
+}
+
+// Sign returns claims as an RS256 JWT
+func (k *Key) Sign(claims map[string]interface{}) (string, error) {
+	signed, err := Encode(map[string]interface{}{"alg": "RS256", "typ": "JWT", "kid": k.ID}, claims)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, k.Private, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// Encode returns the header and claims of a JWT without its signature,
+// for signing them or forging tokens in tests
+func Encode(header, claims map[string]interface{}) (string, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c), nil
+}
@@ -1,28 +1,109 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/erickhilda/vugo/internal/collab"
 	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
 	"github.com/erickhilda/vugo/internal/handlers"
 	"github.com/erickhilda/vugo/internal/handlers/api"
+	"github.com/erickhilda/vugo/internal/jobs"
+	"github.com/erickhilda/vugo/internal/mailer"
 	authMiddleware "github.com/erickhilda/vugo/internal/middleware"
+	"github.com/erickhilda/vugo/internal/oidc"
 	"github.com/erickhilda/vugo/internal/services"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 )
 
+// allowedOrigins are the browser origins allowed to call the API
+var allowedOrigins = []string{"http://localhost:8080", "http://localhost:5173"}
+
+// appURL returns the address the app is served at, used for links in
+// emails. APP_URL overrides the default.
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return url
+	}
+	return allowedOrigins[0]
+}
+
+// sessionTimeouts returns how long sessions last. SESSION_IDLE_TIMEOUT and
+// SESSION_ABSOLUTE_TIMEOUT override the defaults with durations such as
+// "12h".
+func sessionTimeouts() services.SessionTimeouts {
+	timeouts := services.DefaultSessionTimeouts
+	for name, timeout := range map[string]*time.Duration{
+		"SESSION_IDLE_TIMEOUT":     &timeouts.Idle,
+		"SESSION_ABSOLUTE_TIMEOUT": &timeouts.Absolute,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			log.Printf("Ignoring invalid %s %q", name, value)
+			continue
+		}
+		*timeout = d
+	}
+	return timeouts
+}
+
+// loginAttemptStore returns where failed logins are tracked.
+// LOGIN_ATTEMPT_STORE=sqlite keeps them in the database so limits survive
+// restarts; by default they are kept in memory.
+func loginAttemptStore(q *queries.Queries) services.LoginAttemptStore {
+	switch store := os.Getenv("LOGIN_ATTEMPT_STORE"); store {
+	case "sqlite":
+		return services.NewSQLiteLoginAttemptStore(q)
+	case "", "memory":
+	default:
+		log.Printf("Ignoring unknown LOGIN_ATTEMPT_STORE %q, using memory", store)
+	}
+	return services.NewMemoryLoginAttemptStore()
+}
+
 // Server wraps the HTTP server and dependencies
 type Server struct {
-	db              *sql.DB
-	router          *chi.Mux
-	authService     *services.AuthService
-	apiAuthHandlers *api.APIAuthHandlers
-	authMW          *authMiddleware.AuthMiddleware
+	db                      *sql.DB
+	router                  *chi.Mux
+	authService             *services.AuthService
+	loginLimiter            *services.LoginLimiter
+	ssoService              *services.SSOService
+	projectService          *services.ProjectService
+	projectAuthorizer       *services.ProjectAuthorizer
+	boardService            *services.BoardService
+	taskService             *services.TaskService
+	activityService         *services.ActivityService
+	commentService          *services.CommentService
+	notificationService     *services.NotificationService
+	rankRebalancer          *services.RankRebalancer
+	eventBroker             *events.Broker
+	collabHub               *collab.Hub
+	apiAuthHandlers         *api.APIAuthHandlers
+	apiSSOHandlers          *api.APISSOHandlers
+	apiProjectHandlers      *api.APIProjectHandlers
+	apiBoardHandlers        *api.APIBoardHandlers
+	apiTaskHandlers         *api.APITaskHandlers
+	apiActivityHandlers     *api.APIActivityHandlers
+	apiCommentHandlers      *api.APICommentHandlers
+	apiNotificationHandlers *api.APINotificationHandlers
+	apiEventHandlers        *api.APIEventHandlers
+	apiCollabHandlers       *api.APICollabHandlers
+	apiAdminHandlers        *api.APIAdminHandlers
+	scheduler               *jobs.Scheduler
+	authMW                  *authMiddleware.AuthMiddleware
+	projectMW               *authMiddleware.ProjectMiddleware
 }
 
 // New creates a new server instance
@@ -34,13 +115,44 @@ func New(db *sql.DB) *Server {
 
 	// Initialize services
 	queries := queries.New(db)
-	s.authService = services.NewAuthService(queries)
+	mail := mailer.FromEnv()
+	s.loginLimiter = services.NewLoginLimiter(loginAttemptStore(queries), queries, services.AccountLoginPolicy, services.IPLoginPolicy)
+	s.authService = services.NewAuthService(db, queries, mail, appURL(), os.Getenv("PASSWORD_LOGIN_DISABLED") != "true", sessionTimeouts(), s.loginLimiter)
+	s.ssoService = services.NewSSOService(db, queries, s.authService, oidc.FromEnv(strings.TrimSuffix(appURL(), "/")+"/api/auth/oidc/callback"))
+	s.projectService = services.NewProjectService(db, queries, os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true")
+	s.projectAuthorizer = services.NewProjectAuthorizer(queries)
+	s.rankRebalancer = services.NewRankRebalancer(db, queries)
+	s.eventBroker = events.NewBroker()
+	s.collabHub = collab.NewHub()
+	s.boardService = services.NewBoardService(db, queries, s.rankRebalancer, s.eventBroker)
+	s.taskService = services.NewTaskService(db, queries, s.rankRebalancer, s.eventBroker)
+	s.activityService = services.NewActivityService(queries)
+	s.commentService = services.NewCommentService(db, queries, s.eventBroker)
+	s.notificationService = services.NewNotificationService(db, queries, s.eventBroker, mail, appURL())
+
+	// Register background jobs
+	s.scheduler = jobs.NewScheduler()
+	s.scheduler.Register(s.authService.Jobs()...)
+	s.scheduler.Register(s.loginLimiter.Jobs()...)
+	s.scheduler.Register(s.ssoService.Jobs()...)
+	s.scheduler.Register(s.notificationService.Jobs()...)
 
 	// Initialize middleware
-	s.authMW = authMiddleware.NewAuthMiddleware(s.authService)
+	s.authMW = authMiddleware.NewAuthMiddleware(s.authService, strings.Split(os.Getenv("ADMIN_EMAILS"), ","))
+	s.projectMW = authMiddleware.NewProjectMiddleware(s.projectAuthorizer)
 
 	// Initialize API handlers
 	s.apiAuthHandlers = api.NewAPIAuthHandlers(s.authService)
+	s.apiSSOHandlers = api.NewAPISSOHandlers(s.ssoService, s.authService)
+	s.apiProjectHandlers = api.NewAPIProjectHandlers(s.projectService)
+	s.apiBoardHandlers = api.NewAPIBoardHandlers(s.boardService)
+	s.apiTaskHandlers = api.NewAPITaskHandlers(s.taskService)
+	s.apiActivityHandlers = api.NewAPIActivityHandlers(s.activityService)
+	s.apiCommentHandlers = api.NewAPICommentHandlers(s.commentService)
+	s.apiNotificationHandlers = api.NewAPINotificationHandlers(s.notificationService)
+	s.apiEventHandlers = api.NewAPIEventHandlers(s.eventBroker)
+	s.apiAdminHandlers = api.NewAPIAdminHandlers(s.scheduler, s.loginLimiter)
+	s.apiCollabHandlers = api.NewAPICollabHandlers(s.authService, s.projectAuthorizer, s.eventBroker, s.collabHub, allowedOrigins)
 
 	s.setupMiddleware()
 	s.setupRoutes()
@@ -48,6 +160,20 @@ func New(db *sql.DB) *Server {
 	return s
 }
 
+// StartJobs runs the scheduled background jobs and the rank rebalancer
+// until ctx is cancelled
+func (s *Server) StartJobs(ctx context.Context) {
+	s.scheduler.Start(ctx)
+	s.rankRebalancer.Start(ctx)
+}
+
+// WaitJobs blocks until the background jobs have stopped after the context
+// given to StartJobs is cancelled
+func (s *Server) WaitJobs() {
+	s.scheduler.Wait()
+	s.rankRebalancer.Wait()
+}
+
 // setupMiddleware configures middleware
 func (s *Server) setupMiddleware() {
 	// Request ID for tracing
@@ -61,7 +187,7 @@ func (s *Server) setupMiddleware() {
 
 	// CORS
 	s.router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:8080", "http://localhost:5173"},
+		AllowedOrigins:   allowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
@@ -82,13 +208,159 @@ func (s *Server) setupRoutes() {
 	s.router.Route("/api", func(r chi.Router) {
 		// Auth API routes (public)
 		r.Post("/auth/login", s.apiAuthHandlers.HandleLogin)
+		r.Post("/auth/login/2fa", s.apiAuthHandlers.HandleTwoFactorLogin)
 		r.Post("/auth/register", s.apiAuthHandlers.HandleRegister)
+		r.Post("/auth/forgot-password", s.apiAuthHandlers.HandleForgotPassword)
+		r.Post("/auth/reset-password", s.apiAuthHandlers.HandleResetPassword)
+		r.Post("/auth/verify", s.apiAuthHandlers.HandleVerifyEmail)
+		r.Get("/auth/options", s.apiSSOHandlers.HandleAuthOptions)
+		r.Get("/auth/oidc/login", s.apiSSOHandlers.HandleSSOLogin)
+		r.Get("/auth/oidc/callback", s.apiSSOHandlers.HandleSSOCallback)
+
+		// Board collaboration socket, which checks the session itself
+		r.Get("/boards/{id}/ws", s.apiCollabHandlers.HandleBoardSocket)
 
 		// Protected API routes
 		r.Group(func(r chi.Router) {
 			r.Use(s.authMW.RequireAuth)
+			r.Use(s.authMW.RequireCSRF)
+			scope := s.authMW.RequireScope
 			r.Get("/auth/me", s.apiAuthHandlers.HandleMe)
-			r.Post("/auth/logout", s.apiAuthHandlers.HandleLogout)
+
+			// Account routes cannot be used with API tokens
+			r.Group(func(r chi.Router) {
+				r.Use(s.authMW.RequireSession)
+				r.Patch("/auth/me", s.apiAuthHandlers.HandleUpdateMe)
+				r.Post("/auth/change-password", s.apiAuthHandlers.HandleChangePassword)
+				r.Post("/auth/verify/resend", s.apiAuthHandlers.HandleResendVerification)
+				r.Get("/auth/2fa", s.apiAuthHandlers.HandleTwoFactorStatus)
+				r.Post("/auth/2fa/enroll", s.apiAuthHandlers.HandleEnrollTwoFactor)
+				r.Post("/auth/2fa/confirm", s.apiAuthHandlers.HandleConfirmTwoFactor)
+				r.Post("/auth/2fa/disable", s.apiAuthHandlers.HandleDisableTwoFactor)
+				r.Post("/auth/2fa/recovery-codes", s.apiAuthHandlers.HandleRegenerateRecoveryCodes)
+				r.Post("/auth/oidc/link", s.apiSSOHandlers.HandleSSOLink)
+				r.Get("/auth/tokens", s.apiAuthHandlers.HandleListAPITokens)
+				r.Post("/auth/tokens", s.apiAuthHandlers.HandleCreateAPIToken)
+				r.Delete("/auth/tokens/{id}", s.apiAuthHandlers.HandleRevokeAPIToken)
+				r.Get("/auth/csrf", s.apiAuthHandlers.HandleCSRFToken)
+				r.Get("/auth/sessions", s.apiAuthHandlers.HandleListSessions)
+				r.Delete("/auth/sessions", s.apiAuthHandlers.HandleRevokeAllSessions)
+				r.Delete("/auth/sessions/{id}", s.apiAuthHandlers.HandleRevokeSession)
+				r.Post("/auth/logout", s.apiAuthHandlers.HandleLogout)
+			})
+
+			// Admin routes, for the users in ADMIN_EMAILS
+			r.Group(func(r chi.Router) {
+				r.Use(s.authMW.RequireSession)
+				r.Use(s.authMW.RequireAdmin)
+				r.Get("/admin/jobs", s.apiAdminHandlers.HandleListJobs)
+				r.Post("/admin/jobs/{name}/run", s.apiAdminHandlers.HandleRunJob)
+				r.Get("/admin/security-events", s.apiAdminHandlers.HandleListSecurityEvents)
+			})
+
+			// Notification API routes
+			r.With(scope(services.ScopeReadNotifications)).Get("/notifications", s.apiNotificationHandlers.HandleListNotifications)
+			r.With(scope(services.ScopeReadNotifications)).Get("/notifications/unread-count", s.apiNotificationHandlers.HandleUnreadCount)
+			r.With(scope(services.ScopeReadNotifications)).Get("/notifications/events", s.apiEventHandlers.HandleNotificationEvents)
+			r.With(scope(services.ScopeReadNotifications)).Get("/notifications/preferences", s.apiNotificationHandlers.HandleGetPreferences)
+			r.With(scope(services.ScopeWriteNotifications)).Put("/notifications/preferences", s.apiNotificationHandlers.HandleUpdatePreferences)
+			r.With(scope(services.ScopeWriteNotifications)).Post("/notifications/read-all", s.apiNotificationHandlers.HandleMarkAllRead)
+			r.With(scope(services.ScopeWriteNotifications)).Post("/notifications/{id}/read", s.apiNotificationHandlers.HandleMarkRead)
+
+			// Project API routes
+			r.With(scope(services.ScopeReadProjects)).Get("/projects", s.apiProjectHandlers.HandleListProjects)
+			r.With(scope(services.ScopeWriteProjects)).Post("/projects", s.apiProjectHandlers.HandleCreateProject)
+
+			// Project-scoped routes go through the project authorizer
+			r.Route("/projects/{id}", func(r chi.Router) {
+				r.Use(s.projectMW.LoadProject(authMiddleware.ProjectFromURL("id")))
+				can := s.projectMW.RequirePermission
+
+				r.With(can(services.PermViewProject)).Get("/", s.apiProjectHandlers.HandleGetProject)
+				r.With(can(services.PermEditProject)).Put("/", s.apiProjectHandlers.HandleUpdateProject)
+				r.With(can(services.PermDeleteProject)).Delete("/", s.apiProjectHandlers.HandleDeleteProject)
+				r.With(can(services.PermArchiveProject)).Post("/archive", s.apiProjectHandlers.HandleArchiveProject)
+				r.With(can(services.PermArchiveProject)).Post("/unarchive", s.apiProjectHandlers.HandleUnarchiveProject)
+				r.With(can(services.PermTransferProject)).Post("/transfer", s.apiProjectHandlers.HandleTransferProject)
+
+				// Members
+				r.With(can(services.PermViewProject)).Get("/members", s.apiProjectHandlers.HandleListMembers)
+				r.With(can(services.PermManageMembers)).Post("/members", s.apiProjectHandlers.HandleAddMember)
+				r.With(can(services.PermManageMembers)).Patch("/members/{userID}", s.apiProjectHandlers.HandleUpdateMember)
+				r.With(can(services.PermManageMembers)).Delete("/members/{userID}", s.apiProjectHandlers.HandleRemoveMember)
+
+				// Boards
+				r.With(can(services.PermViewProject)).Get("/boards", s.apiBoardHandlers.HandleListBoards)
+				r.With(can(services.PermManageBoards)).Post("/boards", s.apiBoardHandlers.HandleCreateBoard)
+
+				// Activity
+				r.With(can(services.PermViewProject)).Get("/activity", s.apiActivityHandlers.HandleListProjectActivity)
+			})
+
+			// Board API routes
+			r.Route("/boards/{id}", func(r chi.Router) {
+				r.Use(s.projectMW.LoadProject(s.projectMW.ProjectFromBoard("id")))
+				can := s.projectMW.RequirePermission
+
+				r.With(can(services.PermViewProject)).Get("/", s.apiBoardHandlers.HandleGetBoard)
+				r.With(can(services.PermManageBoards)).Put("/", s.apiBoardHandlers.HandleUpdateBoard)
+				r.With(can(services.PermManageBoards)).Delete("/", s.apiBoardHandlers.HandleDeleteBoard)
+				r.With(can(services.PermManageBoards)).Put("/position", s.apiBoardHandlers.HandleUpdateBoardPosition)
+				r.With(can(services.PermViewProject)).Get("/events", s.apiEventHandlers.HandleBoardEvents)
+
+				// Columns
+				r.With(can(services.PermViewProject)).Get("/columns", s.apiBoardHandlers.HandleListColumns)
+				r.With(can(services.PermManageBoards)).Post("/columns", s.apiBoardHandlers.HandleCreateColumn)
+			})
+
+			// Column API routes
+			r.Route("/columns/{id}", func(r chi.Router) {
+				r.Use(s.projectMW.LoadProject(s.projectMW.ProjectFromColumn("id")))
+				can := s.projectMW.RequirePermission
+
+				r.With(can(services.PermManageBoards)).Put("/", s.apiBoardHandlers.HandleUpdateColumn)
+				r.With(can(services.PermManageBoards)).Delete("/", s.apiBoardHandlers.HandleDeleteColumn)
+				r.With(can(services.PermManageBoards)).Put("/position", s.apiBoardHandlers.HandleUpdateColumnPosition)
+
+				// Tasks
+				r.With(can(services.PermEditTasks)).Post("/tasks", s.apiTaskHandlers.HandleCreateTask)
+			})
+
+			// Task API routes
+			r.Route("/tasks/{id}", func(r chi.Router) {
+				r.Use(s.projectMW.LoadProject(s.projectMW.ProjectFromTask("id")))
+				can := s.projectMW.RequirePermission
+
+				r.With(can(services.PermViewProject)).Get("/", s.apiTaskHandlers.HandleGetTask)
+				r.With(can(services.PermViewProject)).Get("/report", s.apiTaskHandlers.HandleTaskReport)
+				r.With(can(services.PermEditTasks)).Put("/", s.apiTaskHandlers.HandleUpdateTask)
+				r.With(can(services.PermEditTasks)).Delete("/", s.apiTaskHandlers.HandleDeleteTask)
+				r.With(can(services.PermEditTasks)).Patch("/move", s.apiTaskHandlers.HandleMoveTask)
+				r.With(can(services.PermEditTasks)).Post("/complete", s.apiTaskHandlers.HandleCompleteTask)
+				r.With(can(services.PermEditTasks)).Post("/uncomplete", s.apiTaskHandlers.HandleUncompleteTask)
+
+				// Assignees
+				r.With(can(services.PermEditTasks)).Post("/assignees", s.apiTaskHandlers.HandleAssignTask)
+				r.With(can(services.PermEditTasks)).Delete("/assignees/{userID}", s.apiTaskHandlers.HandleUnassignTask)
+
+				// Labels
+				r.With(can(services.PermEditTasks)).Post("/labels", s.apiTaskHandlers.HandleAddTaskLabel)
+				r.With(can(services.PermEditTasks)).Delete("/labels/{labelID}", s.apiTaskHandlers.HandleRemoveTaskLabel)
+
+				// Comments
+				r.With(can(services.PermViewProject)).Get("/comments", s.apiCommentHandlers.HandleListComments)
+				r.With(can(services.PermEditTasks)).Post("/comments", s.apiCommentHandlers.HandleCreateComment)
+				r.With(can(services.PermEditTasks)).Put("/comments/{commentID}", s.apiCommentHandlers.HandleUpdateComment)
+				r.With(can(services.PermEditTasks)).Delete("/comments/{commentID}", s.apiCommentHandlers.HandleDeleteComment)
+
+				// Watching
+				r.With(can(services.PermViewProject)).Get("/watch", s.apiNotificationHandlers.HandleGetWatching)
+				r.With(can(services.PermWatchTasks)).Post("/watch", s.apiNotificationHandlers.HandleWatchTask)
+				r.With(can(services.PermWatchTasks)).Delete("/watch", s.apiNotificationHandlers.HandleUnwatchTask)
+
+				// Activity
+				r.With(can(services.PermViewProject)).Get("/activity", s.apiActivityHandlers.HandleListTaskActivity)
+			})
 		})
 	})
 
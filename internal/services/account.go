@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrIncorrectPassword is returned when the current password given to
+// confirm an account change is wrong
+var ErrIncorrectPassword = errors.New("current password is incorrect")
+
+// maxAvatarURLLength is the longest avatar URL accepted
+const maxAvatarURLLength = 2048
+
+// ProfileUpdate holds the profile fields to change. Nil fields are left
+// as they are; an empty avatar URL removes the avatar.
+type ProfileUpdate struct {
+	Name      *string
+	AvatarURL *string
+}
+
+// UpdateProfile changes a user's name and avatar and returns the updated
+// user
+func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, update ProfileUpdate) (*queries.User, error) {
+	user, err := s.queries.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := queries.UpdateUserParams{
+		Name:      user.Name,
+		AvatarUrl: user.AvatarUrl,
+		ID:        user.ID,
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if len(name) < 2 || len(name) > 100 {
+			return nil, newValidationError("name must be between 2 and 100 characters")
+		}
+		params.Name = name
+	}
+
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" && !isWebURL(avatarURL) {
+			return nil, newValidationError("avatar_url must be an http or https URL of at most 2048 characters")
+		}
+		params.AvatarUrl = sql.NullString{String: avatarURL, Valid: avatarURL != ""}
+	}
+
+	updated, err := s.queries.UpdateUser(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ChangePassword replaces a user's password after checking the current
+// one. All of the user's other sessions and pending password resets are
+// revoked; keepSessionID stays signed in.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, keepSessionID int64, currentPassword, newPassword string) error {
+	user, err := s.queries.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrIncorrectPassword
+	}
+
+	if err := validatePassword(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		if err := qtx.UpdateUserPassword(ctx, queries.UpdateUserPasswordParams{
+			PasswordHash: string(hashedPassword),
+			ID:           user.ID,
+		}); err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteOtherUserSessions(ctx, queries.DeleteOtherUserSessionsParams{
+			UserID: user.ID,
+			ID:     keepSessionID,
+		}); err != nil {
+			return err
+		}
+		return qtx.DeleteUserPasswordResetTokens(ctx, user.ID)
+	})
+}
+
+// isWebURL reports whether s is an absolute http or https URL of an
+// acceptable length
+func isWebURL(s string) bool {
+	if len(s) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
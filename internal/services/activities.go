@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+// Actions recorded in the project activity log
+const (
+	ActivityBoardCreated = "board_created"
+	ActivityBoardUpdated = "board_updated"
+	ActivityBoardMoved   = "board_moved"
+	ActivityBoardDeleted = "board_deleted"
+
+	ActivityColumnCreated = "column_created"
+	ActivityColumnUpdated = "column_updated"
+	ActivityColumnMoved   = "column_moved"
+	ActivityColumnDeleted = "column_deleted"
+
+	ActivityTaskCreated      = "task_created"
+	ActivityTaskUpdated      = "task_updated"
+	ActivityTaskMoved        = "task_moved"
+	ActivityTaskCompleted    = "task_completed"
+	ActivityTaskUncompleted  = "task_uncompleted"
+	ActivityTaskDeleted      = "task_deleted"
+	ActivityTaskAssigned     = "task_assigned"
+	ActivityTaskUnassigned   = "task_unassigned"
+	ActivityTaskLabelAdded   = "task_label_added"
+	ActivityTaskLabelRemoved = "task_label_removed"
+
+	ActivityCommentCreated = "comment_created"
+	ActivityCommentUpdated = "comment_updated"
+	ActivityCommentDeleted = "comment_deleted"
+
+	ActivityMemberAdded        = "member_added"
+	ActivityMemberRoleChanged  = "member_role_changed"
+	ActivityMemberRemoved      = "member_removed"
+	ActivityProjectTransferred = "project_transferred"
+
+	ActivityWipLimitOverridden = "wip_limit_overridden"
+)
+
+// ActivityService handles reading the project activity log
+type ActivityService struct {
+	queries *queries.Queries
+}
+
+// NewActivityService creates a new activity service
+func NewActivityService(q *queries.Queries) *ActivityService {
+	return &ActivityService{
+		queries: q,
+	}
+}
+
+// ActivityEntry is an activity together with the user who performed it
+type ActivityEntry struct {
+	Activity      queries.Activity
+	UserName      string
+	UserEmail     string
+	UserAvatarURL string
+}
+
+// ActivityPage is one page of the activity log, newest first
+type ActivityPage struct {
+	Entries []ActivityEntry
+	HasMore bool
+}
+
+// ListProjectActivity returns a page of a project's activity log
+func (s *ActivityService) ListProjectActivity(ctx context.Context, projectID, limit, offset int64) (*ActivityPage, error) {
+	// Fetch one extra row to tell whether another page follows
+	rows, err := s.queries.ListActivitiesByProject(ctx, queries.ListActivitiesByProjectParams{
+		ProjectID: projectID,
+		Limit:     limit + 1,
+		Offset:    offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ActivityEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, ActivityEntry{
+			Activity: queries.Activity{
+				ID:        row.ID,
+				ProjectID: row.ProjectID,
+				UserID:    row.UserID,
+				TaskID:    row.TaskID,
+				Action:    row.Action,
+				Details:   row.Details,
+				CreatedAt: row.CreatedAt,
+			},
+			UserName:      row.UserName,
+			UserEmail:     row.UserEmail,
+			UserAvatarURL: row.UserAvatarUrl.String,
+		})
+	}
+
+	return newActivityPage(entries, limit), nil
+}
+
+// ListTaskActivity returns a page of a task's activity log
+func (s *ActivityService) ListTaskActivity(ctx context.Context, taskID, limit, offset int64) (*ActivityPage, error) {
+	rows, err := s.queries.ListActivitiesByTask(ctx, queries.ListActivitiesByTaskParams{
+		TaskID: sql.NullInt64{Int64: taskID, Valid: true},
+		Limit:  limit + 1,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ActivityEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, ActivityEntry{
+			Activity: queries.Activity{
+				ID:        row.ID,
+				ProjectID: row.ProjectID,
+				UserID:    row.UserID,
+				TaskID:    row.TaskID,
+				Action:    row.Action,
+				Details:   row.Details,
+				CreatedAt: row.CreatedAt,
+			},
+			UserName:      row.UserName,
+			UserEmail:     row.UserEmail,
+			UserAvatarURL: row.UserAvatarUrl.String,
+		})
+	}
+
+	return newActivityPage(entries, limit), nil
+}
+
+// newActivityPage trims entries fetched with one extra row to limit
+func newActivityPage(entries []ActivityEntry, limit int64) *ActivityPage {
+	page := &ActivityPage{Entries: entries}
+	if int64(len(entries)) > limit {
+		page.Entries = entries[:limit]
+		page.HasMore = true
+	}
+	return page
+}
+
+// inTx runs fn in a transaction, so a mutation and the activity it records
+// are stored together or not at all
+func inTx(ctx context.Context, db *sql.DB, q *queries.Queries, fn func(qtx *queries.Queries) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// recordActivity writes an entry to the project activity log. A zero
+// taskID records a project-level activity and details, if not nil, is
+// stored as JSON.
+func recordActivity(ctx context.Context, q *queries.Queries, projectID, userID, taskID int64, action string, details interface{}) error {
+	var detailsJSON sql.NullString
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		detailsJSON = sql.NullString{String: string(b), Valid: true}
+	}
+
+	_, err := q.CreateActivity(ctx, queries.CreateActivityParams{
+		ProjectID: projectID,
+		UserID:    userID,
+		TaskID:    sql.NullInt64{Int64: taskID, Valid: taskID != 0},
+		Action:    action,
+		Details:   detailsJSON,
+	})
+	return err
+}
+
+// fieldChange is the old and new value of a changed field
+type fieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// changeSet collects the fields an update changed, keyed by field name
+type changeSet map[string]fieldChange
+
+// add records a field if its value changed. Values must be comparable, so
+// nullable columns are passed through optional first.
+func (c changeSet) add(field string, old, new interface{}) {
+	if old != new {
+		c[field] = fieldChange{Old: old, New: new}
+	}
+}
+
+// details returns the activity details for the change set
+func (c changeSet) details() map[string]interface{} {
+	return map[string]interface{}{
+		"changes": c,
+	}
+}
+
+// optional converts a nullable value to nil or its plain value. Times are
+// task due dates, so they are formatted as dates.
+func optional(v interface{}) interface{} {
+	switch v := v.(type) {
+	case sql.NullString:
+		if v.Valid {
+			return v.String
+		}
+	case sql.NullInt64:
+		if v.Valid {
+			return v.Int64
+		}
+	case sql.NullTime:
+		if v.Valid {
+			return v.Time.Format("2006-01-02")
+		}
+	default:
+		return v
+	}
+	return nil
+}
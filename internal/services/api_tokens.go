@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+var (
+	// ErrAPITokenNotFound is returned when an API token does not exist or
+	// belongs to another user
+	ErrAPITokenNotFound = errors.New("API token not found")
+	// ErrInvalidAPIToken is returned when a request is made with an API
+	// token that does not exist or has expired
+	ErrInvalidAPIToken = errors.New("invalid or expired API token")
+)
+
+const (
+	// APITokenPrefix starts every API token, so leaked tokens are easy to
+	// spot by secret scanners
+	APITokenPrefix = "vugo_pat_"
+	// apiTokenDisplayLength is how much of a token is kept to show in token
+	// lists
+	apiTokenDisplayLength = len(APITokenPrefix) + 6
+	// maxAPITokenLifetimeDays is the longest expiry a token can be given
+	maxAPITokenLifetimeDays = 365
+	// apiTokenTouchInterval is how often the last used time of a token is
+	// updated
+	apiTokenTouchInterval = time.Minute
+)
+
+// APITokenInput holds the fields for a new API token. A zero
+// ExpiresInDays creates a token that does not expire.
+type APITokenInput struct {
+	Name          string
+	Scopes        []string
+	ExpiresInDays int
+}
+
+// CreatedAPIToken is a new API token with its secret. The secret is only
+// stored hashed, so this is the only time it is available.
+type CreatedAPIToken struct {
+	Token  queries.ApiToken
+	Secret string
+}
+
+// CreateAPIToken creates a personal API token for a user
+func (s *AuthService) CreateAPIToken(ctx context.Context, userID int64, input APITokenInput) (*CreatedAPIToken, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 100 {
+		return nil, newValidationError("name must be between 1 and 100 characters")
+	}
+
+	scopes, err := ParseScopes(input.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxAPITokenLifetimeDays {
+		return nil, newValidationError("expires_in_days must be between 0 and 365")
+	}
+	var expiresAt sql.NullTime
+	if input.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{
+			Time:  time.Now().UTC().AddDate(0, 0, input.ExpiresInDays),
+			Valid: true,
+		}
+	}
+
+	random, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+	secret := APITokenPrefix + random
+
+	token, err := s.queries.CreateAPIToken(ctx, queries.CreateAPITokenParams{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:apiTokenDisplayLength],
+		TokenHash: hashToken(secret),
+		Scopes:    formatScopes(scopes),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedAPIToken{
+		Token:  token,
+		Secret: secret,
+	}, nil
+}
+
+// ListAPITokens returns a user's API tokens, newest first
+func (s *AuthService) ListAPITokens(ctx context.Context, userID int64) ([]queries.ApiToken, error) {
+	tokens, err := s.queries.ListUserAPITokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []queries.ApiToken{}
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes one of a user's API tokens
+func (s *AuthService) RevokeAPIToken(ctx context.Context, userID, tokenID int64) error {
+	deleted, err := s.queries.DeleteUserAPIToken(ctx, queries.DeleteUserAPITokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// GetUserByAPIToken validates an API token and returns its user and
+// scopes, recording that the token was used
+func (s *AuthService) GetUserByAPIToken(ctx context.Context, secret string) (*queries.User, Scopes, error) {
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	token, err := s.queries.GetAPITokenByHash(ctx, hashToken(secret))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrInvalidAPIToken
+		}
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	if token.ExpiresAt.Valid && now.After(token.ExpiresAt.Time) {
+		return nil, nil, ErrInvalidAPIToken
+	}
+
+	user, err := s.queries.GetUser(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Failing to record the use should not fail the request
+	if err := s.queries.TouchAPIToken(ctx, queries.TouchAPITokenParams{
+		LastUsedAt:   sql.NullTime{Time: now, Valid: true},
+		ID:           token.ID,
+		LastUsedAt_2: sql.NullTime{Time: now.Add(-apiTokenTouchInterval), Valid: true},
+	}); err != nil {
+		log.Printf("Error recording use of API token %d: %v", token.ID, err)
+	}
+
+	return &user, ParseStoredScopes(token.Scopes), nil
+}
+
+// CleanupExpiredAPITokens deletes expired API tokens
+func (s *AuthService) CleanupExpiredAPITokens(ctx context.Context) error {
+	return s.queries.DeleteExpiredAPITokens(ctx)
+}
+
+// ParseStoredScopes reads the scopes of a stored API token. Scopes that
+// are no longer known are dropped.
+func ParseStoredScopes(stored string) Scopes {
+	scopes := Scopes{}
+	for _, name := range strings.Fields(stored) {
+		if parsed, err := ParseScopes([]string{name}); err == nil {
+			scopes = append(scopes, parsed...)
+		}
+	}
+	return scopes
+}
+
+// formatScopes returns scopes in the form they are stored in
+func formatScopes(scopes Scopes) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, " ")
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+)
+
+var (
+	// ErrAlreadyAssigned is returned when assigning a user who is already assigned
+	ErrAlreadyAssigned = errors.New("user is already assigned to this task")
+
+	// ErrAssigneeNotFound is returned when a user is not assigned to the task
+	ErrAssigneeNotFound = errors.New("assignee not found")
+)
+
+// ListAssignees returns the users assigned to a task
+func (s *TaskService) ListAssignees(ctx context.Context, taskID int64) ([]queries.GetTaskAssigneesRow, error) {
+	return s.queries.GetTaskAssignees(ctx, taskID)
+}
+
+// AssignTask assigns a project member to a task. The assignee starts
+// watching the task and is notified unless they assigned themselves.
+func (s *TaskService) AssignTask(ctx context.Context, access *ProjectAccess, taskID, userID int64) error {
+	// Only members of the task's project can be assigned
+	if userID != access.Project.OwnerID {
+		isMember, err := s.queries.IsProjectMember(ctx, queries.IsProjectMemberParams{
+			ProjectID: access.Project.ID,
+			UserID:    userID,
+		})
+		if err != nil {
+			return err
+		}
+		if isMember == 0 {
+			return newValidationError("assignee must be a member of the project")
+		}
+	}
+
+	assigned, err := s.queries.IsTaskAssignedToUser(ctx, queries.IsTaskAssignedToUserParams{
+		TaskID: taskID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if assigned != 0 {
+		return ErrAlreadyAssigned
+	}
+
+	var boardID int64
+	var out outbox
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := qtx.AssignTaskToUser(ctx, queries.AssignTaskToUserParams{
+			TaskID: taskID,
+			UserID: userID,
+		}); err != nil {
+			return err
+		}
+
+		if err := recordAssigneeActivity(ctx, qtx, access, taskID, userID, ActivityTaskAssigned); err != nil {
+			return err
+		}
+
+		if err := watch(ctx, qtx, taskID, userID); err != nil {
+			return err
+		}
+		if userID == access.UserID {
+			return nil
+		}
+		return out.notify(ctx, qtx, notification{
+			UserID:    userID,
+			ActorID:   access.UserID,
+			ProjectID: access.Project.ID,
+			TaskID:    taskID,
+			Type:      NotificationAssigned,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.broker.Publish(boardID, events.TaskAssigned, access.UserID, events.Refs{
+		TaskID: taskID,
+		UserID: userID,
+	})
+	out.push(ctx, s.queries, s.broker)
+
+	return nil
+}
+
+// UnassignTask removes a user from a task
+func (s *TaskService) UnassignTask(ctx context.Context, access *ProjectAccess, taskID, userID int64) error {
+	assigned, err := s.queries.IsTaskAssignedToUser(ctx, queries.IsTaskAssignedToUserParams{
+		TaskID: taskID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if assigned == 0 {
+		return ErrAssigneeNotFound
+	}
+
+	var boardID int64
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.UnassignTaskFromUser(ctx, queries.UnassignTaskFromUserParams{
+			TaskID: taskID,
+			UserID: userID,
+		}); err != nil {
+			return err
+		}
+
+		return recordAssigneeActivity(ctx, qtx, access, taskID, userID, ActivityTaskUnassigned)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.broker.Publish(boardID, events.TaskUnassigned, access.UserID, events.Refs{
+		TaskID: taskID,
+		UserID: userID,
+	})
+
+	return nil
+}
+
+// recordAssigneeActivity logs that a user was assigned to or removed from
+// a task
+func recordAssigneeActivity(ctx context.Context, q *queries.Queries, access *ProjectAccess, taskID, userID int64, action string) error {
+	user, err := q.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return recordActivity(ctx, q, access.Project.ID, access.UserID, taskID, action, map[string]interface{}{
+		"user_id":   user.ID,
+		"user_name": user.Name,
+	})
+}
@@ -2,43 +2,90 @@ package services
 
 import (
 	"context"
-	"crypto/rand"
 	"database/sql"
-	"encoding/base64"
 	"errors"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/jobs"
+	"github.com/erickhilda/vugo/internal/mailer"
+	"github.com/erickhilda/vugo/internal/templates"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// AuthService handles authentication business logic
+// ErrPasswordLoginDisabled is returned by password logins, registration
+// and password resets when the deployment only allows single sign-on
+var ErrPasswordLoginDisabled = errors.New("password login is disabled, sign in with single sign-on")
+
+// ErrInvalidCredentials is returned when a login has the wrong email or
+// password
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// cleanupInterval is how often expired sessions, tokens and other
+// short-lived records are deleted
+const cleanupInterval = time.Hour
+
+// AuthService handles authentication business logic. appURL is the
+// address of the app that emails link to. passwordLogin controls whether
+// users can register and sign in with a password. limiter slows down
+// password guessing.
 type AuthService struct {
-	queries *queries.Queries
+	db            *sql.DB
+	queries       *queries.Queries
+	mailer        mailer.Mailer
+	appURL        string
+	passwordLogin bool
+	timeouts      SessionTimeouts
+	limiter       *LoginLimiter
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(q *queries.Queries) *AuthService {
+func NewAuthService(db *sql.DB, q *queries.Queries, m mailer.Mailer, appURL string, passwordLogin bool, timeouts SessionTimeouts, limiter *LoginLimiter) *AuthService {
 	return &AuthService{
-		queries: q,
+		db:            db,
+		queries:       q,
+		mailer:        m,
+		appURL:        strings.TrimSuffix(appURL, "/"),
+		passwordLogin: passwordLogin,
+		timeouts:      timeouts,
+		limiter:       limiter,
 	}
 }
 
-// RegisterResult contains the result of registration
+// PasswordLoginEnabled reports whether users can sign in with a password
+func (s *AuthService) PasswordLoginEnabled() bool {
+	return s.passwordLogin
+}
+
+// RegisterResult contains the result of registration. SessionToken is
+// the secret for the session cookie; only its hash is stored.
 type RegisterResult struct {
-	User    queries.User
-	Session queries.Session
+	User         queries.User
+	Session      queries.Session
+	SessionToken string
 }
 
-// Register creates a new user and session
-func (s *AuthService) Register(ctx context.Context, email, password, name string) (*RegisterResult, error) {
+// Register creates a new user and session and emails the user a link to
+// verify their email address
+func (s *AuthService) Register(ctx context.Context, email, password, name string, client SessionClient) (*RegisterResult, error) {
+	if !s.passwordLogin {
+		return nil, ErrPasswordLoginDisabled
+	}
+
 	// Validate input
+	email = normalizeEmail(email)
+	if err := validateEmail(email); err != nil {
+		return nil, err
+	}
+
 	if len(name) < 2 || len(name) > 100 {
 		return nil, errors.New("name must be between 2 and 100 characters")
 	}
 
-	if len(password) < 8 {
-		return nil, errors.New("password must be at least 8 characters")
+	if err := validatePassword(password); err != nil {
+		return nil, err
 	}
 
 	// Check if email already exists
@@ -68,39 +115,52 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 	}
 
 	// Create session
-	sessionID, err := s.GenerateSessionID()
+	session, token, err := s.createSession(ctx, user.ID, client)
 	if err != nil {
 		return nil, err
 	}
 
-	session, err := s.queries.CreateSession(ctx, queries.CreateSessionParams{
-		ID:        sessionID,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour), // 7 days
-	})
-	if err != nil {
-		return nil, err
+	// The account is usable without a verified email, so failing to send
+	// the email does not fail the registration; the user can ask again
+	if err := s.sendVerification(ctx, s.queries, &user); err != nil {
+		log.Printf("Error sending verification email to user %d: %v", user.ID, err)
 	}
 
 	return &RegisterResult{
-		User:    user,
-		Session: session,
+		User:         user,
+		Session:      session,
+		SessionToken: token,
 	}, nil
 }
 
-// LoginResult contains the result of login
+// LoginResult contains the result of login. SessionToken is the secret
+// for the session cookie. Users with two-factor authentication get a
+// Challenge to complete the login with instead of a session.
 type LoginResult struct {
-	User    queries.User
-	Session queries.Session
+	User              queries.User
+	Session           queries.Session
+	SessionToken      string
+	TwoFactorRequired bool
+	Challenge         string
 }
 
-// Login authenticates a user and creates a session
-func (s *AuthService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+// Login authenticates a user and creates a session, or a login challenge
+// if the user has two-factor authentication enabled
+func (s *AuthService) Login(ctx context.Context, email, password string, client SessionClient) (*LoginResult, error) {
+	if !s.passwordLogin {
+		return nil, ErrPasswordLoginDisabled
+	}
+
+	// Too many failed logins block further attempts for a while
+	if err := s.limiter.Check(ctx, email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
-	user, err := s.queries.GetUserByEmail(ctx, email)
+	user, err := s.queries.GetUserByEmail(ctx, normalizeEmail(email))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("invalid email or password")
+			return nil, s.loginFailed(ctx, email, client, sql.NullInt64{}, ErrInvalidCredentials)
 		}
 		return nil, err
 	}
@@ -108,72 +168,99 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (*Login
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password))
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, s.loginFailed(ctx, email, client, sql.NullInt64{Int64: user.ID, Valid: true}, ErrInvalidCredentials)
 	}
 
-	// Create session
-	sessionID, err := s.GenerateSessionID()
+	// The session is only created once the second factor is verified
+	enabled, err := twoFactorEnabled(ctx, s.queries, user.ID)
 	if err != nil {
 		return nil, err
 	}
+	if enabled {
+		challenge, err := s.createLoginChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResult{
+			User:              user,
+			TwoFactorRequired: true,
+			Challenge:         challenge,
+		}, nil
+	}
 
-	session, err := s.queries.CreateSession(ctx, queries.CreateSessionParams{
-		ID:        sessionID,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour), // 7 days
-	})
+	if err := s.limiter.Succeed(ctx, email); err != nil {
+		return nil, err
+	}
+
+	// Create session
+	session, token, err := s.createSession(ctx, user.ID, client)
 	if err != nil {
 		return nil, err
 	}
 
 	return &LoginResult{
-		User:    user,
-		Session: session,
+		User:         user,
+		Session:      session,
+		SessionToken: token,
 	}, nil
 }
 
-// Logout deletes a session
-func (s *AuthService) Logout(ctx context.Context, sessionID string) error {
-	return s.queries.DeleteSession(ctx, sessionID)
+// loginFailed records a failed login and returns err, or the error
+// recording it
+func (s *AuthService) loginFailed(ctx context.Context, email string, client SessionClient, userID sql.NullInt64, err error) error {
+	if failErr := s.limiter.Fail(ctx, email, client.IPAddress, userID); failErr != nil {
+		return failErr
+	}
+	return err
 }
 
-// GetUserBySession validates a session and returns the user
-func (s *AuthService) GetUserBySession(ctx context.Context, sessionID string) (*queries.User, error) {
-	// Get session
-	session, err := s.queries.GetSession(ctx, sessionID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("session not found")
-		}
-		return nil, err
+// Jobs returns the background jobs that delete expired sessions and tokens
+func (s *AuthService) Jobs() []jobs.Job {
+	return []jobs.Job{
+		{Name: "session-cleanup", Interval: cleanupInterval, Run: s.CleanupExpiredSessions},
+		{Name: "token-cleanup", Interval: cleanupInterval, Run: s.cleanupExpiredTokens},
 	}
+}
 
-	// Check if session is expired
-	if time.Now().After(session.ExpiresAt) {
-		// Delete expired session
-		_ = s.queries.DeleteSession(ctx, sessionID)
-		return nil, errors.New("session expired")
-	}
+// cleanupExpiredTokens deletes expired password reset, email verification
+// and API tokens and login challenges. Every kind is tried even if one
+// fails.
+func (s *AuthService) cleanupExpiredTokens(ctx context.Context) error {
+	return errors.Join(
+		s.CleanupExpiredResetTokens(ctx),
+		s.CleanupExpiredVerificationTokens(ctx),
+		s.CleanupExpiredLoginChallenges(ctx),
+		s.CleanupExpiredAPITokens(ctx),
+	)
+}
 
-	// Get user
-	user, err := s.queries.GetUser(ctx, session.UserID)
+// sendEmail renders an email template for a user and sends it in the
+// background, so responses do not wait for the mail server. data gets the
+// Subject and the user's Name added.
+func (s *AuthService) sendEmail(user *queries.User, subject, name string, data map[string]interface{}) error {
+	data["Subject"] = subject
+	data["Name"] = user.Name
+	body, err := templates.Render(name, data)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &user, nil
+	go func() {
+		if err := s.mailer.Send(context.Background(), &mailer.Message{
+			To:      user.Email,
+			Subject: subject,
+			Body:    body,
+		}); err != nil {
+			log.Printf("Error emailing %q to user %d: %v", subject, user.ID, err)
+		}
+	}()
+	return nil
 }
 
-// GenerateSessionID generates a secure random session ID
-func (s *AuthService) GenerateSessionID() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+// validatePassword checks that a new password is long enough
+func validatePassword(password string) error {
+	if len(password) < 8 {
+		return newValidationError("password must be at least 8 characters")
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
-}
-
-// CleanupExpiredSessions deletes expired sessions
-func (s *AuthService) CleanupExpiredSessions(ctx context.Context) error {
-	return s.queries.DeleteExpiredSessions(ctx)
+	return nil
 }
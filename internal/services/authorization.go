@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+// Role is the role of a user within a project
+type Role string
+
+const (
+	// RoleViewer can only read project content
+	RoleViewer Role = "viewer"
+	// RoleMember can work on tasks and comment
+	RoleMember Role = "member"
+	// RoleAdmin can manage boards, columns, labels and members
+	RoleAdmin Role = "admin"
+	// RoleOwner has full control, including deleting and transferring the project
+	RoleOwner Role = "owner"
+)
+
+// roleRanks orders roles from least to most privileged
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// ParseRole validates a role name
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", newValidationError("role must be one of 'owner', 'admin', 'member', 'viewer'")
+	}
+	return role, nil
+}
+
+// AtLeast reports whether r is at least as privileged as other
+func (r Role) AtLeast(other Role) bool {
+	return roleRanks[r] >= roleRanks[other]
+}
+
+// Permission is an action that can be performed within a project
+type Permission string
+
+const (
+	// PermViewProject allows reading the project, its boards, tasks and comments
+	PermViewProject Permission = "project:view"
+	// PermEditTasks allows creating, editing, moving and commenting on tasks
+	PermEditTasks Permission = "tasks:edit"
+	// PermWatchTasks allows watching and unwatching tasks
+	PermWatchTasks Permission = "tasks:watch"
+	// PermManageBoards allows managing boards and columns
+	PermManageBoards Permission = "boards:manage"
+	// PermManageLabels allows managing project labels
+	PermManageLabels Permission = "labels:manage"
+	// PermManageMembers allows inviting, removing and changing roles of members
+	PermManageMembers Permission = "members:manage"
+	// PermEditProject allows changing the project name, description and color
+	PermEditProject Permission = "project:edit"
+	// PermArchiveProject allows archiving and unarchiving the project
+	PermArchiveProject Permission = "project:archive"
+	// PermDeleteProject allows deleting the project
+	PermDeleteProject Permission = "project:delete"
+	// PermTransferProject allows transferring ownership to another member
+	PermTransferProject Permission = "project:transfer"
+)
+
+// permissionMatrix maps each permission to the least privileged role
+// that is granted it. Higher roles inherit everything below them.
+var permissionMatrix = map[Permission]Role{
+	PermViewProject:     RoleViewer,
+	PermEditTasks:       RoleMember,
+	PermWatchTasks:      RoleViewer,
+	PermManageBoards:    RoleAdmin,
+	PermManageLabels:    RoleAdmin,
+	PermManageMembers:   RoleAdmin,
+	PermEditProject:     RoleAdmin,
+	PermArchiveProject:  RoleOwner,
+	PermDeleteProject:   RoleOwner,
+	PermTransferProject: RoleOwner,
+}
+
+// RoleCan reports whether a role is granted a permission
+func RoleCan(role Role, perm Permission) bool {
+	required, ok := permissionMatrix[perm]
+	if !ok {
+		return false
+	}
+	return role.AtLeast(required)
+}
+
+// Scope limits what an API token may do on behalf of its user. Sessions
+// are not limited by scopes.
+type Scope string
+
+const (
+	// ScopeReadProjects allows reading projects, boards, tasks, comments
+	// and activity
+	ScopeReadProjects Scope = "read:projects"
+	// ScopeWriteTasks allows creating, editing, moving and commenting on
+	// tasks
+	ScopeWriteTasks Scope = "write:tasks"
+	// ScopeWriteProjects allows creating and editing projects, boards,
+	// columns and labels
+	ScopeWriteProjects Scope = "write:projects"
+	// ScopeAdminProjects allows managing members and archiving, deleting
+	// and transferring projects
+	ScopeAdminProjects Scope = "admin:projects"
+	// ScopeReadNotifications allows reading notifications and notification
+	// preferences
+	ScopeReadNotifications Scope = "read:notifications"
+	// ScopeWriteNotifications allows marking notifications read, changing
+	// notification preferences and watching tasks
+	ScopeWriteNotifications Scope = "write:notifications"
+)
+
+// AllScopes lists every scope, in the order they are documented
+var AllScopes = []Scope{
+	ScopeReadProjects,
+	ScopeWriteTasks,
+	ScopeWriteProjects,
+	ScopeAdminProjects,
+	ScopeReadNotifications,
+	ScopeWriteNotifications,
+}
+
+// scopeMatrix maps each project permission to the scope a token needs for
+// it
+var scopeMatrix = map[Permission]Scope{
+	PermViewProject:     ScopeReadProjects,
+	PermEditTasks:       ScopeWriteTasks,
+	PermWatchTasks:      ScopeWriteNotifications,
+	PermManageBoards:    ScopeWriteProjects,
+	PermManageLabels:    ScopeWriteProjects,
+	PermEditProject:     ScopeWriteProjects,
+	PermManageMembers:   ScopeAdminProjects,
+	PermArchiveProject:  ScopeAdminProjects,
+	PermDeleteProject:   ScopeAdminProjects,
+	PermTransferProject: ScopeAdminProjects,
+}
+
+// Scopes is the set of scopes granted to an API token
+type Scopes []Scope
+
+// ParseScopes validates scope names and removes duplicates
+func ParseScopes(names []string) (Scopes, error) {
+	if len(names) == 0 {
+		return nil, newValidationError("at least one scope is required")
+	}
+
+	var scopes Scopes
+	for _, name := range names {
+		scope := Scope(name)
+		known := false
+		for _, s := range AllScopes {
+			if s == scope {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, newValidationError("unknown scope '" + name + "'")
+		}
+		if !scopes.Has(scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// Has reports whether a scope is granted
+func (s Scopes) Has(scope Scope) bool {
+	for _, granted := range s {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Allow reports whether the scopes cover a project permission
+func (s Scopes) Allow(perm Permission) bool {
+	required, ok := scopeMatrix[perm]
+	if !ok {
+		return false
+	}
+	return s.Has(required)
+}
+
+// ProjectAccess describes what a user may do within a project. Scopes is
+// set when the request is made with an API token and further limits what
+// the role allows; it is nil for sessions.
+type ProjectAccess struct {
+	Project queries.Project
+	UserID  int64
+	Role    Role
+	Scopes  Scopes
+}
+
+// Can reports whether the user is granted a permission on the project
+func (a *ProjectAccess) Can(perm Permission) bool {
+	if a.Scopes != nil && !a.Scopes.Allow(perm) {
+		return false
+	}
+	return RoleCan(a.Role, perm)
+}
+
+// ProjectAuthorizer resolves project roles and enforces the permission matrix
+type ProjectAuthorizer struct {
+	queries *queries.Queries
+}
+
+// NewProjectAuthorizer creates a new project authorizer
+func NewProjectAuthorizer(q *queries.Queries) *ProjectAuthorizer {
+	return &ProjectAuthorizer{
+		queries: q,
+	}
+}
+
+// Access loads a project and the user's role in it. Users who are not
+// members get ErrProjectNotFound so project existence is not leaked.
+func (a *ProjectAuthorizer) Access(ctx context.Context, projectID, userID int64) (*ProjectAccess, error) {
+	project, err := a.queries.GetProject(ctx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	// The owner column is authoritative even without a member row
+	if project.OwnerID == userID {
+		return &ProjectAccess{Project: project, UserID: userID, Role: RoleOwner}, nil
+	}
+
+	member, err := a.queries.GetProjectMember(ctx, queries.GetProjectMemberParams{
+		ProjectID: projectID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	role, err := ParseRole(member.Role)
+	if err != nil {
+		// Unknown roles get the least privilege rather than none
+		role = RoleViewer
+	}
+	// Ownership is only granted through projects.owner_id
+	if role == RoleOwner {
+		role = RoleAdmin
+	}
+
+	return &ProjectAccess{Project: project, UserID: userID, Role: role}, nil
+}
+
+// Authorize loads the user's access to a project and checks a permission
+func (a *ProjectAuthorizer) Authorize(ctx context.Context, projectID, userID int64, perm Permission) (*ProjectAccess, error) {
+	access, err := a.Access(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !access.Can(perm) {
+		return nil, ErrForbidden
+	}
+
+	return access, nil
+}
+
+// ProjectIDForBoard returns the project a board belongs to. A missing board
+// is reported as ErrProjectNotFound, like a project the user cannot see, so
+// non-members cannot tell which IDs exist.
+func (a *ProjectAuthorizer) ProjectIDForBoard(ctx context.Context, boardID int64) (int64, error) {
+	board, err := a.queries.GetBoard(ctx, boardID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrProjectNotFound
+		}
+		return 0, err
+	}
+	return board.ProjectID, nil
+}
+
+// ProjectIDForColumn returns the project a column belongs to, reporting a
+// missing column like ProjectIDForBoard
+func (a *ProjectAuthorizer) ProjectIDForColumn(ctx context.Context, columnID int64) (int64, error) {
+	projectID, err := a.queries.GetProjectIDByColumn(ctx, columnID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrProjectNotFound
+		}
+		return 0, err
+	}
+	return projectID, nil
+}
+
+// ProjectIDForTask returns the project a task belongs to, reporting a
+// missing task like ProjectIDForBoard
+func (a *ProjectAuthorizer) ProjectIDForTask(ctx context.Context, taskID int64) (int64, error) {
+	projectID, err := a.queries.GetProjectIDByTask(ctx, taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrProjectNotFound
+		}
+		return 0, err
+	}
+	return projectID, nil
+}
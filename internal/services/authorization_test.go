@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestProjectAccessCan(t *testing.T) {
+	tests := []struct {
+		name   string
+		role   Role
+		scopes Scopes
+		perm   Permission
+		want   bool
+	}{
+		{"viewer session views", RoleViewer, nil, PermViewProject, true},
+		{"viewer session watches", RoleViewer, nil, PermWatchTasks, true},
+		{"viewer session cannot edit tasks", RoleViewer, nil, PermEditTasks, false},
+		{"member session edits tasks", RoleMember, nil, PermEditTasks, true},
+		{"read token views", RoleOwner, Scopes{ScopeReadProjects}, PermViewProject, true},
+		{"read token cannot watch", RoleOwner, Scopes{ScopeReadProjects}, PermWatchTasks, false},
+		{"read token cannot edit tasks", RoleOwner, Scopes{ScopeReadProjects}, PermEditTasks, false},
+		{"notifications token watches", RoleViewer, Scopes{ScopeReadProjects, ScopeWriteNotifications}, PermWatchTasks, true},
+		{"tasks token cannot watch", RoleOwner, Scopes{ScopeWriteTasks}, PermWatchTasks, false},
+		{"token cannot exceed role", RoleViewer, Scopes{ScopeWriteTasks}, PermEditTasks, false},
+		{"admin token manages members", RoleAdmin, Scopes{ScopeAdminProjects}, PermManageMembers, true},
+		{"unknown permission", RoleOwner, nil, Permission("unknown"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := &ProjectAccess{UserID: 1, Role: tt.role, Scopes: tt.scopes}
+			if got := access.Can(tt.perm); got != tt.want {
+				t.Errorf("Can(%s) with role %s and scopes %v = %v, want %v", tt.perm, tt.role, tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+)
+
+var (
+	// ErrBoardNotFound is returned when a board does not exist
+	ErrBoardNotFound = errors.New("board not found")
+
+	// ErrColumnNotFound is returned when a column does not exist
+	ErrColumnNotFound = errors.New("column not found")
+)
+
+// BoardService handles board and column business logic
+type BoardService struct {
+	db         *sql.DB
+	queries    *queries.Queries
+	rebalancer *RankRebalancer
+	broker     *events.Broker
+}
+
+// NewBoardService creates a new board service
+func NewBoardService(db *sql.DB, q *queries.Queries, rebalancer *RankRebalancer, broker *events.Broker) *BoardService {
+	return &BoardService{
+		db:         db,
+		queries:    q,
+		rebalancer: rebalancer,
+		broker:     broker,
+	}
+}
+
+// ColumnInput contains the editable fields of a column
+type ColumnInput struct {
+	Name     string
+	Color    string
+	WipLimit *int64
+}
+
+// validate trims and checks the column input
+func (in *ColumnInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Color = strings.TrimSpace(in.Color)
+
+	if len(in.Name) < 1 || len(in.Name) > 50 {
+		return newValidationError("column name must be between 1 and 50 characters")
+	}
+
+	if in.Color != "" && !hexColorPattern.MatchString(in.Color) {
+		return newValidationError("color must be a valid hex color code")
+	}
+
+	if in.WipLimit != nil && *in.WipLimit < 1 {
+		return newValidationError("wip_limit must be at least 1")
+	}
+
+	return nil
+}
+
+// wipLimit converts the optional WIP limit to a nullable value
+func (in *ColumnInput) wipLimit() sql.NullInt64 {
+	if in.WipLimit == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *in.WipLimit, Valid: true}
+}
+
+// validateBoardName trims and checks a board name
+func validateBoardName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) < 1 || len(name) > 100 {
+		return "", newValidationError("board name must be between 1 and 100 characters")
+	}
+	return name, nil
+}
+
+// FullBoard is a board with its columns and their open tasks
+type FullBoard struct {
+	Board   queries.Board
+	Columns []BoardColumn
+}
+
+// BoardColumn is a column together with its open tasks
+type BoardColumn struct {
+	Column queries.Column
+	Tasks  []queries.Task
+}
+
+// OverWipLimit reports whether the column holds more open tasks than its
+// WIP limit allows
+func (c *BoardColumn) OverWipLimit() bool {
+	return c.Column.WipLimit.Valid && int64(len(c.Tasks)) > c.Column.WipLimit.Int64
+}
+
+// ListBoards returns the boards of a project in display order
+func (s *BoardService) ListBoards(ctx context.Context, projectID int64) ([]queries.Board, error) {
+	return s.queries.ListBoardsByProject(ctx, projectID)
+}
+
+// CreateBoard appends a new board to a project
+func (s *BoardService) CreateBoard(ctx context.Context, access *ProjectAccess, name string) (*queries.Board, error) {
+	name, err := validateBoardName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var board queries.Board
+	scope := rankScope{list: boardRanks, parentID: access.Project.ID}
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		rank, err := placeRank(ctx, qtx, scope, math.MaxInt, func() ([]string, error) {
+			return boardRanksExcept(ctx, qtx, access.Project.ID, 0)
+		})
+		if err != nil {
+			return err
+		}
+
+		board, err = qtx.CreateBoard(ctx, queries.CreateBoardParams{
+			ProjectID: access.Project.ID,
+			Name:      name,
+			Rank:      rank,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityBoardCreated, map[string]interface{}{
+			"board_id":   board.ID,
+			"board_name": board.Name,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.rebalancer.checkRank(scope, board.Rank)
+
+	return &board, nil
+}
+
+// GetBoard returns a single board
+func (s *BoardService) GetBoard(ctx context.Context, boardID int64) (*queries.Board, error) {
+	board, err := s.queries.GetBoard(ctx, boardID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBoardNotFound
+		}
+		return nil, err
+	}
+	return &board, nil
+}
+
+// GetFullBoard returns a board with all its columns and their open tasks
+// using a fixed number of queries regardless of board size
+func (s *BoardService) GetFullBoard(ctx context.Context, boardID int64) (*FullBoard, error) {
+	board, err := s.GetBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := s.queries.ListColumnsByBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.queries.ListOpenTasksByBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group tasks by column, keeping their rank order
+	tasksByColumn := make(map[int64][]queries.Task, len(columns))
+	for _, t := range tasks {
+		tasksByColumn[t.ColumnID] = append(tasksByColumn[t.ColumnID], t)
+	}
+
+	full := &FullBoard{
+		Board:   *board,
+		Columns: make([]BoardColumn, 0, len(columns)),
+	}
+	for _, c := range columns {
+		full.Columns = append(full.Columns, BoardColumn{
+			Column: c,
+			Tasks:  tasksByColumn[c.ID],
+		})
+	}
+
+	return full, nil
+}
+
+// UpdateBoard renames a board
+func (s *BoardService) UpdateBoard(ctx context.Context, access *ProjectAccess, boardID int64, name string) (*queries.Board, error) {
+	name, err := validateBoardName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var board queries.Board
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		old, err := qtx.GetBoard(ctx, boardID)
+		if err != nil {
+			return err
+		}
+
+		board, err = qtx.UpdateBoard(ctx, queries.UpdateBoardParams{
+			Name: name,
+			ID:   boardID,
+		})
+		if err != nil {
+			return err
+		}
+
+		changes := changeSet{}
+		changes.add("name", old.Name, board.Name)
+		if len(changes) == 0 {
+			return nil
+		}
+
+		details := changes.details()
+		details["board_id"] = board.ID
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityBoardUpdated, details)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrBoardNotFound
+		}
+		return nil, err
+	}
+
+	s.broker.Publish(board.ID, events.BoardUpdated, access.UserID, &board)
+
+	return &board, nil
+}
+
+// UpdateBoardPosition moves a board to an index among its project's
+// boards. Only the moved board's row is written.
+func (s *BoardService) UpdateBoardPosition(ctx context.Context, access *ProjectAccess, boardID, position int64) error {
+	if position < 0 {
+		return newValidationError("position must not be negative")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := s.queries.WithTx(tx)
+
+	board, err := qtx.GetBoard(ctx, boardID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBoardNotFound
+		}
+		return err
+	}
+
+	scope := rankScope{list: boardRanks, parentID: board.ProjectID}
+	rank, err := placeRank(ctx, qtx, scope, int(position), func() ([]string, error) {
+		return boardRanksExcept(ctx, qtx, board.ProjectID, board.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := qtx.UpdateBoardRank(ctx, queries.UpdateBoardRankParams{
+		Rank: rank,
+		ID:   board.ID,
+	}); err != nil {
+		return err
+	}
+
+	if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityBoardMoved, map[string]interface{}{
+		"board_id":   board.ID,
+		"board_name": board.Name,
+		"position":   position,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.rebalancer.checkRank(scope, rank)
+
+	board.Rank = rank
+	s.broker.Publish(board.ID, events.BoardMoved, access.UserID, &board)
+
+	return nil
+}
+
+// boardRanksExcept returns the ordered ranks of a project's boards,
+// leaving out the board being placed
+func boardRanksExcept(ctx context.Context, q *queries.Queries, projectID, boardID int64) ([]string, error) {
+	boards, err := q.ListBoardsByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make([]string, 0, len(boards))
+	for _, b := range boards {
+		if b.ID != boardID {
+			ranks = append(ranks, b.Rank)
+		}
+	}
+	return ranks, nil
+}
+
+// DeleteBoard deletes a board with all its columns and tasks
+func (s *BoardService) DeleteBoard(ctx context.Context, access *ProjectAccess, boardID int64) error {
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		board, err := qtx.GetBoard(ctx, boardID)
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteBoard(ctx, boardID); err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityBoardDeleted, map[string]interface{}{
+			"board_id":   board.ID,
+			"board_name": board.Name,
+		})
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrBoardNotFound
+		}
+		return err
+	}
+
+	s.broker.Publish(boardID, events.BoardDeleted, access.UserID, events.Refs{BoardID: boardID})
+
+	return nil
+}
+
+// ListColumns returns the columns of a board in display order
+func (s *BoardService) ListColumns(ctx context.Context, boardID int64) ([]queries.Column, error) {
+	return s.queries.ListColumnsByBoard(ctx, boardID)
+}
+
+// CreateColumn appends a new column to a board
+func (s *BoardService) CreateColumn(ctx context.Context, access *ProjectAccess, boardID int64, input ColumnInput) (*queries.Column, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	var column queries.Column
+	scope := rankScope{list: columnRanks, parentID: boardID}
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		rank, err := placeRank(ctx, qtx, scope, math.MaxInt, func() ([]string, error) {
+			return columnRanksExcept(ctx, qtx, boardID, 0)
+		})
+		if err != nil {
+			return err
+		}
+
+		column, err = qtx.CreateColumn(ctx, queries.CreateColumnParams{
+			BoardID:  boardID,
+			Name:     input.Name,
+			Rank:     rank,
+			Color:    nullString(input.Color),
+			WipLimit: input.wipLimit(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityColumnCreated, map[string]interface{}{
+			"board_id":    boardID,
+			"column_id":   column.ID,
+			"column_name": column.Name,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.rebalancer.checkRank(scope, column.Rank)
+
+	s.broker.Publish(boardID, events.ColumnCreated, access.UserID, &column)
+
+	return &column, nil
+}
+
+// GetColumn returns a single column
+func (s *BoardService) GetColumn(ctx context.Context, columnID int64) (*queries.Column, error) {
+	column, err := s.queries.GetColumn(ctx, columnID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrColumnNotFound
+		}
+		return nil, err
+	}
+	return &column, nil
+}
+
+// UpdateColumn updates a column's name, color and WIP limit
+func (s *BoardService) UpdateColumn(ctx context.Context, access *ProjectAccess, columnID int64, input ColumnInput) (*queries.Column, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	var column queries.Column
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		old, err := qtx.GetColumn(ctx, columnID)
+		if err != nil {
+			return err
+		}
+
+		column, err = qtx.UpdateColumn(ctx, queries.UpdateColumnParams{
+			Name:     input.Name,
+			Color:    nullString(input.Color),
+			WipLimit: input.wipLimit(),
+			ID:       columnID,
+		})
+		if err != nil {
+			return err
+		}
+
+		changes := changeSet{}
+		changes.add("name", old.Name, column.Name)
+		changes.add("color", optional(old.Color), optional(column.Color))
+		changes.add("wip_limit", optional(old.WipLimit), optional(column.WipLimit))
+		if len(changes) == 0 {
+			return nil
+		}
+
+		details := changes.details()
+		details["column_id"] = column.ID
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityColumnUpdated, details)
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrColumnNotFound
+		}
+		return nil, err
+	}
+
+	s.broker.Publish(column.BoardID, events.ColumnUpdated, access.UserID, &column)
+
+	return &column, nil
+}
+
+// UpdateColumnPosition moves a column to an index among its board's
+// columns. Only the moved column's row is written.
+func (s *BoardService) UpdateColumnPosition(ctx context.Context, access *ProjectAccess, columnID, position int64) error {
+	if position < 0 {
+		return newValidationError("position must not be negative")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := s.queries.WithTx(tx)
+
+	column, err := qtx.GetColumn(ctx, columnID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrColumnNotFound
+		}
+		return err
+	}
+
+	scope := rankScope{list: columnRanks, parentID: column.BoardID}
+	rank, err := placeRank(ctx, qtx, scope, int(position), func() ([]string, error) {
+		return columnRanksExcept(ctx, qtx, column.BoardID, column.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := qtx.UpdateColumnRank(ctx, queries.UpdateColumnRankParams{
+		Rank: rank,
+		ID:   column.ID,
+	}); err != nil {
+		return err
+	}
+
+	if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityColumnMoved, map[string]interface{}{
+		"column_id":   column.ID,
+		"column_name": column.Name,
+		"position":    position,
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.rebalancer.checkRank(scope, rank)
+
+	column.Rank = rank
+	s.broker.Publish(column.BoardID, events.ColumnMoved, access.UserID, &column)
+
+	return nil
+}
+
+// columnRanksExcept returns the ordered ranks of a board's columns,
+// leaving out the column being placed
+func columnRanksExcept(ctx context.Context, q *queries.Queries, boardID, columnID int64) ([]string, error) {
+	columns, err := q.ListColumnsByBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+
+	ranks := make([]string, 0, len(columns))
+	for _, c := range columns {
+		if c.ID != columnID {
+			ranks = append(ranks, c.Rank)
+		}
+	}
+	return ranks, nil
+}
+
+// DeleteColumn deletes a column with all its tasks
+func (s *BoardService) DeleteColumn(ctx context.Context, access *ProjectAccess, columnID int64) error {
+	var column queries.Column
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		column, err = qtx.GetColumn(ctx, columnID)
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteColumn(ctx, columnID); err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityColumnDeleted, map[string]interface{}{
+			"board_id":    column.BoardID,
+			"column_id":   column.ID,
+			"column_name": column.Name,
+		})
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrColumnNotFound
+		}
+		return err
+	}
+
+	s.broker.Publish(column.BoardID, events.ColumnDeleted, access.UserID, events.Refs{
+		BoardID:  column.BoardID,
+		ColumnID: column.ID,
+	})
+
+	return nil
+}
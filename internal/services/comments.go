@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+	"github.com/erickhilda/vugo/internal/markdown"
+)
+
+// ErrCommentNotFound is returned when a comment does not exist on the task
+var ErrCommentNotFound = errors.New("comment not found")
+
+// maxCommentLength is the longest comment source accepted
+const maxCommentLength = 10000
+
+// CommentService handles task comment business logic
+type CommentService struct {
+	db      *sql.DB
+	queries *queries.Queries
+	broker  *events.Broker
+}
+
+// NewCommentService creates a new comment service
+func NewCommentService(db *sql.DB, q *queries.Queries, broker *events.Broker) *CommentService {
+	return &CommentService{
+		db:      db,
+		queries: q,
+		broker:  broker,
+	}
+}
+
+// CommentDetail is a comment with its author and its Markdown source
+// rendered to sanitized HTML
+type CommentDetail struct {
+	Comment         queries.Comment
+	AuthorName      string
+	AuthorEmail     string
+	AuthorAvatarURL string
+	HTML            string
+}
+
+// validateCommentContent trims and checks a comment's Markdown source
+func validateCommentContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if len(content) < 1 || len(content) > maxCommentLength {
+		return "", newValidationError("content must be between 1 and 10000 characters")
+	}
+	return content, nil
+}
+
+// newCommentDetail renders a comment for display, turning mentions that
+// resolve into links
+func newCommentDetail(comment queries.Comment, name, email string, avatarURL sql.NullString, resolve markdown.MentionResolver) (*CommentDetail, error) {
+	html, _, err := markdown.RenderMentions(comment.Content, resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommentDetail{
+		Comment:         comment,
+		AuthorName:      name,
+		AuthorEmail:     email,
+		AuthorAvatarURL: avatarURL.String,
+		HTML:            html,
+	}, nil
+}
+
+// ListComments returns the comments of a task, oldest first
+func (s *CommentService) ListComments(ctx context.Context, access *ProjectAccess, taskID int64) ([]CommentDetail, error) {
+	resolve, err := memberMentions(ctx, s.queries, access.Project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListCommentsByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]CommentDetail, 0, len(rows))
+	for _, row := range rows {
+		detail, err := newCommentDetail(queries.Comment{
+			ID:        row.ID,
+			TaskID:    row.TaskID,
+			UserID:    row.UserID,
+			Content:   row.Content,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}, row.UserName, row.UserEmail, row.UserAvatarUrl, resolve)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, *detail)
+	}
+
+	return comments, nil
+}
+
+// CreateComment adds a comment by the current user to a task and notifies
+// the project members it mentions and the task's other watchers. The
+// author starts watching the task.
+func (s *CommentService) CreateComment(ctx context.Context, access *ProjectAccess, taskID int64, content string) (*CommentDetail, error) {
+	content, err := validateCommentContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var detail *CommentDetail
+	var boardID int64
+	var out outbox
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		resolve, err := memberMentions(ctx, qtx, access.Project.ID)
+		if err != nil {
+			return err
+		}
+		mentions, err := markdown.Mentions(content, resolve)
+		if err != nil {
+			return err
+		}
+
+		comment, err := qtx.CreateComment(ctx, queries.CreateCommentParams{
+			TaskID:  taskID,
+			UserID:  access.UserID,
+			Content: content,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, taskID, ActivityCommentCreated, map[string]interface{}{
+			"comment_id": comment.ID,
+		}); err != nil {
+			return err
+		}
+
+		// Mentioned watchers are only told about the mention
+		mentioned, err := syncMentions(ctx, qtx, &out, access, taskID, comment.ID, mentions)
+		if err != nil {
+			return err
+		}
+		if err := out.notifyWatchers(ctx, qtx, access, taskID, comment.ID, NotificationCommented, mentioned); err != nil {
+			return err
+		}
+		if err := watch(ctx, qtx, taskID, access.UserID); err != nil {
+			return err
+		}
+
+		detail, err = getCommentDetail(ctx, qtx, resolve, taskID, comment.ID)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	s.broker.Publish(boardID, events.CommentCreated, access.UserID, detail)
+	out.push(ctx, s.queries, s.broker)
+
+	return detail, nil
+}
+
+// UpdateComment changes a comment's content and notifies the project
+// members newly mentioned in it. Only its author and project admins may
+// edit a comment.
+func (s *CommentService) UpdateComment(ctx context.Context, access *ProjectAccess, taskID, commentID int64, content string) (*CommentDetail, error) {
+	content, err := validateCommentContent(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var detail *CommentDetail
+	var boardID int64
+	var out outbox
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		comment, err := getManageableComment(ctx, qtx, access, taskID, commentID)
+		if err != nil {
+			return err
+		}
+
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		resolve, err := memberMentions(ctx, qtx, access.Project.ID)
+		if err != nil {
+			return err
+		}
+		mentions, err := markdown.Mentions(content, resolve)
+		if err != nil {
+			return err
+		}
+
+		// The author stays the same when an admin edits the comment
+		updated, err := qtx.UpdateComment(ctx, queries.UpdateCommentParams{
+			Content: content,
+			ID:      comment.ID,
+			UserID:  comment.UserID,
+		})
+		if err != nil {
+			return err
+		}
+
+		changes := changeSet{}
+		changes.add("content", comment.Content, updated.Content)
+		if len(changes) > 0 {
+			details := changes.details()
+			details["comment_id"] = comment.ID
+			if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, taskID, ActivityCommentUpdated, details); err != nil {
+				return err
+			}
+		}
+
+		if _, err := syncMentions(ctx, qtx, &out, access, taskID, comment.ID, mentions); err != nil {
+			return err
+		}
+
+		detail, err = getCommentDetail(ctx, qtx, resolve, taskID, comment.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.broker.Publish(boardID, events.CommentUpdated, access.UserID, detail)
+	out.push(ctx, s.queries, s.broker)
+
+	return detail, nil
+}
+
+// DeleteComment deletes a comment. Only its author and project admins may
+// delete a comment.
+func (s *CommentService) DeleteComment(ctx context.Context, access *ProjectAccess, taskID, commentID int64) error {
+	var boardID int64
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		comment, err := getManageableComment(ctx, qtx, access, taskID, commentID)
+		if err != nil {
+			return err
+		}
+
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteComment(ctx, queries.DeleteCommentParams{
+			ID:     comment.ID,
+			UserID: comment.UserID,
+		}); err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, taskID, ActivityCommentDeleted, map[string]interface{}{
+			"comment_id": comment.ID,
+			"author_id":  comment.UserID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	s.broker.Publish(boardID, events.CommentDeleted, access.UserID, events.Refs{
+		TaskID:    taskID,
+		CommentID: commentID,
+	})
+
+	return nil
+}
+
+// getCommentDetail loads a comment of a task with its author
+func getCommentDetail(ctx context.Context, q *queries.Queries, resolve markdown.MentionResolver, taskID, commentID int64) (*CommentDetail, error) {
+	row, err := q.GetCommentWithUser(ctx, commentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	if row.TaskID != taskID {
+		return nil, ErrCommentNotFound
+	}
+
+	return newCommentDetail(queries.Comment{
+		ID:        row.ID,
+		TaskID:    row.TaskID,
+		UserID:    row.UserID,
+		Content:   row.Content,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}, row.UserName, row.UserEmail, row.UserAvatarUrl, resolve)
+}
+
+// getManageableComment loads a comment of a task and checks that the
+// acting user may edit or delete it
+func getManageableComment(ctx context.Context, q *queries.Queries, access *ProjectAccess, taskID, commentID int64) (*queries.Comment, error) {
+	comment, err := q.GetComment(ctx, commentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	if comment.TaskID != taskID {
+		return nil, ErrCommentNotFound
+	}
+
+	if comment.UserID != access.UserID && !access.Role.AtLeast(RoleAdmin) {
+		return nil, ErrForbidden
+	}
+
+	return &comment, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+var (
+	// ErrInvalidVerificationToken is returned when an email verification
+	// token does not exist or has expired
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	// ErrEmailAlreadyVerified is returned when asking to verify an email
+	// that already is
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	// ErrVerificationThrottled is returned when verification emails are
+	// requested too often
+	ErrVerificationThrottled = errors.New("too many verification emails requested, try again later")
+	// ErrEmailNotVerified is returned when an action requires a user with a
+	// verified email
+	ErrEmailNotVerified = errors.New("user has not verified their email address")
+)
+
+const (
+	// emailVerificationTTL is how long an email verification link stays
+	// valid
+	emailVerificationTTL = 24 * time.Hour
+	// verificationResendInterval is how long to wait between verification
+	// emails
+	verificationResendInterval = time.Minute
+	// maxVerificationEmailsPerHour is the most verification emails sent to
+	// a user in an hour
+	maxVerificationEmailsPerHour = 5
+)
+
+// normalizeEmail returns an email in the form it is stored in: trimmed and
+// lower case
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateEmail checks that a normalized email is a bare address, without
+// a display name or angle brackets
+func validateEmail(email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > 254 {
+		return newValidationError("email must be a valid email address")
+	}
+	return nil
+}
+
+// VerifyEmail marks the email of the user a verification token was sent to
+// as verified and returns the user. The user's verification tokens are
+// used up.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (*queries.User, error) {
+	var user queries.User
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		verification, err := qtx.GetEmailVerificationTokenByHash(ctx, hashToken(token))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidVerificationToken
+			}
+			return err
+		}
+		if time.Now().After(verification.ExpiresAt) {
+			return ErrInvalidVerificationToken
+		}
+
+		user, err = qtx.MarkUserEmailVerified(ctx, verification.UserID)
+		if err != nil {
+			return err
+		}
+		return qtx.DeleteUserEmailVerificationTokens(ctx, verification.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// ResendVerification emails a new verification link to a user whose email
+// is not verified yet, at most once a minute and five times an hour
+func (s *AuthService) ResendVerification(ctx context.Context, user *queries.User) error {
+	if user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	now := time.Now().UTC()
+	recent, err := s.queries.CountEmailVerificationTokensSince(ctx, queries.CountEmailVerificationTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: sql.NullTime{Time: now.Add(-verificationResendInterval), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	hourly, err := s.queries.CountEmailVerificationTokensSince(ctx, queries.CountEmailVerificationTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if recent > 0 || hourly >= maxVerificationEmailsPerHour {
+		return ErrVerificationThrottled
+	}
+
+	return s.sendVerification(ctx, s.queries, user)
+}
+
+// CleanupExpiredVerificationTokens deletes expired email verification
+// tokens
+func (s *AuthService) CleanupExpiredVerificationTokens(ctx context.Context) error {
+	return s.queries.DeleteExpiredEmailVerificationTokens(ctx)
+}
+
+// sendVerification creates a verification token for a user and emails
+// them the link
+func (s *AuthService) sendVerification(ctx context.Context, q *queries.Queries, user *queries.User) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	if _, err := q.CreateEmailVerificationToken(ctx, queries.CreateEmailVerificationTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTTL),
+	}); err != nil {
+		return err
+	}
+
+	return s.sendEmail(user, "Verify your email address", "emails/verify_email.html", map[string]interface{}{
+		"URL": s.appURL + "/verify-email?token=" + url.QueryEscape(token),
+	})
+}
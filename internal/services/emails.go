@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/mailer"
+	"github.com/erickhilda/vugo/internal/templates"
+)
+
+// Email delivery options for a notification type
+const (
+	DeliveryImmediate = "immediate"
+	DeliveryDigest    = "digest"
+	DeliveryOff       = "off"
+)
+
+// EmailNotificationTypes are the notification types that are sent by email.
+// Comments on watched tasks are only shown in the app.
+var EmailNotificationTypes = []string{NotificationAssigned, NotificationMentioned, NotificationDueSoon}
+
+const (
+	// emailInterval is how often unsent notification emails are looked for
+	emailInterval = time.Minute
+	// emailBatchSize is the most immediate emails sent per run
+	emailBatchSize = 100
+	// emailMaxAge is how old a notification may be and still be emailed
+	// immediately, so emails are not sent long after the fact when sending
+	// was down or a user switches from digests to immediate emails
+	emailMaxAge = time.Hour
+	// digestHour is the hour of the day, in UTC, digests are sent at
+	digestHour = 8
+)
+
+// emailItem is one notification in an email
+type emailItem struct {
+	Summary string
+	URL     string
+	Time    sql.NullTime
+}
+
+// emailNotification is a notification to email with the names it refers to
+type emailNotification struct {
+	Notification queries.Notification
+	UserName     string
+	UserEmail    string
+	ActorName    string
+	ProjectName  string
+	TaskTitle    string
+}
+
+// newEmailNotification converts a notification row with its recipient
+func newEmailNotification(row queries.ListImmediateEmailNotificationsRow) emailNotification {
+	return emailNotification{
+		Notification: queries.Notification{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			ActorID:   row.ActorID,
+			ProjectID: row.ProjectID,
+			TaskID:    row.TaskID,
+			CommentID: row.CommentID,
+			Type:      row.Type,
+			ReadAt:    row.ReadAt,
+			CreatedAt: row.CreatedAt,
+			EmailedAt: row.EmailedAt,
+		},
+		UserName:    row.UserName,
+		UserEmail:   row.UserEmail,
+		ActorName:   row.ActorName.String,
+		ProjectName: row.ProjectName.String,
+		TaskTitle:   row.TaskTitle.String,
+	}
+}
+
+// GetEmailPreferences returns how a user receives emails for each emailed
+// notification type. Types without a preference are sent immediately.
+func (s *NotificationService) GetEmailPreferences(ctx context.Context, userID int64) (map[string]string, error) {
+	rows, err := s.queries.ListEmailPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := make(map[string]string, len(EmailNotificationTypes))
+	for _, t := range EmailNotificationTypes {
+		prefs[t] = DeliveryImmediate
+	}
+	for _, row := range rows {
+		if _, ok := prefs[row.Type]; ok {
+			prefs[row.Type] = row.Delivery
+		}
+	}
+
+	return prefs, nil
+}
+
+// UpdateEmailPreferences changes how a user receives emails for the given
+// notification types and returns the preferences for all types
+func (s *NotificationService) UpdateEmailPreferences(ctx context.Context, userID int64, prefs map[string]string) (map[string]string, error) {
+	for t, delivery := range prefs {
+		if !isEmailNotificationType(t) {
+			return nil, newValidationError(fmt.Sprintf("unknown notification type %q", t))
+		}
+		if delivery != DeliveryImmediate && delivery != DeliveryDigest && delivery != DeliveryOff {
+			return nil, newValidationError("delivery must be immediate, digest or off")
+		}
+	}
+
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		for t, delivery := range prefs {
+			if err := qtx.UpsertEmailPreference(ctx, queries.UpsertEmailPreferenceParams{
+				UserID:   userID,
+				Type:     t,
+				Delivery: delivery,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetEmailPreferences(ctx, userID)
+}
+
+// SendEmails emails the recent notifications of users who receive them
+// immediately. A notification whose email fails is retried on the next run.
+func (s *NotificationService) SendEmails(ctx context.Context) error {
+	rows, err := s.queries.ListImmediateEmailNotifications(ctx, queries.ListImmediateEmailNotificationsParams{
+		CreatedAt: sql.NullTime{Time: time.Now().UTC().Add(-emailMaxAge), Valid: true},
+		Limit:     emailBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		n := newEmailNotification(row)
+		item := s.emailItem(&n)
+		subject := item.Summary
+		if n.ProjectName != "" {
+			subject = fmt.Sprintf("[%s] %s", n.ProjectName, item.Summary)
+		}
+
+		body, err := templates.Render("emails/notification.html", map[string]interface{}{
+			"Subject":     subject,
+			"Name":        n.UserName,
+			"Item":        item,
+			"SettingsURL": s.settingsURL(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := s.mailer.Send(ctx, &mailer.Message{
+			To:      n.UserEmail,
+			Subject: subject,
+			Body:    body,
+		}); err != nil {
+			log.Printf("Error emailing notification %d: %v", n.Notification.ID, err)
+			continue
+		}
+
+		if err := s.queries.MarkNotificationEmailed(ctx, n.Notification.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendDigests emails each user who receives digests their unread
+// notifications from before the last digest hour that have not been
+// emailed yet, including those of digests missed while the server was
+// down. Notifications are marked as emailed, so running it again sends
+// nothing new.
+func (s *NotificationService) SendDigests(ctx context.Context) error {
+	end := lastDigestTime(time.Now())
+	rows, err := s.queries.ListDigestEmailNotifications(ctx, sql.NullTime{Time: end, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	// Rows are ordered by user, so each user's notifications are adjacent
+	for start := 0; start < len(rows); {
+		stop := start
+		for stop < len(rows) && rows[stop].UserID == rows[start].UserID {
+			stop++
+		}
+		digest := make([]emailNotification, 0, stop-start)
+		for _, row := range rows[start:stop] {
+			digest = append(digest, newEmailNotification(queries.ListImmediateEmailNotificationsRow(row)))
+		}
+		start = stop
+
+		if err := s.sendDigest(ctx, digest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sendDigest emails one user a digest of their notifications
+func (s *NotificationService) sendDigest(ctx context.Context, digest []emailNotification) error {
+	items := make([]emailItem, 0, len(digest))
+	for i := range digest {
+		items = append(items, s.emailItem(&digest[i]))
+	}
+
+	subject := fmt.Sprintf("Your daily digest: %d notifications", len(items))
+	if len(items) == 1 {
+		subject = "Your daily digest: 1 notification"
+	}
+	body, err := templates.Render("emails/digest.html", map[string]interface{}{
+		"Subject":     subject,
+		"Name":        digest[0].UserName,
+		"Items":       items,
+		"SettingsURL": s.settingsURL(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, &mailer.Message{
+		To:      digest[0].UserEmail,
+		Subject: subject,
+		Body:    body,
+	}); err != nil {
+		log.Printf("Error emailing digest to user %d: %v", digest[0].Notification.UserID, err)
+		return nil
+	}
+
+	for _, n := range digest {
+		if err := s.queries.MarkNotificationEmailed(ctx, n.Notification.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emailItem describes a notification in an email, linking to its task
+func (s *NotificationService) emailItem(n *emailNotification) emailItem {
+	actor := n.ActorName
+	if actor == "" {
+		actor = "Someone"
+	}
+	task := n.TaskTitle
+	if task == "" {
+		task = "a task"
+	}
+
+	var summary string
+	switch n.Notification.Type {
+	case NotificationAssigned:
+		summary = fmt.Sprintf("%s assigned you to %s", actor, task)
+	case NotificationMentioned:
+		summary = fmt.Sprintf("%s mentioned you in %s", actor, task)
+	case NotificationDueSoon:
+		summary = fmt.Sprintf("%s is due soon", task)
+	default:
+		summary = fmt.Sprintf("New activity on %s", task)
+	}
+
+	item := emailItem{Summary: summary, Time: n.Notification.CreatedAt}
+	if n.Notification.ProjectID.Valid && n.Notification.TaskID.Valid {
+		item.URL = fmt.Sprintf("%s/projects/%d/tasks/%d", s.appURL, n.Notification.ProjectID.Int64, n.Notification.TaskID.Int64)
+	}
+	return item
+}
+
+// settingsURL links to the page where users choose how they receive emails
+func (s *NotificationService) settingsURL() string {
+	return s.appURL + "/settings/notifications"
+}
+
+// lastDigestTime returns the most recent digest hour at or before now
+func lastDigestTime(now time.Time) time.Time {
+	now = now.UTC()
+	t := time.Date(now.Year(), now.Month(), now.Day(), digestHour, 0, 0, 0, time.UTC)
+	if t.After(now) {
+		t = t.AddDate(0, 0, -1)
+	}
+	return t
+}
+
+// isEmailNotificationType reports whether a notification type is emailed
+func isEmailNotificationType(t string) bool {
+	for _, emailed := range EmailNotificationTypes {
+		if t == emailed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/mailer"
+	"github.com/erickhilda/vugo/internal/templates"
+)
+
+// recordingMailer keeps the messages it is asked to send
+type recordingMailer struct {
+	mu       sync.Mutex
+	messages []*mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// sent returns the messages sent so far and forgets them
+func (m *recordingMailer) sent() []*mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sent := m.messages
+	m.messages = nil
+	return sent
+}
+
+func TestSendDigestsCatchesUpOnMissedDigests(t *testing.T) {
+	ctx := context.Background()
+	if err := templates.Init(templates.Files); err != nil {
+		t.Fatalf("templates.Init: %v", err)
+	}
+	db, q := newTestDB(t)
+	m := &recordingMailer{}
+	s := NewNotificationService(db, q, nil, m, "http://localhost")
+
+	user := createTestUser(t, q, "alice@example.com", "password123")
+	if err := q.UpsertEmailPreference(ctx, queries.UpsertEmailPreferenceParams{
+		UserID:   user.ID,
+		Type:     "assigned",
+		Delivery: DeliveryDigest,
+	}); err != nil {
+		t.Fatalf("UpsertEmailPreference: %v", err)
+	}
+
+	// Notifications from days whose digest was missed while the server
+	// was down, and one that waits for the next digest
+	for _, age := range []string{"-3 days", "-2 days", "+0 days"} {
+		n, err := q.CreateNotification(ctx, queries.CreateNotificationParams{UserID: user.ID, Type: "assigned"})
+		if err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+		if _, err := db.ExecContext(ctx, "UPDATE notifications SET created_at = datetime('now', ?) WHERE id = ?", age, n.ID); err != nil {
+			t.Fatalf("backdate notification: %v", err)
+		}
+	}
+
+	if err := s.SendDigests(ctx); err != nil {
+		t.Fatalf("SendDigests: %v", err)
+	}
+	sent := m.sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(sent))
+	}
+	if want := "Your daily digest: 2 notifications"; sent[0].Subject != want {
+		t.Errorf("subject = %q, want %q", sent[0].Subject, want)
+	}
+
+	if err := s.SendDigests(ctx); err != nil {
+		t.Fatalf("SendDigests again: %v", err)
+	}
+	if sent := m.sent(); len(sent) != 0 {
+		t.Errorf("sent %d digests on a second run, want 0", len(sent))
+	}
+
+	pending, err := q.ListDigestEmailNotifications(ctx, sql.NullTime{Time: lastDigestTime(time.Now()).AddDate(0, 0, 1), Valid: true})
+	if err != nil {
+		t.Fatalf("ListDigestEmailNotifications: %v", err)
+	}
+	if len(pending) != 1 {
+		t.Errorf("%d notifications wait for the next digest, want 1", len(pending))
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrForbidden is returned when the user may see a resource but not
+// perform the requested action on it
+var ErrForbidden = errors.New("you do not have permission to perform this action")
+
+// ErrInsufficientScope is returned when an API token's scopes do not cover
+// the requested action
+var ErrInsufficientScope = errors.New("API token does not have the required scope")
+
+// ValidationError is returned when user input fails validation
+type ValidationError struct {
+	Message string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// newValidationError creates a new validation error
+func newValidationError(message string) error {
+	return &ValidationError{Message: message}
+}
+
+// ErrRateLimited is returned when too many attempts have been made and the
+// caller has to wait before trying again
+var ErrRateLimited = errors.New("too many attempts, try again later")
+
+// RateLimitError is returned when a caller is rate limited. RetryAfter is
+// how long until they may try again.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error()
+}
+
+// Unwrap makes errors.Is match ErrRateLimited
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
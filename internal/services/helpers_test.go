@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database"
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/mailer"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestDB returns a migrated database in a temporary directory, closed
+// when the test ends
+func newTestDB(t *testing.T) (*sql.DB, *queries.Queries) {
+	t.Helper()
+
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob("../../db/migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("find migrations: %v", err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("apply %s: %v", file, err)
+		}
+	}
+
+	return db.DB, queries.New(db.DB)
+}
+
+// lenientLoginPolicy never blocks logins, for tests that fail logins on
+// purpose without testing the limits
+var lenientLoginPolicy = LoginLimitPolicy{
+	FreeFailures:    1000,
+	BaseDelay:       time.Second,
+	MaxDelay:        time.Second,
+	LockoutAfter:    1000,
+	LockoutDuration: time.Second,
+}
+
+// newTestAuthService returns an auth service on a new test database whose
+// login limiter keeps failures in memory
+func newTestAuthService(t *testing.T, policy LoginLimitPolicy) (*AuthService, *queries.Queries) {
+	t.Helper()
+
+	db, q := newTestDB(t)
+	limiter := NewLoginLimiter(NewMemoryLoginAttemptStore(), q, policy, policy)
+	return NewAuthService(db, q, mailer.NewLogMailer(), "http://localhost", true, DefaultSessionTimeouts, limiter), q
+}
+
+// createTestUser creates a user who signs in with a password
+func createTestUser(t *testing.T, q *queries.Queries, email, password string) queries.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+	user, err := q.CreateUser(context.Background(), queries.CreateUserParams{
+		Email:        email,
+		PasswordHash: string(hash),
+		Name:         "Test User",
+	})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+)
+
+var (
+	// ErrLabelNotFound is returned when a label does not exist in the project
+	// or is not attached to the task
+	ErrLabelNotFound = errors.New("label not found")
+
+	// ErrLabelAlreadyAdded is returned when adding a label the task already has
+	ErrLabelAlreadyAdded = errors.New("label is already added to this task")
+)
+
+// ListTaskLabels returns the labels of a task
+func (s *TaskService) ListTaskLabels(ctx context.Context, taskID int64) ([]queries.GetTaskLabelsRow, error) {
+	return s.queries.GetTaskLabels(ctx, taskID)
+}
+
+// AddTaskLabel attaches one of the project's labels to a task
+func (s *TaskService) AddTaskLabel(ctx context.Context, access *ProjectAccess, taskID, labelID int64) error {
+	label, err := s.queries.GetLabel(ctx, labelID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrLabelNotFound
+		}
+		return err
+	}
+	// Labels of other projects are reported as missing
+	if label.ProjectID != access.Project.ID {
+		return ErrLabelNotFound
+	}
+
+	hasLabel, err := s.queries.HasTaskLabel(ctx, queries.HasTaskLabelParams{
+		TaskID:  taskID,
+		LabelID: labelID,
+	})
+	if err != nil {
+		return err
+	}
+	if hasLabel != 0 {
+		return ErrLabelAlreadyAdded
+	}
+
+	var boardID int64
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := qtx.AddTaskLabel(ctx, queries.AddTaskLabelParams{
+			TaskID:  taskID,
+			LabelID: labelID,
+		}); err != nil {
+			return err
+		}
+
+		return recordLabelActivity(ctx, qtx, access, taskID, &label, ActivityTaskLabelAdded)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.broker.Publish(boardID, events.TaskLabelAdded, access.UserID, events.Refs{
+		TaskID:  taskID,
+		LabelID: labelID,
+	})
+
+	return nil
+}
+
+// RemoveTaskLabel detaches a label from a task
+func (s *TaskService) RemoveTaskLabel(ctx context.Context, access *ProjectAccess, taskID, labelID int64) error {
+	hasLabel, err := s.queries.HasTaskLabel(ctx, queries.HasTaskLabelParams{
+		TaskID:  taskID,
+		LabelID: labelID,
+	})
+	if err != nil {
+		return err
+	}
+	if hasLabel == 0 {
+		return ErrLabelNotFound
+	}
+
+	label, err := s.queries.GetLabel(ctx, labelID)
+	if err != nil {
+		return err
+	}
+
+	var boardID int64
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.RemoveTaskLabel(ctx, queries.RemoveTaskLabelParams{
+			TaskID:  taskID,
+			LabelID: labelID,
+		}); err != nil {
+			return err
+		}
+
+		return recordLabelActivity(ctx, qtx, access, taskID, &label, ActivityTaskLabelRemoved)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.broker.Publish(boardID, events.TaskLabelRemoved, access.UserID, events.Refs{
+		TaskID:  taskID,
+		LabelID: labelID,
+	})
+
+	return nil
+}
+
+// recordLabelActivity logs that a label was added to or removed from a task
+func recordLabelActivity(ctx context.Context, q *queries.Queries, access *ProjectAccess, taskID int64, label *queries.Label, action string) error {
+	return recordActivity(ctx, q, access.Project.ID, access.UserID, taskID, action, map[string]interface{}{
+		"label_id":    label.ID,
+		"label_name":  label.Name,
+		"label_color": label.Color,
+	})
+}
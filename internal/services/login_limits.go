@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/jobs"
+)
+
+// Security events recorded in the audit log
+const (
+	SecurityEventLoginLockout = "login_lockout"
+)
+
+// loginFailureWindow is how long failed logins are remembered. Failures
+// start over once a key has had none for this long.
+const loginFailureWindow = time.Hour
+
+// LoginLimitPolicy controls how failed logins slow down further attempts.
+// After FreeFailures, each failure blocks logins for BaseDelay, doubling up
+// to MaxDelay. From LockoutAfter failures on, logins are locked for
+// LockoutDuration.
+type LoginLimitPolicy struct {
+	FreeFailures    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+}
+
+var (
+	// AccountLoginPolicy limits guesses at one account's password
+	AccountLoginPolicy = LoginLimitPolicy{
+		FreeFailures:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+	}
+	// IPLoginPolicy limits guesses from one client across accounts, and is
+	// looser as users may share an address
+	IPLoginPolicy = LoginLimitPolicy{
+		FreeFailures:    10,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Minute,
+		LockoutAfter:    50,
+		LockoutDuration: time.Hour,
+	}
+)
+
+// delay returns how long logins are blocked after a number of failures,
+// and whether that is a lockout
+func (p LoginLimitPolicy) delay(failures int) (time.Duration, bool) {
+	if failures >= p.LockoutAfter {
+		return p.LockoutDuration, true
+	}
+	if failures <= p.FreeFailures {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeFailures + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// LoginAttemptStore keeps failed logins by key. The in-memory store is
+// lost on restart; the SQLite store keeps limits across restarts.
+type LoginAttemptStore interface {
+	// BlockedUntil returns when key may try to log in again, or the zero
+	// time if it is not blocked
+	BlockedUntil(ctx context.Context, key string) (time.Time, error)
+	// AddFailure records a failed login at now and returns the number of
+	// failures, starting over when the last one was before since
+	AddFailure(ctx context.Context, key string, now, since time.Time) (int, error)
+	// Block stops key from logging in until a time
+	Block(ctx context.Context, key string, until time.Time) error
+	// Reset forgets the failures of key
+	Reset(ctx context.Context, key string) error
+	// Cleanup forgets failures before a time that no longer block logins
+	Cleanup(ctx context.Context, before time.Time) error
+}
+
+// LoginLimiter slows down password guessing by tracking failed logins per
+// account and per client IP. Lockouts are recorded in the security audit
+// log.
+type LoginLimiter struct {
+	store   LoginAttemptStore
+	queries *queries.Queries
+	account LoginLimitPolicy
+	ip      LoginLimitPolicy
+}
+
+// NewLoginLimiter creates a new login limiter
+func NewLoginLimiter(store LoginAttemptStore, q *queries.Queries, account, ip LoginLimitPolicy) *LoginLimiter {
+	return &LoginLimiter{
+		store:   store,
+		queries: q,
+		account: account,
+		ip:      ip,
+	}
+}
+
+// Check returns a RateLimitError if logins to an account or from an IP are
+// blocked
+func (l *LoginLimiter) Check(ctx context.Context, email, ip string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, key := range l.keys(email, ip) {
+		until, err := l.store.BlockedUntil(ctx, key)
+		if err != nil {
+			return err
+		}
+		if wait := until.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &RateLimitError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+// Fail records a failed login to an account from an IP. userID is set when
+// the account exists.
+func (l *LoginLimiter) Fail(ctx context.Context, email, ip string, userID sql.NullInt64) error {
+	now := time.Now().UTC()
+	keys := l.keys(email, ip)
+	policies := []LoginLimitPolicy{l.account, l.ip}
+
+	for i, key := range keys {
+		failures, err := l.store.AddFailure(ctx, key, now, now.Add(-loginFailureWindow))
+		if err != nil {
+			return err
+		}
+
+		delay, lockout := policies[i].delay(failures)
+		if delay == 0 {
+			continue
+		}
+		until := now.Add(delay)
+		if err := l.store.Block(ctx, key, until); err != nil {
+			return err
+		}
+
+		if lockout {
+			// Lockouts by IP are not about one user
+			eventUserID := userID
+			if i == 1 {
+				eventUserID = sql.NullInt64{}
+			}
+			l.recordLockout(ctx, eventUserID, ip, map[string]interface{}{
+				"key":          key,
+				"failures":     failures,
+				"locked_until": until.Format(time.RFC3339),
+			})
+		}
+	}
+	return nil
+}
+
+// Succeed forgets the failed logins to an account once its user has
+// signed in. Failures from the IP are kept, so an attacker cannot clear
+// them by signing in to their own account.
+func (l *LoginLimiter) Succeed(ctx context.Context, email string) error {
+	return l.store.Reset(ctx, "account:"+normalizeEmail(email))
+}
+
+// ListSecurityEvents returns a page of security audit events, most recent
+// first
+func (l *LoginLimiter) ListSecurityEvents(ctx context.Context, limit, offset int64) ([]queries.SecurityEvent, error) {
+	return l.queries.ListSecurityEvents(ctx, queries.ListSecurityEventsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// Jobs returns the background job that forgets old failed logins
+func (l *LoginLimiter) Jobs() []jobs.Job {
+	return []jobs.Job{
+		{Name: "login-attempt-cleanup", Interval: cleanupInterval, Run: func(ctx context.Context) error {
+			return l.store.Cleanup(ctx, time.Now().Add(-loginFailureWindow).UTC())
+		}},
+	}
+}
+
+// keys returns the store keys of an account and an IP
+func (l *LoginLimiter) keys(email, ip string) []string {
+	return []string{"account:" + normalizeEmail(email), "ip:" + ip}
+}
+
+// recordLockout adds a lockout to the audit log. Failing to record it is
+// logged but does not fail the login.
+func (l *LoginLimiter) recordLockout(ctx context.Context, userID sql.NullInt64, ip string, details map[string]interface{}) {
+	log.Printf("Login locked out for %s after %d failures", details["key"], details["failures"])
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		log.Printf("Error encoding lockout details: %v", err)
+		return
+	}
+	if err := l.queries.CreateSecurityEvent(ctx, queries.CreateSecurityEventParams{
+		UserID:    userID,
+		Event:     SecurityEventLoginLockout,
+		IpAddress: ip,
+		Details:   string(data),
+	}); err != nil {
+		log.Printf("Error recording login lockout: %v", err)
+	}
+}
+
+// MemoryLoginAttemptStore keeps failed logins in memory. Limits are lost on
+// restart and not shared between server processes.
+type MemoryLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*memoryLoginAttempt
+}
+
+// memoryLoginAttempt is the failed logins of one key
+type memoryLoginAttempt struct {
+	failures     int
+	blockedUntil time.Time
+	lastFailedAt time.Time
+}
+
+// NewMemoryLoginAttemptStore creates a new in-memory login attempt store
+func NewMemoryLoginAttemptStore() *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{
+		attempts: make(map[string]*memoryLoginAttempt),
+	}
+}
+
+// BlockedUntil implements LoginAttemptStore
+func (s *MemoryLoginAttemptStore) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		return attempt.blockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+// AddFailure implements LoginAttemptStore
+func (s *MemoryLoginAttemptStore) AddFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = &memoryLoginAttempt{}
+		s.attempts[key] = attempt
+	}
+	if attempt.lastFailedAt.Before(since) {
+		attempt.failures = 0
+	}
+	attempt.failures++
+	attempt.lastFailedAt = now
+	return attempt.failures, nil
+}
+
+// Block implements LoginAttemptStore
+func (s *MemoryLoginAttemptStore) Block(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.blockedUntil = until
+	}
+	return nil
+}
+
+// Reset implements LoginAttemptStore
+func (s *MemoryLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// Cleanup implements LoginAttemptStore
+func (s *MemoryLoginAttemptStore) Cleanup(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, attempt := range s.attempts {
+		if attempt.lastFailedAt.Before(before) && attempt.blockedUntil.Before(now) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
+
+// SQLiteLoginAttemptStore keeps failed logins in the database, so limits
+// survive restarts
+type SQLiteLoginAttemptStore struct {
+	queries *queries.Queries
+}
+
+// NewSQLiteLoginAttemptStore creates a new SQLite login attempt store
+func NewSQLiteLoginAttemptStore(q *queries.Queries) *SQLiteLoginAttemptStore {
+	return &SQLiteLoginAttemptStore{
+		queries: q,
+	}
+}
+
+// BlockedUntil implements LoginAttemptStore
+func (s *SQLiteLoginAttemptStore) BlockedUntil(ctx context.Context, key string) (time.Time, error) {
+	attempt, err := s.queries.GetLoginAttempt(ctx, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	if !attempt.BlockedUntil.Valid {
+		return time.Time{}, nil
+	}
+	return attempt.BlockedUntil.Time, nil
+}
+
+// AddFailure implements LoginAttemptStore
+func (s *SQLiteLoginAttemptStore) AddFailure(ctx context.Context, key string, now, since time.Time) (int, error) {
+	failures, err := s.queries.AddLoginFailure(ctx, queries.AddLoginFailureParams{
+		AttemptKey:   key,
+		LastFailedAt: now.UTC(),
+		Since:        since.UTC(),
+	})
+	return int(failures), err
+}
+
+// Block implements LoginAttemptStore
+func (s *SQLiteLoginAttemptStore) Block(ctx context.Context, key string, until time.Time) error {
+	return s.queries.BlockLoginAttempts(ctx, queries.BlockLoginAttemptsParams{
+		BlockedUntil: sql.NullTime{Time: until.UTC(), Valid: true},
+		AttemptKey:   key,
+	})
+}
+
+// Reset implements LoginAttemptStore
+func (s *SQLiteLoginAttemptStore) Reset(ctx context.Context, key string) error {
+	return s.queries.DeleteLoginAttempt(ctx, key)
+}
+
+// Cleanup implements LoginAttemptStore
+func (s *SQLiteLoginAttemptStore) Cleanup(ctx context.Context, before time.Time) error {
+	return s.queries.DeleteStaleLoginAttempts(ctx, before.UTC())
+}
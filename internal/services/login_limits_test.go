@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLoginLimitPolicyDelay(t *testing.T) {
+	// Capped early so the cap shows before the lockout
+	capped := LoginLimitPolicy{
+		FreeFailures:    0,
+		BaseDelay:       time.Second,
+		MaxDelay:        5 * time.Second,
+		LockoutAfter:    20,
+		LockoutDuration: time.Hour,
+	}
+
+	tests := []struct {
+		name        string
+		policy      LoginLimitPolicy
+		failures    int
+		wantDelay   time.Duration
+		wantLockout bool
+	}{
+		{"no failures", AccountLoginPolicy, 0, 0, false},
+		{"last free failure", AccountLoginPolicy, 3, 0, false},
+		{"first delayed failure", AccountLoginPolicy, 4, time.Second, false},
+		{"doubles", AccountLoginPolicy, 5, 2 * time.Second, false},
+		{"doubles again", AccountLoginPolicy, 6, 4 * time.Second, false},
+		{"before lockout", AccountLoginPolicy, 9, 32 * time.Second, false},
+		{"lockout", AccountLoginPolicy, 10, 15 * time.Minute, true},
+		{"after lockout", AccountLoginPolicy, 25, 15 * time.Minute, true},
+		{"IP free failures", IPLoginPolicy, 10, 0, false},
+		{"IP first delayed failure", IPLoginPolicy, 11, time.Second, false},
+		{"IP capped", IPLoginPolicy, 49, 5 * time.Minute, false},
+		{"IP lockout", IPLoginPolicy, 50, time.Hour, true},
+		{"below cap", capped, 3, 4 * time.Second, false},
+		{"reaches cap", capped, 4, 5 * time.Second, false},
+		{"stays at cap", capped, 19, 5 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, lockout := tt.policy.delay(tt.failures)
+			if delay != tt.wantDelay || lockout != tt.wantLockout {
+				t.Errorf("delay(%d) = %v, %v, want %v, %v", tt.failures, delay, lockout, tt.wantDelay, tt.wantLockout)
+			}
+		})
+	}
+}
+
+// testLoginPolicy blocks after two failures and locks out after four
+var testLoginPolicy = LoginLimitPolicy{
+	FreeFailures:    2,
+	BaseDelay:       time.Minute,
+	MaxDelay:        time.Minute,
+	LockoutAfter:    4,
+	LockoutDuration: time.Hour,
+}
+
+// newTestLoginLimiter returns a login limiter using testLoginPolicy for
+// accounts and IPs that keeps failures in memory
+func newTestLoginLimiter(t *testing.T) (*LoginLimiter, *MemoryLoginAttemptStore) {
+	t.Helper()
+
+	_, q := newTestDB(t)
+	store := NewMemoryLoginAttemptStore()
+	return NewLoginLimiter(store, q, testLoginPolicy, testLoginPolicy), store
+}
+
+// failLogins fails n logins to an account from an IP
+func failLogins(t *testing.T, l *LoginLimiter, email, ip string, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if err := l.Fail(context.Background(), email, ip, sql.NullInt64{}); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+}
+
+// retryAfter returns how long Check blocks logins, or 0 if it allows them
+func retryAfter(t *testing.T, l *LoginLimiter, email, ip string) time.Duration {
+	t.Helper()
+
+	err := l.Check(context.Background(), email, ip)
+	if err == nil {
+		return 0
+	}
+	var rateErr *RateLimitError
+	if !errors.As(err, &rateErr) {
+		t.Fatalf("Check: %v, want a RateLimitError", err)
+	}
+	return rateErr.RetryAfter
+}
+
+func TestLoginLimiterBackoffAndLockout(t *testing.T) {
+	tests := []struct {
+		failures int
+		// wantBlocked is roughly how long logins are blocked
+		wantBlocked time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.failures), func(t *testing.T) {
+			l, _ := newTestLoginLimiter(t)
+			failLogins(t, l, "alice@example.com", "192.0.2.1", tt.failures)
+
+			got := retryAfter(t, l, "alice@example.com", "192.0.2.1")
+			if got > tt.wantBlocked || got < tt.wantBlocked-time.Second {
+				t.Errorf("blocked for %v after %d failures, want %v", got, tt.failures, tt.wantBlocked)
+			}
+		})
+	}
+}
+
+func TestLoginLimiterRecordsLockouts(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLoginLimiter(t)
+
+	failLogins(t, l, "alice@example.com", "192.0.2.1", testLoginPolicy.LockoutAfter-1)
+	events, err := l.ListSecurityEvents(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListSecurityEvents: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("got %d security events before the lockout, want 0", len(events))
+	}
+
+	// Both the account and the IP are locked out
+	failLogins(t, l, "alice@example.com", "192.0.2.1", 1)
+	events, err = l.ListSecurityEvents(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListSecurityEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d security events, want 2", len(events))
+	}
+	for _, event := range events {
+		if event.Event != SecurityEventLoginLockout || event.IpAddress != "192.0.2.1" {
+			t.Errorf("got event %q from %q, want %q from 192.0.2.1", event.Event, event.IpAddress, SecurityEventLoginLockout)
+		}
+	}
+}
+
+func TestLoginLimiterWindowReset(t *testing.T) {
+	l, store := newTestLoginLimiter(t)
+
+	failLogins(t, l, "alice@example.com", "192.0.2.1", testLoginPolicy.LockoutAfter-1)
+	if retryAfter(t, l, "alice@example.com", "192.0.2.1") == 0 {
+		t.Fatal("logins are not blocked")
+	}
+
+	// The failures age out of the window and the block ends
+	store.mu.Lock()
+	for _, attempt := range store.attempts {
+		attempt.lastFailedAt = attempt.lastFailedAt.Add(-loginFailureWindow - time.Second)
+		attempt.blockedUntil = time.Now().Add(-time.Second)
+	}
+	store.mu.Unlock()
+
+	// Counting starts over, so the next failure is free instead of a
+	// lockout
+	failLogins(t, l, "alice@example.com", "192.0.2.1", 1)
+	if got := retryAfter(t, l, "alice@example.com", "192.0.2.1"); got != 0 {
+		t.Errorf("blocked for %v after failures aged out, want 0", got)
+	}
+}
+
+func TestLoginLimiterSucceedKeepsIPFailures(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLoginLimiter(t)
+
+	failLogins(t, l, "alice@example.com", "192.0.2.1", testLoginPolicy.FreeFailures+1)
+	if err := l.Succeed(ctx, "Alice@Example.com"); err != nil {
+		t.Fatalf("Succeed: %v", err)
+	}
+
+	if got := retryAfter(t, l, "alice@example.com", "198.51.100.1"); got != 0 {
+		t.Errorf("account blocked for %v after signing in, want 0", got)
+	}
+	if got := retryAfter(t, l, "bob@example.com", "192.0.2.1"); got == 0 {
+		t.Error("IP is not blocked after an account signed in from it")
+	}
+}
+
+func TestLoginAttemptStores(t *testing.T) {
+	stores := []struct {
+		name string
+		new  func(t *testing.T) LoginAttemptStore
+	}{
+		{"memory", func(t *testing.T) LoginAttemptStore {
+			return NewMemoryLoginAttemptStore()
+		}},
+		{"sqlite", func(t *testing.T) LoginAttemptStore {
+			_, q := newTestDB(t)
+			return NewSQLiteLoginAttemptStore(q)
+		}},
+	}
+
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			testLoginAttemptStore(t, st.new)
+		})
+	}
+}
+
+// testLoginAttemptStore checks that a store keeps to the LoginAttemptStore
+// contract
+func testLoginAttemptStore(t *testing.T, newStore func(t *testing.T) LoginAttemptStore) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	longAgo := now.Add(-24 * time.Hour)
+
+	// addFailures adds n failures at a time and returns the last count
+	addFailures := func(t *testing.T, s LoginAttemptStore, key string, at, since time.Time, n int) int {
+		t.Helper()
+		var failures int
+		for i := 0; i < n; i++ {
+			var err error
+			if failures, err = s.AddFailure(ctx, key, at, since); err != nil {
+				t.Fatalf("AddFailure: %v", err)
+			}
+		}
+		return failures
+	}
+	blockedUntil := func(t *testing.T, s LoginAttemptStore, key string) time.Time {
+		t.Helper()
+		until, err := s.BlockedUntil(ctx, key)
+		if err != nil {
+			t.Fatalf("BlockedUntil: %v", err)
+		}
+		return until
+	}
+
+	t.Run("counts failures", func(t *testing.T) {
+		s := newStore(t)
+		if until := blockedUntil(t, s, "account:a"); !until.IsZero() {
+			t.Errorf("unknown key blocked until %v", until)
+		}
+		if got := addFailures(t, s, "account:a", now, longAgo, 3); got != 3 {
+			t.Errorf("got %d failures, want 3", got)
+		}
+		if got := addFailures(t, s, "ip:a", now, longAgo, 1); got != 1 {
+			t.Errorf("got %d failures for another key, want 1", got)
+		}
+	})
+
+	t.Run("starts over after the window", func(t *testing.T) {
+		s := newStore(t)
+		addFailures(t, s, "account:a", now.Add(-loginFailureWindow-time.Minute), longAgo, 3)
+		if got := addFailures(t, s, "account:a", now, now.Add(-loginFailureWindow), 1); got != 1 {
+			t.Errorf("got %d failures after the window, want 1", got)
+		}
+	})
+
+	t.Run("blocks", func(t *testing.T) {
+		s := newStore(t)
+		until := now.Add(time.Minute)
+		addFailures(t, s, "account:a", now, longAgo, 1)
+		if err := s.Block(ctx, "account:a", until); err != nil {
+			t.Fatalf("Block: %v", err)
+		}
+		if got := blockedUntil(t, s, "account:a"); !got.Equal(until) {
+			t.Errorf("blocked until %v, want %v", got, until)
+		}
+	})
+
+	t.Run("resets one key", func(t *testing.T) {
+		s := newStore(t)
+		for _, key := range []string{"account:a", "ip:a"} {
+			addFailures(t, s, key, now, longAgo, 2)
+			if err := s.Block(ctx, key, now.Add(time.Minute)); err != nil {
+				t.Fatalf("Block: %v", err)
+			}
+		}
+		if err := s.Reset(ctx, "account:a"); err != nil {
+			t.Fatalf("Reset: %v", err)
+		}
+
+		if until := blockedUntil(t, s, "account:a"); !until.IsZero() {
+			t.Errorf("reset key blocked until %v", until)
+		}
+		if got := addFailures(t, s, "account:a", now, longAgo, 1); got != 1 {
+			t.Errorf("got %d failures after reset, want 1", got)
+		}
+		if until := blockedUntil(t, s, "ip:a"); until.IsZero() {
+			t.Error("other key is no longer blocked")
+		}
+	})
+
+	t.Run("cleans up", func(t *testing.T) {
+		s := newStore(t)
+		old := now.Add(-2 * loginFailureWindow)
+		addFailures(t, s, "account:stale", old, longAgo, 2)
+		addFailures(t, s, "account:blocked", old, longAgo, 2)
+		if err := s.Block(ctx, "account:blocked", now.Add(time.Hour)); err != nil {
+			t.Fatalf("Block: %v", err)
+		}
+		addFailures(t, s, "account:recent", now, longAgo, 2)
+
+		if err := s.Cleanup(ctx, now.Add(-loginFailureWindow)); err != nil {
+			t.Fatalf("Cleanup: %v", err)
+		}
+
+		// Kept keys go on counting
+		for key, want := range map[string]int{"account:stale": 1, "account:blocked": 3, "account:recent": 3} {
+			if got := addFailures(t, s, key, now, longAgo, 1); got != want {
+				t.Errorf("%s: got %d failures after cleanup, want %d", key, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+var (
+	// ErrMemberNotFound is returned when a user is not a member of the project
+	ErrMemberNotFound = errors.New("member not found")
+
+	// ErrUserNotFound is returned when no user matches the given email
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrAlreadyMember is returned when adding a user who is already a member
+	ErrAlreadyMember = errors.New("user is already a project member")
+)
+
+// MemberDetail is a project member together with their user information
+type MemberDetail struct {
+	Member queries.ProjectMember
+	User   queries.User
+}
+
+// ListMembers returns all members of a project with their user information
+func (s *ProjectService) ListMembers(ctx context.Context, projectID int64) ([]queries.ListProjectMembersRow, error) {
+	return s.queries.ListProjectMembers(ctx, projectID)
+}
+
+// AddMember adds an existing user to a project by email
+func (s *ProjectService) AddMember(ctx context.Context, access *ProjectAccess, email string, role Role) (*MemberDetail, error) {
+	if role == RoleOwner {
+		return nil, newValidationError("ownership can only be granted by transferring the project")
+	}
+
+	// Nobody can grant more than they have
+	if !access.Role.AtLeast(role) {
+		return nil, ErrForbidden
+	}
+
+	user, err := s.queries.GetUserByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if s.requireVerifiedEmail && !user.EmailVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	isMember, err := s.queries.IsProjectMember(ctx, queries.IsProjectMemberParams{
+		ProjectID: access.Project.ID,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if isMember != 0 || user.ID == access.Project.OwnerID {
+		return nil, ErrAlreadyMember
+	}
+
+	var member queries.ProjectMember
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		member, err = qtx.AddProjectMember(ctx, queries.AddProjectMemberParams{
+			ProjectID: access.Project.ID,
+			UserID:    user.ID,
+			Role:      string(role),
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityMemberAdded, map[string]interface{}{
+			"user_id":   user.ID,
+			"user_name": user.Name,
+			"role":      member.Role,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemberDetail{Member: member, User: user}, nil
+}
+
+// UpdateMemberRole changes the role of a project member
+func (s *ProjectService) UpdateMemberRole(ctx context.Context, access *ProjectAccess, userID int64, role Role) error {
+	if role == RoleOwner {
+		return newValidationError("ownership can only be granted by transferring the project")
+	}
+
+	if !access.Role.AtLeast(role) {
+		return ErrForbidden
+	}
+
+	member, err := s.getManageableMember(ctx, access, userID)
+	if err != nil {
+		return err
+	}
+
+	if member.Role == string(role) {
+		return nil
+	}
+
+	return inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		if err := qtx.UpdateProjectMemberRole(ctx, queries.UpdateProjectMemberRoleParams{
+			Role:      string(role),
+			ProjectID: member.ProjectID,
+			UserID:    member.UserID,
+		}); err != nil {
+			return err
+		}
+
+		changes := changeSet{}
+		changes.add("role", member.Role, string(role))
+
+		details := changes.details()
+		details["user_id"] = member.UserID
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityMemberRoleChanged, details)
+	})
+}
+
+// RemoveMember removes a user from a project and stops them watching its
+// tasks
+func (s *ProjectService) RemoveMember(ctx context.Context, access *ProjectAccess, userID int64) error {
+	member, err := s.getManageableMember(ctx, access, userID)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		if err := qtx.RemoveProjectMember(ctx, queries.RemoveProjectMemberParams{
+			ProjectID: member.ProjectID,
+			UserID:    member.UserID,
+		}); err != nil {
+			return err
+		}
+		if err := qtx.DeleteProjectTaskWatchers(ctx, queries.DeleteProjectTaskWatchersParams{
+			UserID:    member.UserID,
+			ProjectID: member.ProjectID,
+		}); err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityMemberRemoved, map[string]interface{}{
+			"user_id": member.UserID,
+			"role":    member.Role,
+		})
+	})
+}
+
+// TransferOwnership makes another member the owner of the project. The
+// previous owner stays on as an admin.
+func (s *ProjectService) TransferOwnership(ctx context.Context, project *queries.Project, newOwnerID int64) error {
+	if newOwnerID == project.OwnerID {
+		return newValidationError("user already owns this project")
+	}
+
+	_, err := s.queries.GetProjectMember(ctx, queries.GetProjectMemberParams{
+		ProjectID: project.ID,
+		UserID:    newOwnerID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.TransferProjectOwnership(ctx, queries.TransferProjectOwnershipParams{
+		OwnerID: newOwnerID,
+		ID:      project.ID,
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.UpsertProjectMember(ctx, queries.UpsertProjectMemberParams{
+		ProjectID: project.ID,
+		UserID:    newOwnerID,
+		Role:      string(RoleOwner),
+	}); err != nil {
+		return err
+	}
+
+	if err := qtx.UpsertProjectMember(ctx, queries.UpsertProjectMemberParams{
+		ProjectID: project.ID,
+		UserID:    project.OwnerID,
+		Role:      string(RoleAdmin),
+	}); err != nil {
+		return err
+	}
+
+	// Only the owner can transfer a project, so the previous owner acted
+	if err := recordActivity(ctx, qtx, project.ID, project.OwnerID, 0, ActivityProjectTransferred, map[string]interface{}{
+		"from_user_id": project.OwnerID,
+		"to_user_id":   newOwnerID,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getManageableMember loads a member and checks that the acting user may
+// change them. The owner can never be changed this way, and only the owner
+// may change other admins.
+func (s *ProjectService) getManageableMember(ctx context.Context, access *ProjectAccess, userID int64) (*queries.ProjectMember, error) {
+	if userID == access.Project.OwnerID {
+		return nil, ErrForbidden
+	}
+
+	member, err := s.queries.GetProjectMember(ctx, queries.GetProjectMemberParams{
+		ProjectID: access.Project.ID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+
+	if Role(member.Role).AtLeast(RoleAdmin) && access.Role != RoleOwner && userID != access.UserID {
+		return nil, ErrForbidden
+	}
+
+	return &member, nil
+}
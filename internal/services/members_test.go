@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+// seedTask creates a project owned by owner with a task due tomorrow and
+// returns them
+func seedTask(t *testing.T, q *queries.Queries, owner queries.User, name string) (queries.Project, queries.Task) {
+	t.Helper()
+	ctx := context.Background()
+
+	project, err := q.CreateProject(ctx, queries.CreateProjectParams{OwnerID: owner.ID, Name: name})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	if _, err := q.AddProjectMember(ctx, queries.AddProjectMemberParams{
+		ProjectID: project.ID,
+		UserID:    owner.ID,
+		Role:      string(RoleOwner),
+	}); err != nil {
+		t.Fatalf("add owner: %v", err)
+	}
+	board, err := q.CreateBoard(ctx, queries.CreateBoardParams{ProjectID: project.ID, Name: "Board", Rank: "i"})
+	if err != nil {
+		t.Fatalf("create board: %v", err)
+	}
+	column, err := q.CreateColumn(ctx, queries.CreateColumnParams{BoardID: board.ID, Name: "To do", Rank: "i"})
+	if err != nil {
+		t.Fatalf("create column: %v", err)
+	}
+	task, err := q.CreateTask(ctx, queries.CreateTaskParams{
+		ColumnID:  column.ID,
+		CreatedBy: owner.ID,
+		Title:     "Task",
+		Rank:      "i",
+		DueDate:   sql.NullTime{Time: time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second), Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("create task: %v", err)
+	}
+	return project, task
+}
+
+// watchers returns who is notified about a task's comments and due date
+func watchers(t *testing.T, q *queries.Queries, taskID int64) (commented, dueSoon string) {
+	t.Helper()
+	ctx := context.Background()
+
+	ids, err := q.ListTaskWatcherIDs(ctx, taskID)
+	if err != nil {
+		t.Fatalf("ListTaskWatcherIDs: %v", err)
+	}
+	due, err := q.ListDueSoonTaskWatchers(ctx)
+	if err != nil {
+		t.Fatalf("ListDueSoonTaskWatchers: %v", err)
+	}
+	var dueIDs []int64
+	for _, row := range due {
+		if row.TaskID == taskID {
+			dueIDs = append(dueIDs, row.UserID)
+		}
+	}
+	return fmt.Sprint(ids), fmt.Sprint(dueIDs)
+}
+
+func TestRemoveMemberStopsWatching(t *testing.T) {
+	ctx := context.Background()
+	db, q := newTestDB(t)
+	s := NewProjectService(db, q, false)
+
+	owner := createTestUser(t, q, "owner@example.com", "password123")
+	member := createTestUser(t, q, "member@example.com", "password123")
+	project, task := seedTask(t, q, owner, "Project")
+	otherProject, otherTask := seedTask(t, q, owner, "Other project")
+
+	seeded := []struct {
+		project queries.Project
+		task    queries.Task
+	}{{project, task}, {otherProject, otherTask}}
+	for _, st := range seeded {
+		if _, err := q.AddProjectMember(ctx, queries.AddProjectMemberParams{
+			ProjectID: st.project.ID,
+			UserID:    member.ID,
+			Role:      string(RoleMember),
+		}); err != nil {
+			t.Fatalf("add member: %v", err)
+		}
+		for _, userID := range []int64{owner.ID, member.ID} {
+			if err := q.WatchTask(ctx, queries.WatchTaskParams{TaskID: st.task.ID, UserID: userID}); err != nil {
+				t.Fatalf("watch task: %v", err)
+			}
+		}
+	}
+
+	want := fmt.Sprint([]int64{owner.ID, member.ID})
+	if commented, dueSoon := watchers(t, q, task.ID); commented != want || dueSoon != want {
+		t.Fatalf("watchers before removal = %s and %s, want %s", commented, dueSoon, want)
+	}
+
+	access := &ProjectAccess{Project: project, UserID: owner.ID, Role: RoleOwner}
+	if err := s.RemoveMember(ctx, access, member.ID); err != nil {
+		t.Fatalf("RemoveMember: %v", err)
+	}
+
+	watching, err := q.IsWatchingTask(ctx, queries.IsWatchingTaskParams{TaskID: task.ID, UserID: member.ID})
+	if err != nil {
+		t.Fatalf("IsWatchingTask: %v", err)
+	}
+	if watching != 0 {
+		t.Error("removed member still watches the project's task")
+	}
+	if commented, dueSoon := watchers(t, q, otherTask.ID); commented != want || dueSoon != want {
+		t.Errorf("watchers of another project = %s and %s, want %s", commented, dueSoon, want)
+	}
+
+	// A watch left behind by an ex-member does not notify them
+	if err := q.WatchTask(ctx, queries.WatchTaskParams{TaskID: task.ID, UserID: member.ID}); err != nil {
+		t.Fatalf("watch task: %v", err)
+	}
+	want = fmt.Sprint([]int64{owner.ID})
+	if commented, dueSoon := watchers(t, q, task.ID); commented != want || dueSoon != want {
+		t.Errorf("watchers after removal = %s and %s, want %s", commented, dueSoon, want)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/markdown"
+)
+
+// mentionKey normalizes a mention handle or member name for lookup, so
+// "@JaneDoe" and "@janedoe" both mention "Jane Doe"
+func mentionKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
+// memberMentions resolves mentions against the members of a project. A
+// member is mentioned by email or by name without spaces, ignoring case. A
+// name shared by several members mentions none of them.
+func memberMentions(ctx context.Context, q *queries.Queries, projectID int64) (markdown.MentionResolver, error) {
+	members, err := q.ListProjectMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail := make(map[string]markdown.Mention, len(members))
+	byName := make(map[string]markdown.Mention, len(members))
+	ambiguous := make(map[string]bool)
+	for _, m := range members {
+		mention := markdown.Mention{UserID: m.UserID, Name: m.UserName}
+		byEmail[strings.ToLower(m.UserEmail)] = mention
+
+		key := mentionKey(m.UserName)
+		if _, ok := byName[key]; ok {
+			ambiguous[key] = true
+		}
+		byName[key] = mention
+	}
+
+	return func(handle string) (markdown.Mention, bool) {
+		if strings.Contains(handle, "@") {
+			mention, ok := byEmail[strings.ToLower(handle)]
+			return mention, ok
+		}
+
+		key := mentionKey(handle)
+		if ambiguous[key] {
+			return markdown.Mention{}, false
+		}
+		mention, ok := byName[key]
+		return mention, ok
+	}, nil
+}
+
+// renderMentions renders Markdown with mentions of project members turned
+// into links and returns the members mentioned
+func renderMentions(ctx context.Context, q *queries.Queries, projectID int64, source string) (string, []markdown.Mention, error) {
+	resolve, err := memberMentions(ctx, q, projectID)
+	if err != nil {
+		return "", nil, err
+	}
+	return markdown.RenderMentions(source, resolve)
+}
+
+// syncMentions stores who is mentioned in a task description (commentID
+// zero) or comment and notifies the members mentioned for the first time.
+// Users are not notified of mentioning themselves. It returns the users
+// notified.
+func syncMentions(ctx context.Context, q *queries.Queries, out *outbox, access *ProjectAccess, taskID, commentID int64, mentions []markdown.Mention) (map[int64]bool, error) {
+	existing, err := q.ListMentionedUserIDs(ctx, queries.ListMentionedUserIDsParams{
+		TaskID:    taskID,
+		CommentID: nullID(commentID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	mentioned := make(map[int64]bool, len(mentions))
+	for _, m := range mentions {
+		mentioned[m.UserID] = true
+	}
+
+	previous := make(map[int64]bool, len(existing))
+	for _, userID := range existing {
+		previous[userID] = true
+		if mentioned[userID] {
+			continue
+		}
+		if err := q.DeleteMention(ctx, queries.DeleteMentionParams{
+			TaskID:    taskID,
+			CommentID: nullID(commentID),
+			UserID:    userID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	notified := make(map[int64]bool)
+	for _, m := range mentions {
+		if previous[m.UserID] {
+			continue
+		}
+		if err := q.CreateMention(ctx, queries.CreateMentionParams{
+			TaskID:    taskID,
+			CommentID: nullID(commentID),
+			UserID:    m.UserID,
+		}); err != nil {
+			return nil, err
+		}
+
+		if m.UserID == access.UserID {
+			continue
+		}
+		if err := out.notify(ctx, q, notification{
+			UserID:    m.UserID,
+			ActorID:   access.UserID,
+			ProjectID: access.Project.ID,
+			TaskID:    taskID,
+			CommentID: commentID,
+			Type:      NotificationMentioned,
+		}); err != nil {
+			return nil, err
+		}
+		notified[m.UserID] = true
+	}
+
+	return notified, nil
+}
+
+// syncDescriptionMentions stores who is mentioned in a task's description
+// and notifies the members mentioned for the first time
+func syncDescriptionMentions(ctx context.Context, q *queries.Queries, out *outbox, access *ProjectAccess, task *queries.Task) error {
+	resolve, err := memberMentions(ctx, q, access.Project.ID)
+	if err != nil {
+		return err
+	}
+
+	mentions, err := markdown.Mentions(task.Description.String, resolve)
+	if err != nil {
+		return err
+	}
+
+	_, err = syncMentions(ctx, q, out, access, task.ID, 0, mentions)
+	return err
+}
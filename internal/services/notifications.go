@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+	"github.com/erickhilda/vugo/internal/jobs"
+	"github.com/erickhilda/vugo/internal/mailer"
+)
+
+// ErrNotificationNotFound is returned when a notification does not exist
+// or belongs to another user
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// Notification types
+const (
+	NotificationMentioned = "mentioned"
+	NotificationAssigned  = "assigned"
+	NotificationCommented = "commented"
+	NotificationDueSoon   = "due_soon"
+)
+
+// dueReminderInterval is how often tasks are checked for due dates coming
+// up
+const dueReminderInterval = time.Hour
+
+// NotificationService handles reading notifications, watching tasks,
+// sending due date reminders and emailing notifications. appURL is the
+// address of the app that emails link to.
+type NotificationService struct {
+	db      *sql.DB
+	queries *queries.Queries
+	broker  *events.Broker
+	mailer  mailer.Mailer
+	appURL  string
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(db *sql.DB, q *queries.Queries, broker *events.Broker, m mailer.Mailer, appURL string) *NotificationService {
+	return &NotificationService{
+		db:      db,
+		queries: q,
+		broker:  broker,
+		mailer:  m,
+		appURL:  strings.TrimSuffix(appURL, "/"),
+	}
+}
+
+// NotificationEntry is a notification with the names of the user, project
+// and task it refers to. Names are empty when the notification does not
+// refer to one, or it has since been deleted.
+type NotificationEntry struct {
+	Notification   queries.Notification
+	ActorName      string
+	ActorEmail     string
+	ActorAvatarURL string
+	ProjectName    string
+	TaskTitle      string
+}
+
+// NotificationPage is one page of a user's notifications, newest first,
+// with the user's total unread count
+type NotificationPage struct {
+	Entries []NotificationEntry
+	HasMore bool
+	Unread  int64
+}
+
+// newNotificationEntry converts a notification row with its details
+func newNotificationEntry(row queries.GetNotificationWithDetailsRow) NotificationEntry {
+	return NotificationEntry{
+		Notification: queries.Notification{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			ActorID:   row.ActorID,
+			ProjectID: row.ProjectID,
+			TaskID:    row.TaskID,
+			CommentID: row.CommentID,
+			Type:      row.Type,
+			ReadAt:    row.ReadAt,
+			CreatedAt: row.CreatedAt,
+			EmailedAt: row.EmailedAt,
+		},
+		ActorName:      row.ActorName.String,
+		ActorEmail:     row.ActorEmail.String,
+		ActorAvatarURL: row.ActorAvatarUrl.String,
+		ProjectName:    row.ProjectName.String,
+		TaskTitle:      row.TaskTitle.String,
+	}
+}
+
+// ListNotifications returns a page of a user's notifications, optionally
+// only the unread ones
+func (s *NotificationService) ListNotifications(ctx context.Context, userID int64, unreadOnly bool, limit, offset int64) (*NotificationPage, error) {
+	// Fetch one extra row to tell whether another page follows
+	var rows []queries.GetNotificationWithDetailsRow
+	if unreadOnly {
+		unread, err := s.queries.ListUnreadNotificationsByUser(ctx, queries.ListUnreadNotificationsByUserParams{
+			UserID: userID,
+			Limit:  limit + 1,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range unread {
+			rows = append(rows, queries.GetNotificationWithDetailsRow(row))
+		}
+	} else {
+		all, err := s.queries.ListNotificationsByUser(ctx, queries.ListNotificationsByUserParams{
+			UserID: userID,
+			Limit:  limit + 1,
+			Offset: offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range all {
+			rows = append(rows, queries.GetNotificationWithDetailsRow(row))
+		}
+	}
+
+	count, err := s.queries.CountUnreadNotifications(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &NotificationPage{
+		Entries: make([]NotificationEntry, 0, len(rows)),
+		Unread:  count,
+	}
+	for _, row := range rows {
+		page.Entries = append(page.Entries, newNotificationEntry(row))
+	}
+	if int64(len(page.Entries)) > limit {
+		page.Entries = page.Entries[:limit]
+		page.HasMore = true
+	}
+
+	return page, nil
+}
+
+// CountUnread returns how many unread notifications a user has
+func (s *NotificationService) CountUnread(ctx context.Context, userID int64) (int64, error) {
+	return s.queries.CountUnreadNotifications(ctx, userID)
+}
+
+// MarkRead marks one of a user's notifications as read. Marking a read
+// notification keeps its original read time.
+func (s *NotificationService) MarkRead(ctx context.Context, userID, notificationID int64) (*NotificationEntry, error) {
+	if err := s.queries.MarkNotificationRead(ctx, queries.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	}); err != nil {
+		return nil, err
+	}
+
+	row, err := s.queries.GetNotificationWithDetails(ctx, queries.GetNotificationWithDetailsParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotificationNotFound
+		}
+		return nil, err
+	}
+
+	entry := newNotificationEntry(row)
+	return &entry, nil
+}
+
+// MarkAllRead marks all of a user's notifications as read and returns how
+// many were unread
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID int64) (int64, error) {
+	return s.queries.MarkAllNotificationsRead(ctx, userID)
+}
+
+// IsWatching reports whether a user watches a task
+func (s *NotificationService) IsWatching(ctx context.Context, userID, taskID int64) (bool, error) {
+	watching, err := s.queries.IsWatchingTask(ctx, queries.IsWatchingTaskParams{
+		TaskID: taskID,
+		UserID: userID,
+	})
+	if err != nil {
+		return false, err
+	}
+	return watching == 1, nil
+}
+
+// WatchTask subscribes the current user to a task's comments and due date
+// reminders
+func (s *NotificationService) WatchTask(ctx context.Context, access *ProjectAccess, taskID int64) error {
+	return s.queries.WatchTask(ctx, queries.WatchTaskParams{
+		TaskID: taskID,
+		UserID: access.UserID,
+	})
+}
+
+// UnwatchTask stops the current user's notifications about a task other
+// than mentions and assignments
+func (s *NotificationService) UnwatchTask(ctx context.Context, access *ProjectAccess, taskID int64) error {
+	return s.queries.UnwatchTask(ctx, queries.UnwatchTaskParams{
+		TaskID: taskID,
+		UserID: access.UserID,
+	})
+}
+
+// Jobs returns the background jobs that send due date reminders,
+// notification emails and digests
+func (s *NotificationService) Jobs() []jobs.Job {
+	return []jobs.Job{
+		{Name: "due-reminders", Interval: dueReminderInterval, Run: s.SendDueReminders},
+		{Name: "notification-emails", Interval: emailInterval, Run: s.SendEmails},
+		{Name: "notification-digests", Interval: emailInterval, Run: s.SendDigests},
+	}
+}
+
+// SendDueReminders notifies the watchers of open tasks due today or
+// tomorrow, once per task and watcher
+func (s *NotificationService) SendDueReminders(ctx context.Context) error {
+	var out outbox
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		due, err := qtx.ListDueSoonTaskWatchers(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range due {
+			if err := out.notify(ctx, qtx, notification{
+				UserID:    w.UserID,
+				ProjectID: w.ProjectID,
+				TaskID:    w.TaskID,
+				Type:      NotificationDueSoon,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	out.push(ctx, s.queries, s.broker)
+	return nil
+}
+
+// notification describes a notification to send to a user. Zero IDs are
+// stored as NULL.
+type notification struct {
+	UserID    int64
+	ActorID   int64
+	ProjectID int64
+	TaskID    int64
+	CommentID int64
+	Type      string
+}
+
+// outbox collects the notifications stored in a transaction so they can be
+// pushed to their recipients once it commits
+type outbox []queries.Notification
+
+// notify stores a notification for a user
+func (o *outbox) notify(ctx context.Context, q *queries.Queries, n notification) error {
+	created, err := q.CreateNotification(ctx, queries.CreateNotificationParams{
+		UserID:    n.UserID,
+		ActorID:   nullID(n.ActorID),
+		ProjectID: nullID(n.ProjectID),
+		TaskID:    nullID(n.TaskID),
+		CommentID: nullID(n.CommentID),
+		Type:      n.Type,
+	})
+	if err != nil {
+		return err
+	}
+
+	*o = append(*o, created)
+	return nil
+}
+
+// push sends the collected notifications to their recipients' open
+// connections. The notifications are already stored, so a failure here is
+// only logged; clients see them when they next load their notifications.
+func (o outbox) push(ctx context.Context, q *queries.Queries, broker *events.Broker) {
+	for _, n := range o {
+		row, err := q.GetNotificationWithDetails(ctx, queries.GetNotificationWithDetailsParams{
+			ID:     n.ID,
+			UserID: n.UserID,
+		})
+		if err != nil {
+			log.Printf("Error loading notification %d: %v", n.ID, err)
+			continue
+		}
+
+		entry := newNotificationEntry(row)
+		broker.PublishToUser(n.UserID, events.NotificationCreated, n.ActorID.Int64, &entry)
+	}
+}
+
+// notifyWatchers notifies the watchers of a task, except the user acting
+// and those in skip
+func (o *outbox) notifyWatchers(ctx context.Context, q *queries.Queries, access *ProjectAccess, taskID, commentID int64, notificationType string, skip map[int64]bool) error {
+	watchers, err := q.ListTaskWatcherIDs(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range watchers {
+		if userID == access.UserID || skip[userID] {
+			continue
+		}
+		if err := o.notify(ctx, q, notification{
+			UserID:    userID,
+			ActorID:   access.UserID,
+			ProjectID: access.Project.ID,
+			TaskID:    taskID,
+			CommentID: commentID,
+			Type:      notificationType,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch subscribes a user to a task. Watching a task twice is a no-op.
+func watch(ctx context.Context, q *queries.Queries, taskID, userID int64) error {
+	return q.WatchTask(ctx, queries.WatchTaskParams{
+		TaskID: taskID,
+		UserID: userID,
+	})
+}
+
+// nullID converts an optional ID, where zero means none, to a nullable
+// value
+func nullID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidResetToken is returned when a password reset token does not
+// exist, has expired or has already been used
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+const (
+	// passwordResetTTL is how long a password reset link stays valid
+	passwordResetTTL = time.Hour
+	// passwordResetInterval is how long to wait between password reset
+	// emails to a user
+	passwordResetInterval = time.Minute
+	// maxPasswordResetsPerHour is the most password reset emails sent to a
+	// user in an hour
+	maxPasswordResetsPerHour = 5
+)
+
+// RequestPasswordReset emails a password reset link to the user with the
+// given email, at most once a minute and five times an hour. Nothing is
+// sent for unknown emails or when throttled, but the result is the same so
+// callers cannot tell whether an email is registered.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	if !s.passwordLogin {
+		return ErrPasswordLoginDisabled
+	}
+
+	user, err := s.queries.GetUserByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+
+	// Throttle per user so the form cannot be used to flood an inbox
+	now := time.Now().UTC()
+	recent, err := s.queries.CountPasswordResetTokensSince(ctx, queries.CountPasswordResetTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: sql.NullTime{Time: now.Add(-passwordResetInterval), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	hourly, err := s.queries.CountPasswordResetTokensSince(ctx, queries.CountPasswordResetTokensSinceParams{
+		UserID:    user.ID,
+		CreatedAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if recent > 0 || hourly >= maxPasswordResetsPerHour {
+		return nil
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.queries.CreatePasswordResetToken(ctx, queries.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}); err != nil {
+		return err
+	}
+
+	// The email is sent in the background so the response time does not
+	// reveal whether the email is registered
+	return s.sendEmail(&user, "Reset your Vugo password", "emails/password_reset.html", map[string]interface{}{
+		"URL":      s.appURL + "/reset-password?token=" + url.QueryEscape(token),
+		"ValidFor": fmt.Sprintf("%d minutes", int(passwordResetTTL.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password using a reset token. The token can
+// only be used once, and all of the user's sessions and other reset tokens
+// are revoked.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string) error {
+	if !s.passwordLogin {
+		return ErrPasswordLoginDisabled
+	}
+
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		reset, err := qtx.GetPasswordResetTokenByHash(ctx, hashToken(token))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInvalidResetToken
+			}
+			return err
+		}
+		if reset.UsedAt.Valid || time.Now().After(reset.ExpiresAt) {
+			return ErrInvalidResetToken
+		}
+
+		// Claiming the token fails if a concurrent reset used it first
+		used, err := qtx.UsePasswordResetToken(ctx, reset.ID)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return ErrInvalidResetToken
+		}
+
+		if err := qtx.UpdateUserPassword(ctx, queries.UpdateUserPasswordParams{
+			PasswordHash: string(hashedPassword),
+			ID:           reset.UserID,
+		}); err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteUserSessions(ctx, reset.UserID); err != nil {
+			return err
+		}
+		return qtx.DeleteUserPasswordResetTokens(ctx, reset.UserID)
+	})
+}
+
+// CleanupExpiredResetTokens deletes expired password reset tokens
+func (s *AuthService) CleanupExpiredResetTokens(ctx context.Context) error {
+	return s.queries.DeleteExpiredPasswordResetTokens(ctx)
+}
+
+// generateToken returns a random URL-safe token
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash a token is stored as
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+// ErrProjectNotFound is returned when a project does not exist or the
+// user has no access to it
+var ErrProjectNotFound = errors.New("project not found")
+
+// DefaultProjectColor is used when a project is created without a color
+const DefaultProjectColor = "#6366f1"
+
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// ProjectService handles project business logic. When requireVerifiedEmail
+// is set, only users with a verified email can be added to projects.
+type ProjectService struct {
+	db                   *sql.DB
+	queries              *queries.Queries
+	requireVerifiedEmail bool
+}
+
+// NewProjectService creates a new project service
+func NewProjectService(db *sql.DB, q *queries.Queries, requireVerifiedEmail bool) *ProjectService {
+	return &ProjectService{
+		db:                   db,
+		queries:              q,
+		requireVerifiedEmail: requireVerifiedEmail,
+	}
+}
+
+// ProjectInput contains the editable fields of a project
+type ProjectInput struct {
+	Name        string
+	Description string
+	Color       string
+}
+
+// validate trims and checks the project input
+func (in *ProjectInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	in.Description = strings.TrimSpace(in.Description)
+	in.Color = strings.TrimSpace(in.Color)
+
+	if len(in.Name) < 2 || len(in.Name) > 100 {
+		return newValidationError("name must be between 2 and 100 characters")
+	}
+
+	if len(in.Description) > 500 {
+		return newValidationError("description must be at most 500 characters")
+	}
+
+	if in.Color == "" {
+		in.Color = DefaultProjectColor
+	}
+	if !hexColorPattern.MatchString(in.Color) {
+		return newValidationError("color must be a valid hex color code")
+	}
+
+	return nil
+}
+
+// ProjectDetail is a project together with its owner information
+type ProjectDetail struct {
+	Project    queries.Project
+	OwnerName  string
+	OwnerEmail string
+}
+
+// ListProjects returns the projects a user owns or is a member of.
+// When archived is true only archived projects are returned.
+func (s *ProjectService) ListProjects(ctx context.Context, userID int64, archived bool) ([]queries.Project, error) {
+	listOwned, listMember := s.queries.ListProjectsByOwner, s.queries.ListProjectsByMember
+	if archived {
+		listOwned, listMember = s.queries.ListArchivedProjectsByOwner, s.queries.ListArchivedProjectsByMember
+	}
+
+	owned, err := listOwned(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := listMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Owners are usually members as well, so merge without duplicates
+	seen := make(map[int64]bool, len(owned))
+	projects := make([]queries.Project, 0, len(owned)+len(member))
+	for _, p := range owned {
+		seen[p.ID] = true
+		projects = append(projects, p)
+	}
+	for _, p := range member {
+		if !seen[p.ID] {
+			seen[p.ID] = true
+			projects = append(projects, p)
+		}
+	}
+
+	return projects, nil
+}
+
+// CreateProject creates a project and registers the creator as its owner
+func (s *ProjectService) CreateProject(ctx context.Context, userID int64, input ProjectInput) (*queries.Project, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := s.queries.WithTx(tx)
+
+	project, err := qtx.CreateProject(ctx, queries.CreateProjectParams{
+		OwnerID:     userID,
+		Name:        input.Name,
+		Description: nullString(input.Description),
+		Color:       sql.NullString{String: input.Color, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = qtx.AddProjectMember(ctx, queries.AddProjectMemberParams{
+		ProjectID: project.ID,
+		UserID:    userID,
+		Role:      "owner",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &project, nil
+}
+
+// GetProject returns a project with its owner
+func (s *ProjectService) GetProject(ctx context.Context, projectID int64) (*ProjectDetail, error) {
+	row, err := s.queries.GetProjectWithOwner(ctx, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	return &ProjectDetail{
+		Project: queries.Project{
+			ID:          row.ID,
+			OwnerID:     row.OwnerID,
+			Name:        row.Name,
+			Description: row.Description,
+			Color:       row.Color,
+			Archived:    row.Archived,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+		},
+		OwnerName:  row.OwnerName,
+		OwnerEmail: row.OwnerEmail,
+	}, nil
+}
+
+// UpdateProject updates a project's name, description and color
+func (s *ProjectService) UpdateProject(ctx context.Context, project *queries.Project, input ProjectInput) (*queries.Project, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.queries.UpdateProject(ctx, queries.UpdateProjectParams{
+		Name:        input.Name,
+		Description: nullString(input.Description),
+		Color:       sql.NullString{String: input.Color, Valid: true},
+		ID:          project.ID,
+		OwnerID:     project.OwnerID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// ArchiveProject hides a project from the active project list
+func (s *ProjectService) ArchiveProject(ctx context.Context, project *queries.Project) error {
+	return s.queries.ArchiveProject(ctx, queries.ArchiveProjectParams{
+		ID:      project.ID,
+		OwnerID: project.OwnerID,
+	})
+}
+
+// UnarchiveProject restores an archived project
+func (s *ProjectService) UnarchiveProject(ctx context.Context, project *queries.Project) error {
+	return s.queries.UnarchiveProject(ctx, queries.UnarchiveProjectParams{
+		ID:      project.ID,
+		OwnerID: project.OwnerID,
+	})
+}
+
+// DeleteProject permanently deletes a project and everything in it
+func (s *ProjectService) DeleteProject(ctx context.Context, project *queries.Project) error {
+	return s.queries.DeleteProject(ctx, queries.DeleteProjectParams{
+		ID:      project.ID,
+		OwnerID: project.OwnerID,
+	})
+}
+
+// nullString converts an empty string to a NULL value
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
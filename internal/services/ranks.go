@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+// Boards, columns, tasks and checklist items are ordered by a LexoRank-style
+// string rank instead of an integer position. A rank is a base-36 fraction
+// written with rankDigits (so ranks compare bytewise) that never ends in
+// '0', which guarantees another rank always fits between any two. Moving an
+// item therefore only rewrites that item's row.
+
+// rankDigits are the digits of a rank in ascending byte order
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// maxRankLength is the length beyond which a list is rebalanced in the
+// background so ranks stay short
+const maxRankLength = 16
+
+// errRankCollision is returned when two neighbouring ranks leave no room
+// between them, e.g. because they are equal
+var errRankCollision = errors.New("no rank between neighbours")
+
+// validRank reports whether s is a well formed rank
+func validRank(s string) bool {
+	if s == "" || s[len(s)-1] == '0' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(rankDigits, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// rankBetween returns a rank that sorts strictly between a and b. An empty
+// a means the start of the list and an empty b its end.
+func rankBetween(a, b string) (string, error) {
+	if (a != "" && !validRank(a)) || (b != "" && !validRank(b)) {
+		return "", errRankCollision
+	}
+	if a != "" && b != "" && a >= b {
+		return "", errRankCollision
+	}
+
+	switch {
+	case b == "":
+		return rankAfter(a), nil
+	case a == "":
+		return rankBefore(b), nil
+	}
+	return rankMidpoint(a, b), nil
+}
+
+// rankAfter returns a short rank greater than a by incrementing its first
+// digit that is not already the largest. Appending to a list this way only
+// grows ranks by one digit every 35 items.
+func rankAfter(a string) string {
+	if a == "" {
+		return rankMidpoint("", "")
+	}
+	for i := 0; i < len(a); i++ {
+		d := strings.IndexByte(rankDigits, a[i])
+		if d < len(rankDigits)-1 {
+			return a[:i] + string(rankDigits[d+1])
+		}
+	}
+	return a + string(rankDigits[1])
+}
+
+// rankBefore returns a short rank less than b by decrementing its first
+// digit that can be decremented without ending in '0'. It is the
+// counterpart of rankAfter for prepending.
+func rankBefore(b string) string {
+	for i := 0; i < len(b); i++ {
+		d := strings.IndexByte(rankDigits, b[i])
+		if d > 1 {
+			return b[:i] + string(rankDigits[d-1])
+		}
+	}
+	return rankMidpoint("", b)
+}
+
+// rankMidpoint returns the rank halfway between a and b, where a < b, an
+// empty a means zero and an empty b means one
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// Keep the common prefix, treating missing digits of a as '0'
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + rankMidpoint(rest, b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(rankDigits, a[0])
+	}
+	digitB := len(rankDigits)
+	if b != "" {
+		digitB = strings.IndexByte(rankDigits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(rankDigits[(digitA+digitB+1)/2])
+	}
+
+	// The first digits are adjacent: either b's first digit alone sorts
+	// between them, or keep a's first digit and recurse on the rest of a
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(rankDigits[digitA]) + rankMidpoint(rest, "")
+}
+
+// rankDigitAt returns the digit of s at i, or '0' past its end
+func rankDigitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return rankDigits[0]
+}
+
+// rankAt returns the rank that places an item at index within ranks, the
+// ordered ranks of its siblings. The index is clamped to the list.
+func rankAt(ranks []string, index int) (string, error) {
+	if index > len(ranks) {
+		index = len(ranks)
+	}
+
+	before, after := "", ""
+	if index > 0 {
+		before = ranks[index-1]
+	}
+	if index < len(ranks) {
+		after = ranks[index]
+	}
+	return rankBetween(before, after)
+}
+
+// spreadRanks returns n evenly spaced ranks of the smallest width that
+// leaves plenty of room between neighbours
+func spreadRanks(n int) []string {
+	base := int64(len(rankDigits))
+
+	width, space := 1, base
+	for space < int64(n+1)*base && width < 10 {
+		width++
+		space *= base
+	}
+	step := space / int64(n+1)
+
+	ranks := make([]string, n)
+	buf := make([]byte, width)
+	for i := range ranks {
+		v := step * int64(i+1)
+		for j := width - 1; j >= 0; j-- {
+			buf[j] = rankDigits[v%base]
+			v /= base
+		}
+		ranks[i] = strings.TrimRight(string(buf), rankDigits[:1])
+	}
+	return ranks
+}
+
+// rankList identifies a kind of ranked list
+type rankList int
+
+const (
+	// boardRanks orders the boards of a project
+	boardRanks rankList = iota
+	// columnRanks orders the columns of a board
+	columnRanks
+	// taskRanks orders the tasks of a column
+	taskRanks
+	// checklistRanks orders the checklist items of a task
+	checklistRanks
+)
+
+// rankScope is a single ranked list, e.g. the tasks of one column
+type rankScope struct {
+	list     rankList
+	parentID int64
+}
+
+// rankedItem is an item of a ranked list
+type rankedItem struct {
+	id   int64
+	rank string
+}
+
+// loadRanks returns the items of a ranked list in order
+func loadRanks(ctx context.Context, q *queries.Queries, scope rankScope) ([]rankedItem, error) {
+	var items []rankedItem
+
+	switch scope.list {
+	case boardRanks:
+		boards, err := q.ListBoardsByProject(ctx, scope.parentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range boards {
+			items = append(items, rankedItem{id: b.ID, rank: b.Rank})
+		}
+	case columnRanks:
+		columns, err := q.ListColumnsByBoard(ctx, scope.parentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range columns {
+			items = append(items, rankedItem{id: c.ID, rank: c.Rank})
+		}
+	case taskRanks:
+		// Completed tasks keep their rank so they return to the same place
+		// when reopened, so they are rebalanced too
+		tasks, err := q.ListTaskRanksByColumn(ctx, scope.parentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			items = append(items, rankedItem{id: t.ID, rank: t.Rank})
+		}
+	case checklistRanks:
+		checklist, err := q.ListChecklistItemsByTask(ctx, scope.parentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range checklist {
+			items = append(items, rankedItem{id: c.ID, rank: c.Rank})
+		}
+	}
+
+	return items, nil
+}
+
+// updateRank stores the rank of a single item of a list
+func updateRank(ctx context.Context, q *queries.Queries, list rankList, id int64, rank string) error {
+	switch list {
+	case boardRanks:
+		return q.UpdateBoardRank(ctx, queries.UpdateBoardRankParams{Rank: rank, ID: id})
+	case columnRanks:
+		return q.UpdateColumnRank(ctx, queries.UpdateColumnRankParams{Rank: rank, ID: id})
+	case taskRanks:
+		return q.UpdateTaskRank(ctx, queries.UpdateTaskRankParams{Rank: rank, ID: id})
+	case checklistRanks:
+		return q.UpdateChecklistItemRank(ctx, queries.UpdateChecklistItemRankParams{Rank: rank, ID: id})
+	}
+	return nil
+}
+
+// rebalanceRanks respaces every rank of a list evenly, keeping its order.
+// Only items whose rank changes are written.
+func rebalanceRanks(ctx context.Context, q *queries.Queries, scope rankScope) error {
+	items, err := loadRanks(ctx, q, scope)
+	if err != nil {
+		return err
+	}
+
+	for i, rank := range spreadRanks(len(items)) {
+		if items[i].rank == rank {
+			continue
+		}
+		if err := updateRank(ctx, q, scope.list, items[i].id, rank); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeRank returns the rank that places an item at index among its
+// siblings, whose ordered ranks are returned by siblings. If neighbouring
+// ranks collide the list is rebalanced first, within the same transaction.
+func placeRank(ctx context.Context, q *queries.Queries, scope rankScope, index int, siblings func() ([]string, error)) (string, error) {
+	ranks, err := siblings()
+	if err != nil {
+		return "", err
+	}
+
+	rank, err := rankAt(ranks, index)
+	if !errors.Is(err, errRankCollision) {
+		return rank, err
+	}
+
+	if err := rebalanceRanks(ctx, q, scope); err != nil {
+		return "", err
+	}
+	ranks, err = siblings()
+	if err != nil {
+		return "", err
+	}
+	return rankAt(ranks, index)
+}
+
+// RankRebalancer respaces ranked lists in the background once their ranks
+// grow too long
+type RankRebalancer struct {
+	db      *sql.DB
+	queries *queries.Queries
+
+	mu      sync.Mutex
+	pending map[rankScope]bool
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewRankRebalancer creates a new rank rebalancer
+func NewRankRebalancer(db *sql.DB, q *queries.Queries) *RankRebalancer {
+	return &RankRebalancer{
+		db:      db,
+		queries: q,
+		pending: make(map[rankScope]bool),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Start runs the rebalancer until ctx is cancelled. Lists queued before
+// Start are rebalanced once it runs.
+func (r *RankRebalancer) Start(ctx context.Context) {
+	r.wg.Add(1)
+	go r.run(ctx)
+}
+
+// Wait blocks until the rebalancer has stopped and any running rebalance
+// has returned
+func (r *RankRebalancer) Wait() {
+	r.wg.Wait()
+}
+
+// checkRank queues the list for rebalancing if rank has grown too long
+func (r *RankRebalancer) checkRank(scope rankScope, rank string) {
+	if len(rank) <= maxRankLength {
+		return
+	}
+
+	r.mu.Lock()
+	r.pending[scope] = true
+	r.mu.Unlock()
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run rebalances queued lists as they come in
+func (r *RankRebalancer) run(ctx context.Context) {
+	defer r.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		}
+
+		r.mu.Lock()
+		scopes := make([]rankScope, 0, len(r.pending))
+		for scope := range r.pending {
+			scopes = append(scopes, scope)
+		}
+		r.pending = make(map[rankScope]bool)
+		r.mu.Unlock()
+
+		for _, scope := range scopes {
+			if ctx.Err() != nil {
+				return
+			}
+			if err := r.rebalance(ctx, scope); err != nil {
+				log.Printf("Error rebalancing ranks of list %d/%d: %v", scope.list, scope.parentID, err)
+			}
+		}
+	}
+}
+
+// rebalance respaces a single list in its own transaction
+func (r *RankRebalancer) rebalance(ctx context.Context, scope rankScope) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := rebalanceRanks(ctx, r.queries.WithTx(tx), scope); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+func TestRankBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"empty list", "", ""},
+		{"start of list", "", "i"},
+		{"end of list", "i", ""},
+		{"wide gap", "1", "z"},
+		{"adjacent digits", "a", "b"},
+		{"prefix of b", "a", "a1"},
+		{"longer a", "azz", "b"},
+		{"shared prefix", "abc1", "abc2"},
+		{"before smallest", "", "1"},
+		{"before long smallest", "", "0001"},
+		{"after largest", "zzz", ""},
+		{"before largest", "zzy", "zzz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank, err := rankBetween(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("rankBetween(%q, %q): %v", tt.a, tt.b, err)
+			}
+			if !validRank(rank) {
+				t.Errorf("rankBetween(%q, %q) = %q, not a valid rank", tt.a, tt.b, rank)
+			}
+			if tt.a != "" && rank <= tt.a {
+				t.Errorf("rankBetween(%q, %q) = %q, not after %q", tt.a, tt.b, rank, tt.a)
+			}
+			if tt.b != "" && rank >= tt.b {
+				t.Errorf("rankBetween(%q, %q) = %q, not before %q", tt.a, tt.b, rank, tt.b)
+			}
+		})
+	}
+}
+
+func TestRankBetweenCollision(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+	}{
+		{"equal", "i", "i"},
+		{"reversed", "j", "i"},
+		{"trailing zero", "i0", ""},
+		{"invalid digit", "", "I"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := rankBetween(tt.a, tt.b); !errors.Is(err, errRankCollision) {
+				t.Errorf("rankBetween(%q, %q) error = %v, want errRankCollision", tt.a, tt.b, err)
+			}
+		})
+	}
+}
+
+func TestRankBetweenRepeatedInsertion(t *testing.T) {
+	tests := []struct {
+		name string
+		// towardsStart inserts each item right after a instead of right
+		// before b
+		towardsStart bool
+	}{
+		{"towards start", true},
+		{"towards end", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := "i", "j"
+			for i := 0; i < 200; i++ {
+				mid, err := rankBetween(a, b)
+				if err != nil {
+					t.Fatalf("insert %d: rankBetween(%q, %q): %v", i, a, b, err)
+				}
+				if !validRank(mid) || mid <= a || mid >= b {
+					t.Fatalf("insert %d: rankBetween(%q, %q) = %q", i, a, b, mid)
+				}
+				// Long ranks are what queues a list for rebalancing
+				if len(mid) > maxRankLength {
+					return
+				}
+
+				if tt.towardsStart {
+					b = mid
+				} else {
+					a = mid
+				}
+			}
+			t.Fatalf("ranks never grew past %d digits", maxRankLength)
+		})
+	}
+}
+
+func TestSpreadRanks(t *testing.T) {
+	tests := []struct {
+		n        int
+		maxWidth int
+	}{
+		{0, 0},
+		{1, 2},
+		{35, 2},
+		{36, 3},
+		{1000, 3},
+		{50000, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.n), func(t *testing.T) {
+			ranks := spreadRanks(tt.n)
+			if len(ranks) != tt.n {
+				t.Fatalf("got %d ranks, want %d", len(ranks), tt.n)
+			}
+			for i, rank := range ranks {
+				if !validRank(rank) {
+					t.Fatalf("rank %d = %q, not a valid rank", i, rank)
+				}
+				if len(rank) > tt.maxWidth {
+					t.Fatalf("rank %d = %q, longer than %d digits", i, rank, tt.maxWidth)
+				}
+				if i > 0 && rank <= ranks[i-1] {
+					t.Fatalf("rank %d = %q, not after %q", i, rank, ranks[i-1])
+				}
+				// Every gap must leave room for another item
+				if i > 0 {
+					if _, err := rankBetween(ranks[i-1], rank); err != nil {
+						t.Fatalf("no room between %q and %q: %v", ranks[i-1], rank, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// seedBoards creates a project with a board for each rank and returns the
+// project's rank scope
+func seedBoards(t *testing.T, q *queries.Queries, ranks []string) rankScope {
+	t.Helper()
+	ctx := context.Background()
+
+	user, err := q.CreateUser(ctx, queries.CreateUserParams{Email: "owner@example.com", Name: "Owner"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	project, err := q.CreateProject(ctx, queries.CreateProjectParams{OwnerID: user.ID, Name: "Project"})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	for i, rank := range ranks {
+		if _, err := q.CreateBoard(ctx, queries.CreateBoardParams{
+			ProjectID: project.ID,
+			Name:      fmt.Sprintf("Board %d", i),
+			Rank:      rank,
+		}); err != nil {
+			t.Fatalf("create board: %v", err)
+		}
+	}
+	return rankScope{list: boardRanks, parentID: project.ID}
+}
+
+// boardNames returns the names and ranks of a project's boards in order
+func boardNames(t *testing.T, q *queries.Queries, projectID int64) ([]string, []string) {
+	t.Helper()
+
+	boards, err := q.ListBoardsByProject(context.Background(), projectID)
+	if err != nil {
+		t.Fatalf("list boards: %v", err)
+	}
+	names := make([]string, len(boards))
+	ranks := make([]string, len(boards))
+	for i, b := range boards {
+		names[i], ranks[i] = b.Name, b.Rank
+	}
+	return names, ranks
+}
+
+// midpointRanks returns ranks made by inserting each item right after the
+// first until one grows too long, as repeated moves to one spot would
+func midpointRanks(t *testing.T) []string {
+	t.Helper()
+
+	ranks := []string{"i", "j"}
+	for len(ranks[1]) <= maxRankLength {
+		mid, err := rankBetween(ranks[0], ranks[1])
+		if err != nil {
+			t.Fatalf("rankBetween(%q, %q): %v", ranks[0], ranks[1], err)
+		}
+		ranks = append([]string{ranks[0], mid}, ranks[1:]...)
+	}
+	return ranks
+}
+
+func TestRebalanceRanks(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+
+	scope := seedBoards(t, q, midpointRanks(t))
+	before, _ := boardNames(t, q, scope.parentID)
+
+	if err := rebalanceRanks(ctx, q, scope); err != nil {
+		t.Fatalf("rebalanceRanks: %v", err)
+	}
+
+	after, ranks := boardNames(t, q, scope.parentID)
+	if fmt.Sprint(after) != fmt.Sprint(before) {
+		t.Errorf("order changed:\nbefore %v\nafter  %v", before, after)
+	}
+	if want := spreadRanks(len(ranks)); fmt.Sprint(ranks) != fmt.Sprint(want) {
+		t.Errorf("ranks after rebalancing = %v, want %v", ranks, want)
+	}
+}
+
+func TestPlaceRankRebalancesCollisions(t *testing.T) {
+	ctx := context.Background()
+	_, q := newTestDB(t)
+
+	// Equal ranks leave no room between them
+	scope := seedBoards(t, q, []string{"i", "i", "i"})
+	siblings := func() ([]string, error) {
+		_, ranks := boardNames(t, q, scope.parentID)
+		return ranks, nil
+	}
+
+	rank, err := placeRank(ctx, q, scope, 1, siblings)
+	if err != nil {
+		t.Fatalf("placeRank: %v", err)
+	}
+	_, ranks := boardNames(t, q, scope.parentID)
+	if !(ranks[0] < rank && rank < ranks[1]) {
+		t.Errorf("placeRank = %q, not between %q and %q", rank, ranks[0], ranks[1])
+	}
+}
+
+func TestRankRebalancer(t *testing.T) {
+	db, q := newTestDB(t)
+
+	scope := seedBoards(t, q, midpointRanks(t))
+	_, ranks := boardNames(t, q, scope.parentID)
+	want := fmt.Sprint(spreadRanks(len(ranks)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRankRebalancer(db, q)
+	r.checkRank(scope, ranks[1])
+	r.Start(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, ranks = boardNames(t, q, scope.parentID)
+		if fmt.Sprint(ranks) == want {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("list was not rebalanced, rank %q", ranks[1])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	stopped := make(chan struct{})
+	go func() {
+		r.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rebalancer did not stop after its context was cancelled")
+	}
+}
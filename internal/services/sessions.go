@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+)
+
+// ErrSessionNotFound is returned when a session does not exist, has expired
+// or belongs to another user
+var ErrSessionNotFound = errors.New("session not found")
+
+const (
+	// sessionTouchInterval is how often a session's last seen time is
+	// updated, so every request does not write to the database. Short idle
+	// timeouts update it more often.
+	sessionTouchInterval = time.Minute
+	// maxUserAgentLength is how much of a User-Agent header is kept
+	maxUserAgentLength = 512
+)
+
+// SessionTimeouts controls how long sessions last. A session ends once it
+// has not been used for Idle, and at the latest Absolute after sign in.
+type SessionTimeouts struct {
+	Idle     time.Duration
+	Absolute time.Duration
+}
+
+// DefaultSessionTimeouts are used unless configured otherwise
+var DefaultSessionTimeouts = SessionTimeouts{
+	Idle:     7 * 24 * time.Hour,  // 7 days
+	Absolute: 30 * 24 * time.Hour, // 30 days
+}
+
+// SessionClient describes the browser or device a session is created from,
+// so users can tell their sessions apart
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// GetUserBySession validates a session token and returns the user and
+// session. Sessions that are used are kept alive until their absolute
+// expiry.
+func (s *AuthService) GetUserBySession(ctx context.Context, token string) (*queries.User, *queries.Session, error) {
+	// Get session
+	session, err := s.queries.GetSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrSessionNotFound
+		}
+		return nil, nil, err
+	}
+
+	// Check if session is expired
+	now := time.Now()
+	if now.After(s.SessionExpiresAt(&session)) {
+		// Delete expired session
+		_ = s.queries.DeleteSession(ctx, session.ID)
+		return nil, nil, ErrSessionNotFound
+	}
+
+	// Slide the idle expiry forward
+	touchAfter := sessionTouchInterval
+	if s.timeouts.Idle/2 < touchAfter {
+		touchAfter = s.timeouts.Idle / 2
+	}
+	if now.Sub(session.LastSeenAt) > touchAfter {
+		session.LastSeenAt = now.UTC()
+		if err := s.queries.TouchSession(ctx, queries.TouchSessionParams{
+			LastSeenAt: session.LastSeenAt,
+			ID:         session.ID,
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Get user
+	user, err := s.queries.GetUser(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &user, &session, nil
+}
+
+// SessionExpiresAt returns when a session ends unless it is used again
+func (s *AuthService) SessionExpiresAt(session *queries.Session) time.Time {
+	idle := session.LastSeenAt.Add(s.timeouts.Idle)
+	if idle.Before(session.ExpiresAt) {
+		return idle
+	}
+	return session.ExpiresAt
+}
+
+// Logout deletes the session with a token
+func (s *AuthService) Logout(ctx context.Context, token string) error {
+	session, err := s.queries.GetSessionByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	return s.queries.DeleteSession(ctx, session.ID)
+}
+
+// ListSessions returns a user's sessions that have not expired, most
+// recently used first
+func (s *AuthService) ListSessions(ctx context.Context, userID int64) ([]queries.Session, error) {
+	sessions, err := s.queries.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := make([]queries.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if now.After(s.SessionExpiresAt(&session)) {
+			continue
+		}
+		active = append(active, session)
+	}
+	return active, nil
+}
+
+// RevokeSession signs out one of a user's sessions
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID int64) error {
+	deleted, err := s.queries.DeleteUserSession(ctx, queries.DeleteUserSessionParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions signs a user out everywhere
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID int64) error {
+	return s.queries.DeleteUserSessions(ctx, userID)
+}
+
+// CleanupExpiredSessions deletes sessions past their absolute expiry or
+// idle for too long
+func (s *AuthService) CleanupExpiredSessions(ctx context.Context) error {
+	return s.queries.DeleteExpiredSessions(ctx, time.Now().Add(-s.timeouts.Idle).UTC())
+}
+
+// CSRFToken returns the CSRF token of the session with a token. It is
+// derived from the session token, so it needs no storage and cannot be
+// guessed by a site that cannot read the session cookie.
+func CSRFToken(sessionToken string) string {
+	sum := sha256.Sum256([]byte("csrf:" + sessionToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// createSession creates a new session for a user and returns it with its
+// token. Only a hash of the token is stored.
+func (s *AuthService) createSession(ctx context.Context, userID int64, client SessionClient) (queries.Session, string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return queries.Session{}, "", err
+	}
+
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	now := time.Now().UTC()
+	session, err := s.queries.CreateSession(ctx, queries.CreateSessionParams{
+		TokenHash:  hashToken(token),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IpAddress:  client.IPAddress,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.timeouts.Absolute),
+	})
+	if err != nil {
+		return queries.Session{}, "", err
+	}
+
+	return session, token, nil
+}
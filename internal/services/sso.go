@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/jobs"
+	"github.com/erickhilda/vugo/internal/oidc"
+)
+
+var (
+	// ErrSSONotConfigured is returned when single sign-on is used but no
+	// identity provider is configured
+	ErrSSONotConfigured = errors.New("single sign-on is not configured")
+	// ErrInvalidSSOState is returned when a user comes back from the
+	// identity provider with a state that was not issued, has expired or
+	// has been used
+	ErrInvalidSSOState = errors.New("invalid or expired single sign-on state")
+	// ErrSSOFailed is returned when the identity provider does not
+	// complete the login, or returns a token that fails validation
+	ErrSSOFailed = errors.New("single sign-on failed")
+	// ErrSSOEmailNotVerified is returned when the identity provider has not
+	// verified the email of an account that is not linked yet
+	ErrSSOEmailNotVerified = errors.New("identity provider has not verified the email address")
+	// ErrSSOAccountNotLinked is returned when signing in through the
+	// provider with the email of an account that has a password but no
+	// verified email, as anyone could have registered it, or that has
+	// two-factor authentication, which the provider cannot check. Its owner
+	// has to sign in and link the provider account themselves.
+	ErrSSOAccountNotLinked = errors.New("an account with this email already exists, sign in to link it")
+	// ErrSSOIdentityInUse is returned when linking a provider account that
+	// is already linked to another user
+	ErrSSOIdentityInUse = errors.New("provider account is linked to another user")
+)
+
+// ssoStateTTL is how long a user has to sign in at the identity provider
+const ssoStateTTL = 10 * time.Minute
+
+// SSOService signs users in through an OpenID Connect identity provider.
+// Users are found by their linked provider account, or else linked or
+// created by verified email. Signed in users can also link a provider
+// account themselves. Users with two-factor authentication enter a code
+// after the provider like after their password.
+type SSOService struct {
+	db       *sql.DB
+	queries  *queries.Queries
+	auth     *AuthService
+	provider *oidc.Provider
+}
+
+// NewSSOService creates a new SSO service. provider is nil when single
+// sign-on is not configured.
+func NewSSOService(db *sql.DB, q *queries.Queries, auth *AuthService, provider *oidc.Provider) *SSOService {
+	return &SSOService{
+		db:       db,
+		queries:  q,
+		auth:     auth,
+		provider: provider,
+	}
+}
+
+// Enabled reports whether an identity provider is configured
+func (s *SSOService) Enabled() bool {
+	return s.provider != nil
+}
+
+// SSOLogin is a login started at the identity provider. The user is sent
+// to URL, and State must be kept in the browser to check they come back
+// to the same one.
+type SSOLogin struct {
+	URL   string
+	State string
+}
+
+// BeginLogin starts a login at the identity provider
+func (s *SSOService) BeginLogin(ctx context.Context) (*SSOLogin, error) {
+	return s.begin(ctx, sql.NullInt64{})
+}
+
+// BeginLink starts linking a provider account to a signed in user. The
+// user signs in at the identity provider and comes back through
+// CompleteLogin like for a login.
+func (s *SSOService) BeginLink(ctx context.Context, userID int64) (*SSOLogin, error) {
+	return s.begin(ctx, sql.NullInt64{Int64: userID, Valid: true})
+}
+
+// begin starts a login at the identity provider, linking the provider
+// account to linkUserID when set
+func (s *SSOService) begin(ctx context.Context, linkUserID sql.NullInt64) (*SSOLogin, error) {
+	if s.provider == nil {
+		return nil, ErrSSONotConfigured
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	url, err := s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+
+	if err := s.queries.CreateSSOLoginState(ctx, queries.CreateSSOLoginStateParams{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(ssoStateTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	return &SSOLogin{
+		URL:   url,
+		State: state,
+	}, nil
+}
+
+// CompleteLogin finishes a login or link when the user comes back from
+// the identity provider with an authorization code, and creates a session,
+// or a login challenge if the user has two-factor authentication enabled
+func (s *SSOService) CompleteLogin(ctx context.Context, state, code string, client SessionClient) (*LoginResult, error) {
+	if s.provider == nil {
+		return nil, ErrSSONotConfigured
+	}
+
+	stateHash := hashToken(state)
+	pending, err := s.queries.GetSSOLoginState(ctx, stateHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidSSOState
+		}
+		return nil, err
+	}
+
+	// Claiming the state fails if the callback was already used
+	deleted, err := s.queries.DeleteSSOLoginState(ctx, stateHash)
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 || time.Now().After(pending.ExpiresAt) {
+		return nil, ErrInvalidSSOState
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSSOFailed, err)
+	}
+
+	return s.finish(ctx, pending.LinkUserID, claims, client)
+}
+
+// finish signs in the user of a provider account, or links it to
+// linkUserID when set, and creates a session or a login challenge
+func (s *SSOService) finish(ctx context.Context, linkUserID sql.NullInt64, claims *oidc.Claims, client SessionClient) (*LoginResult, error) {
+	var user *queries.User
+	var err error
+	if linkUserID.Valid {
+		user, err = s.link(ctx, linkUserID.Int64, claims)
+	} else {
+		user, err = s.signIn(ctx, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The provider does not know the user's second factor. Links are
+	// started from a session that has already passed it.
+	if !linkUserID.Valid {
+		enabled, err := twoFactorEnabled(ctx, s.queries, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			challenge, err := s.auth.createLoginChallenge(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+			return &LoginResult{
+				User:              *user,
+				TwoFactorRequired: true,
+				Challenge:         challenge,
+			}, nil
+		}
+	}
+
+	session, token, err := s.auth.createSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		User:         *user,
+		Session:      session,
+		SessionToken: token,
+	}, nil
+}
+
+// CleanupExpiredStates deletes logins that were never completed
+func (s *SSOService) CleanupExpiredStates(ctx context.Context) error {
+	return s.queries.DeleteExpiredSSOLoginStates(ctx)
+}
+
+// Jobs returns the background job that deletes logins that were never
+// completed
+func (s *SSOService) Jobs() []jobs.Job {
+	return []jobs.Job{
+		{Name: "sso-state-cleanup", Interval: cleanupInterval, Run: s.CleanupExpiredStates},
+	}
+}
+
+// signIn returns the user linked to a provider account. Unlinked accounts
+// are linked to the user with the same email, or a new user is created;
+// both need an email the provider has verified. Users who registered with
+// a password but never verified their email are not linked, as the
+// account may have been registered by someone else, and neither are users
+// with two-factor authentication, as the provider cannot check it.
+func (s *SSOService) signIn(ctx context.Context, claims *oidc.Claims) (*queries.User, error) {
+	var user queries.User
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		identity, err := qtx.GetUserIdentity(ctx, queries.GetUserIdentityParams{
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+		})
+		if err == nil {
+			user, err = qtx.GetUser(ctx, identity.UserID)
+			return err
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		email := normalizeEmail(claims.Email)
+		if !claims.EmailVerified || validateEmail(email) != nil {
+			return ErrSSOEmailNotVerified
+		}
+
+		user, err = qtx.GetUserByEmail(ctx, email)
+		switch {
+		case err == sql.ErrNoRows:
+			// Users created here have no password and can only sign in
+			// through the provider until they set one with a reset
+			user, err = qtx.CreateUser(ctx, queries.CreateUserParams{
+				Email:        email,
+				PasswordHash: "",
+				Name:         ssoUserName(claims.Name, email),
+				AvatarUrl:    sql.NullString{Valid: false},
+			})
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !user.EmailVerified && user.PasswordHash != "":
+			return ErrSSOAccountNotLinked
+		default:
+			enabled, err := twoFactorEnabled(ctx, qtx, user.ID)
+			if err != nil {
+				return err
+			}
+			if enabled {
+				return ErrSSOAccountNotLinked
+			}
+			if err := revokeUserAccess(ctx, qtx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		// The provider has verified the email
+		if !user.EmailVerified {
+			if user, err = qtx.MarkUserEmailVerified(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		_, err = qtx.CreateUserIdentity(ctx, queries.CreateUserIdentityParams{
+			UserID:  user.ID,
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+			Email:   email,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// link links a provider account to a user and returns the user. The email
+// is marked verified if the provider has verified it for the same address.
+func (s *SSOService) link(ctx context.Context, userID int64, claims *oidc.Claims) (*queries.User, error) {
+	var user queries.User
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		identity, err := qtx.GetUserIdentity(ctx, queries.GetUserIdentityParams{
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+		})
+		if err == nil {
+			if identity.UserID != userID {
+				return ErrSSOIdentityInUse
+			}
+			user, err = qtx.GetUser(ctx, userID)
+			return err
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		user, err = qtx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := revokeUserAccess(ctx, qtx, user.ID); err != nil {
+			return err
+		}
+
+		email := normalizeEmail(claims.Email)
+		if claims.EmailVerified && email == user.Email && !user.EmailVerified {
+			if user, err = qtx.MarkUserEmailVerified(ctx, user.ID); err != nil {
+				return err
+			}
+		}
+
+		_, err = qtx.CreateUserIdentity(ctx, queries.CreateUserIdentityParams{
+			UserID:  user.ID,
+			Issuer:  claims.Issuer,
+			Subject: claims.Subject,
+			Email:   email,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// revokeUserAccess signs a user out everywhere and voids their password
+// reset links before a provider account is linked, so nobody who used the
+// account before keeps access through it
+func revokeUserAccess(ctx context.Context, q *queries.Queries, userID int64) error {
+	if err := q.DeleteUserSessions(ctx, userID); err != nil {
+		return err
+	}
+	return q.DeleteUserPasswordResetTokens(ctx, userID)
+}
+
+// ssoUserName returns the name for a user created through single sign-on,
+// falling back to the start of their email when the provider sends no
+// usable name
+func ssoUserName(name, email string) string {
+	name = strings.TrimSpace(name)
+	if len(name) < 2 || len(name) > 100 {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	if len(name) < 2 {
+		name = email
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/oidc"
+)
+
+// newTestSSOService returns an SSO service on a new test database. Its
+// provider is not configured, so only sign in and linking can be tested.
+func newTestSSOService(t *testing.T) (*SSOService, *queries.Queries) {
+	t.Helper()
+
+	auth, q := newTestAuthService(t, lenientLoginPolicy)
+	return NewSSOService(auth.db, q, auth, nil), q
+}
+
+// testClaims returns verified claims for a provider account with an email
+func testClaims(email string) *oidc.Claims {
+	return &oidc.Claims{
+		Issuer:        "https://idp.example.com",
+		Subject:       "idp|" + email,
+		Email:         email,
+		EmailVerified: true,
+		Name:          "Test User",
+	}
+}
+
+// giveTestAccess creates a session and a password reset token for a user
+func giveTestAccess(t *testing.T, q *queries.Queries, userID int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := q.CreateSession(ctx, queries.CreateSessionParams{
+		TokenHash:  hashToken("session"),
+		UserID:     userID,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	if _, err := q.CreatePasswordResetToken(ctx, queries.CreatePasswordResetTokenParams{
+		UserID:    userID,
+		TokenHash: hashToken("reset"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("create password reset token: %v", err)
+	}
+}
+
+// testAccess returns how many sessions and password reset tokens a user has
+func testAccess(t *testing.T, q *queries.Queries, userID int64) (sessions, resetTokens int64) {
+	t.Helper()
+	ctx := context.Background()
+
+	list, err := q.ListUserSessions(ctx, userID)
+	if err != nil {
+		t.Fatalf("list sessions: %v", err)
+	}
+	resetTokens, err = q.CountPasswordResetTokensSince(ctx, queries.CountPasswordResetTokensSinceParams{
+		UserID:    userID,
+		CreatedAt: sql.NullTime{Time: time.Time{}, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("count password reset tokens: %v", err)
+	}
+	return int64(len(list)), resetTokens
+}
+
+func TestSSOSignInLinksExistingAccounts(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified bool
+		password string
+		wantErr  error
+	}{
+		{"verified with password", true, "password123", nil},
+		{"unverified without password", false, "", nil},
+		{"unverified with password", false, "password123", ErrSSOAccountNotLinked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, q := newTestSSOService(t)
+
+			user := createTestUser(t, q, "alice@example.com", "password123")
+			if tt.password == "" {
+				if err := q.UpdateUserPassword(ctx, queries.UpdateUserPasswordParams{ID: user.ID}); err != nil {
+					t.Fatalf("clear password: %v", err)
+				}
+			}
+			if tt.verified {
+				if _, err := q.MarkUserEmailVerified(ctx, user.ID); err != nil {
+					t.Fatalf("verify email: %v", err)
+				}
+			}
+			giveTestAccess(t, q, user.ID)
+
+			signedIn, err := s.signIn(ctx, testClaims("alice@example.com"))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("signIn: %v, want %v", err, tt.wantErr)
+			}
+
+			sessions, resetTokens := testAccess(t, q, user.ID)
+			if tt.wantErr != nil {
+				// The account is left as it was
+				if sessions != 1 || resetTokens != 1 {
+					t.Errorf("got %d sessions and %d reset tokens, want 1 and 1", sessions, resetTokens)
+				}
+				if _, err := q.GetUserIdentity(ctx, queries.GetUserIdentityParams{
+					Issuer:  "https://idp.example.com",
+					Subject: "idp|alice@example.com",
+				}); err != sql.ErrNoRows {
+					t.Errorf("GetUserIdentity: %v, want sql.ErrNoRows", err)
+				}
+				return
+			}
+
+			if signedIn.ID != user.ID {
+				t.Errorf("signed in as user %d, want %d", signedIn.ID, user.ID)
+			}
+			if !signedIn.EmailVerified {
+				t.Error("email is not verified after linking")
+			}
+			if sessions != 0 || resetTokens != 0 {
+				t.Errorf("got %d sessions and %d reset tokens after linking, want none", sessions, resetTokens)
+			}
+		})
+	}
+}
+
+func TestSSOSignInRequiresVerifiedEmail(t *testing.T) {
+	s, _ := newTestSSOService(t)
+
+	claims := testClaims("alice@example.com")
+	claims.EmailVerified = false
+	if _, err := s.signIn(context.Background(), claims); !errors.Is(err, ErrSSOEmailNotVerified) {
+		t.Errorf("signIn: %v, want ErrSSOEmailNotVerified", err)
+	}
+}
+
+func TestSSOLink(t *testing.T) {
+	ctx := context.Background()
+	s, q := newTestSSOService(t)
+
+	alice := createTestUser(t, q, "alice@example.com", "password123")
+	bob := createTestUser(t, q, "bob@example.com", "password123")
+	giveTestAccess(t, q, alice.ID)
+
+	// A signed in user can link an account the sign in would not link
+	linked, err := s.link(ctx, alice.ID, testClaims("alice@example.com"))
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if !linked.EmailVerified {
+		t.Error("email is not verified after linking")
+	}
+	if sessions, resetTokens := testAccess(t, q, alice.ID); sessions != 0 || resetTokens != 0 {
+		t.Errorf("got %d sessions and %d reset tokens after linking, want none", sessions, resetTokens)
+	}
+
+	signedIn, err := s.signIn(ctx, testClaims("alice@example.com"))
+	if err != nil {
+		t.Fatalf("signIn after linking: %v", err)
+	}
+	if signedIn.ID != alice.ID {
+		t.Errorf("signed in as user %d, want %d", signedIn.ID, alice.ID)
+	}
+
+	// Linking again is a no-op, but nobody else can link the account
+	if _, err := s.link(ctx, alice.ID, testClaims("alice@example.com")); err != nil {
+		t.Errorf("link again: %v", err)
+	}
+	if _, err := s.link(ctx, bob.ID, testClaims("alice@example.com")); !errors.Is(err, ErrSSOIdentityInUse) {
+		t.Errorf("link to another user: %v, want ErrSSOIdentityInUse", err)
+	}
+
+	// A provider account with another email does not verify the user's
+	claims := testClaims("someone@example.com")
+	linked, err = s.link(ctx, bob.ID, claims)
+	if err != nil {
+		t.Fatalf("link with another email: %v", err)
+	}
+	if linked.EmailVerified {
+		t.Error("email was verified by a provider account with another email")
+	}
+}
+
+func TestSSOTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	s, q := newTestSSOService(t)
+	client := SessionClient{IPAddress: "192.0.2.1"}
+
+	f := enableTestTwoFactor(t, s.auth, "alice@example.com")
+	alice, err := q.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	if _, err := q.MarkUserEmailVerified(ctx, alice.ID); err != nil {
+		t.Fatalf("verify email: %v", err)
+	}
+	giveTestAccess(t, q, alice.ID)
+
+	// The provider cannot check the second factor, so the account is not
+	// linked and its owner is not signed out
+	if _, err := s.finish(ctx, sql.NullInt64{}, testClaims("alice@example.com"), client); !errors.Is(err, ErrSSOAccountNotLinked) {
+		t.Fatalf("sign in: %v, want ErrSSOAccountNotLinked", err)
+	}
+	if sessions, resetTokens := testAccess(t, q, alice.ID); sessions != 1 || resetTokens != 1 {
+		t.Errorf("got %d sessions and %d reset tokens, want 1 and 1", sessions, resetTokens)
+	}
+
+	// Linking from a session that passed the second factor signs in
+	result, err := s.finish(ctx, sql.NullInt64{Int64: alice.ID, Valid: true}, testClaims("alice@example.com"), client)
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if result.TwoFactorRequired || result.SessionToken == "" {
+		t.Error("link did not create a session")
+	}
+
+	// Later sign ins still need a code
+	result, err = s.finish(ctx, sql.NullInt64{}, testClaims("alice@example.com"), client)
+	if err != nil {
+		t.Fatalf("sign in after linking: %v", err)
+	}
+	if !result.TwoFactorRequired || result.Challenge == "" || result.SessionToken != "" {
+		t.Fatal("sign in after linking did not ask for a second factor")
+	}
+	completed, err := s.auth.CompleteLogin(ctx, result.Challenge, f.code(t, 1), client)
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if completed.User.ID != alice.ID || completed.SessionToken == "" {
+		t.Errorf("CompleteLogin signed in user %d with session token %q, want user %d with a session", completed.User.ID, completed.SessionToken, alice.ID)
+	}
+}
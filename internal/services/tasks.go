@@ -0,0 +1,675 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/events"
+)
+
+var (
+	// ErrTaskNotFound is returned when a task does not exist
+	ErrTaskNotFound = errors.New("task not found")
+
+	// ErrWipLimitExceeded is returned when a column is already at its WIP limit
+	ErrWipLimitExceeded = errors.New("column WIP limit exceeded")
+)
+
+// WipLimitError describes a rejected move or create into a full column
+type WipLimitError struct {
+	ColumnID int64
+	Limit    int64
+	Count    int64
+}
+
+// Error implements the error interface
+func (e *WipLimitError) Error() string {
+	return fmt.Sprintf("column already has %d of %d allowed open tasks", e.Count, e.Limit)
+}
+
+// Is makes WipLimitError match ErrWipLimitExceeded
+func (e *WipLimitError) Is(target error) bool {
+	return target == ErrWipLimitExceeded
+}
+
+// validPriorities are the documented task priorities
+var validPriorities = map[string]bool{
+	"low":    true,
+	"medium": true,
+	"high":   true,
+	"urgent": true,
+}
+
+// TaskService handles task business logic
+type TaskService struct {
+	db         *sql.DB
+	queries    *queries.Queries
+	rebalancer *RankRebalancer
+	broker     *events.Broker
+}
+
+// NewTaskService creates a new task service
+func NewTaskService(db *sql.DB, q *queries.Queries, rebalancer *RankRebalancer, broker *events.Broker) *TaskService {
+	return &TaskService{
+		db:         db,
+		queries:    q,
+		rebalancer: rebalancer,
+		broker:     broker,
+	}
+}
+
+// TaskInput contains the editable fields of a task
+type TaskInput struct {
+	Title       string
+	Description string
+	Priority    string
+	DueDate     string
+}
+
+// validate trims and checks the task input
+func (in *TaskInput) validate() error {
+	in.Title = strings.TrimSpace(in.Title)
+	in.Description = strings.TrimSpace(in.Description)
+	in.Priority = strings.TrimSpace(in.Priority)
+	in.DueDate = strings.TrimSpace(in.DueDate)
+
+	if len(in.Title) < 1 || len(in.Title) > 200 {
+		return newValidationError("title must be between 1 and 200 characters")
+	}
+
+	if len(in.Description) > 10000 {
+		return newValidationError("description must be at most 10000 characters")
+	}
+
+	if in.Priority == "" {
+		in.Priority = "medium"
+	}
+	if !validPriorities[in.Priority] {
+		return newValidationError("priority must be one of 'low', 'medium', 'high', 'urgent'")
+	}
+
+	if in.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", in.DueDate); err != nil {
+			return newValidationError("due_date must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	return nil
+}
+
+// dueDate converts the optional due date to a nullable value
+func (in *TaskInput) dueDate() sql.NullTime {
+	if in.DueDate == "" {
+		return sql.NullTime{}
+	}
+	t, _ := time.Parse("2006-01-02", in.DueDate)
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+// GetTask returns a single task
+func (s *TaskService) GetTask(ctx context.Context, taskID int64) (*queries.Task, error) {
+	task, err := s.queries.GetTask(ctx, taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+// TaskDetail is a task with everything shown on its detail view.
+// DescriptionHTML is the description rendered from Markdown, with mentions
+// of project members as links.
+type TaskDetail struct {
+	Task               queries.Task
+	DescriptionHTML    string
+	CreatorName        string
+	CreatorEmail       string
+	CreatorAvatarURL   string
+	Assignees          []queries.GetTaskAssigneesRow
+	Labels             []queries.GetTaskLabelsRow
+	ChecklistTotal     int64
+	ChecklistCompleted int64
+	CommentCount       int64
+}
+
+// GetTaskDetail returns a task with its rendered description, creator,
+// assignees, labels, checklist progress and comment count
+func (s *TaskService) GetTaskDetail(ctx context.Context, access *ProjectAccess, taskID int64) (*TaskDetail, error) {
+	row, err := s.queries.GetTaskWithCreator(ctx, taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	assignees, err := s.queries.GetTaskAssignees(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := s.queries.GetTaskLabels(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	checklist, err := s.queries.GetChecklistProgress(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := s.queries.CountCommentsByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	description, _, err := renderMentions(ctx, s.queries, access.Project.ID, row.Description.String)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TaskDetail{
+		Task: queries.Task{
+			ID:          row.ID,
+			ColumnID:    row.ColumnID,
+			CreatedBy:   row.CreatedBy,
+			Title:       row.Title,
+			Description: row.Description,
+			Priority:    row.Priority,
+			DueDate:     row.DueDate,
+			CompletedAt: row.CompletedAt,
+			CreatedAt:   row.CreatedAt,
+			UpdatedAt:   row.UpdatedAt,
+			Rank:        row.Rank,
+		},
+		DescriptionHTML:    description,
+		CreatorName:        row.CreatorName,
+		CreatorEmail:       row.CreatorEmail,
+		CreatorAvatarURL:   row.CreatorAvatarUrl.String,
+		Assignees:          assignees,
+		Labels:             labels,
+		ChecklistTotal:     checklist.Total,
+		ChecklistCompleted: checklist.Completed,
+		CommentCount:       comments,
+	}, nil
+}
+
+// CreateTask adds a task to the end of a column and notifies the project
+// members mentioned in its description. The creator starts watching the
+// task. If the column is at its WIP limit the task is rejected unless an
+// admin explicitly overrides it.
+func (s *TaskService) CreateTask(ctx context.Context, access *ProjectAccess, columnID int64, input TaskInput, overrideWip bool) (*queries.Task, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := s.queries.WithTx(tx)
+
+	column, err := getProjectColumn(ctx, qtx, access.Project.ID, columnID)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := qtx.CountOpenTasksByColumn(ctx, column.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden, err := checkWipLimit(access, column, count, overrideWip)
+	if err != nil {
+		return nil, err
+	}
+
+	// Append after the last task of the column, open or completed
+	scope := rankScope{list: taskRanks, parentID: column.ID}
+	rank, err := placeRank(ctx, qtx, scope, math.MaxInt, func() ([]string, error) {
+		last, err := qtx.GetLastTaskRankByColumn(ctx, column.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return []string{last}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := qtx.CreateTask(ctx, queries.CreateTaskParams{
+		ColumnID:    column.ID,
+		CreatedBy:   access.UserID,
+		Title:       input.Title,
+		Description: nullString(input.Description),
+		Rank:        rank,
+		Priority:    sql.NullString{String: input.Priority, Valid: true},
+		DueDate:     input.dueDate(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if overridden {
+		if err := recordWipOverride(ctx, qtx, access, column, count, task.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, task.ID, ActivityTaskCreated, map[string]interface{}{
+		"title":       task.Title,
+		"column_id":   column.ID,
+		"column_name": column.Name,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := watch(ctx, qtx, task.ID, access.UserID); err != nil {
+		return nil, err
+	}
+
+	var out outbox
+	if err := syncDescriptionMentions(ctx, qtx, &out, access, &task); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.rebalancer.checkRank(scope, rank)
+
+	s.broker.Publish(column.BoardID, events.TaskCreated, access.UserID, &task)
+	out.push(ctx, s.queries, s.broker)
+
+	return &task, nil
+}
+
+// ColumnOrder is the ordered list of open task IDs in a column
+type ColumnOrder struct {
+	ColumnID int64
+	TaskIDs  []int64
+}
+
+// MoveResult is the outcome of a task move
+type MoveResult struct {
+	Task    queries.Task
+	Columns []ColumnOrder
+}
+
+// MoveTask moves a task to an index among the open tasks of a column in
+// the same project. The task gets a rank between its new neighbours, so the
+// move only writes the task's own row. It runs in one transaction, so
+// concurrent moves are serialized by the database. The target column's WIP
+// limit is enforced.
+func (s *TaskService) MoveTask(ctx context.Context, access *ProjectAccess, taskID, columnID, index int64, overrideWip bool) (*MoveResult, error) {
+	if index < 0 {
+		return nil, newValidationError("index must not be negative")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	qtx := s.queries.WithTx(tx)
+
+	task, err := qtx.GetTask(ctx, taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	column, err := getProjectColumn(ctx, qtx, access.Project.ID, columnID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The open tasks of the target column, without the moved task
+	var siblings []queries.Task
+	scope := rankScope{list: taskRanks, parentID: column.ID}
+	rank, err := placeRank(ctx, qtx, scope, int(index), func() ([]string, error) {
+		tasks, err := qtx.ListTasksByColumn(ctx, column.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		siblings = siblings[:0]
+		ranks := make([]string, 0, len(tasks))
+		for _, t := range tasks {
+			if t.ID == task.ID {
+				continue
+			}
+			siblings = append(siblings, t)
+			ranks = append(ranks, t.Rank)
+		}
+		return ranks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Moving a completed task does not change the open task count
+	if column.ID != task.ColumnID && !task.CompletedAt.Valid {
+		count := int64(len(siblings))
+		overridden, err := checkWipLimit(access, column, count, overrideWip)
+		if err != nil {
+			return nil, err
+		}
+
+		if overridden {
+			if err := recordWipOverride(ctx, qtx, access, column, count, task.ID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	moved, err := qtx.MoveTask(ctx, queries.MoveTaskParams{
+		ColumnID: column.ID,
+		Rank:     rank,
+		ID:       task.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	from := column
+	if task.ColumnID != column.ID {
+		if from, err = getProjectColumn(ctx, qtx, access.Project.ID, task.ColumnID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, task.ID, ActivityTaskMoved, map[string]interface{}{
+		"title":            task.Title,
+		"from_column_id":   from.ID,
+		"from_column_name": from.Name,
+		"to_column_id":     column.ID,
+		"to_column_name":   column.Name,
+		"index":            index,
+	}); err != nil {
+		return nil, err
+	}
+
+	result := &MoveResult{Task: moved}
+
+	columnIDs := []int64{column.ID}
+	if column.ID != task.ColumnID {
+		columnIDs = []int64{task.ColumnID, column.ID}
+	}
+	for _, id := range columnIDs {
+		tasks, err := qtx.ListTasksByColumn(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		order := ColumnOrder{ColumnID: id, TaskIDs: make([]int64, 0, len(tasks))}
+		for _, t := range tasks {
+			order.TaskIDs = append(order.TaskIDs, t.ID)
+		}
+		result.Columns = append(result.Columns, order)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.rebalancer.checkRank(scope, rank)
+
+	// A task moved to another board leaves the board it came from
+	s.broker.Publish(column.BoardID, events.TaskMoved, access.UserID, result)
+	if from.BoardID != column.BoardID {
+		s.broker.Publish(from.BoardID, events.TaskMoved, access.UserID, result)
+	}
+
+	return result, nil
+}
+
+// UpdateTask updates a task's title, description, priority and due date
+// and records which of them changed. Members newly mentioned in the
+// description are notified.
+func (s *TaskService) UpdateTask(ctx context.Context, access *ProjectAccess, taskID int64, input TaskInput) (*queries.Task, error) {
+	if err := input.validate(); err != nil {
+		return nil, err
+	}
+
+	var updated queries.Task
+	var boardID int64
+	var out outbox
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		task, err := qtx.GetTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		updated, err = qtx.UpdateTask(ctx, queries.UpdateTaskParams{
+			Title:       input.Title,
+			Description: nullString(input.Description),
+			Priority:    sql.NullString{String: input.Priority, Valid: true},
+			DueDate:     input.dueDate(),
+			ID:          taskID,
+		})
+		if err != nil {
+			return err
+		}
+
+		changes := changeSet{}
+		changes.add("title", task.Title, updated.Title)
+		changes.add("description", optional(task.Description), optional(updated.Description))
+		changes.add("priority", optional(task.Priority), optional(updated.Priority))
+		changes.add("due_date", optional(task.DueDate), optional(updated.DueDate))
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if _, ok := changes["description"]; ok {
+			if err := syncDescriptionMentions(ctx, qtx, &out, access, &updated); err != nil {
+				return err
+			}
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, taskID, ActivityTaskUpdated, changes.details())
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	s.broker.Publish(boardID, events.TaskUpdated, access.UserID, &updated)
+	out.push(ctx, s.queries, s.broker)
+
+	return &updated, nil
+}
+
+// CompleteTask marks a task as completed. Completing a completed task
+// keeps its original completion time.
+func (s *TaskService) CompleteTask(ctx context.Context, access *ProjectAccess, taskID int64) (*queries.Task, error) {
+	return s.setCompleted(ctx, access, taskID, true)
+}
+
+// UncompleteTask reopens a completed task in its column
+func (s *TaskService) UncompleteTask(ctx context.Context, access *ProjectAccess, taskID int64) (*queries.Task, error) {
+	return s.setCompleted(ctx, access, taskID, false)
+}
+
+// setCompleted completes or reopens a task. Nothing is written or recorded
+// if the task is already in the requested state.
+func (s *TaskService) setCompleted(ctx context.Context, access *ProjectAccess, taskID int64, completed bool) (*queries.Task, error) {
+	var result queries.Task
+	var boardID int64
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		task, err := qtx.GetTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		if task.CompletedAt.Valid == completed {
+			result = task
+			return nil
+		}
+
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		action := ActivityTaskCompleted
+		if completed {
+			err = qtx.CompleteTask(ctx, taskID)
+		} else {
+			action = ActivityTaskUncompleted
+			err = qtx.UncompleteTask(ctx, taskID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := recordActivity(ctx, qtx, access.Project.ID, access.UserID, taskID, action, map[string]interface{}{
+			"title": task.Title,
+		}); err != nil {
+			return err
+		}
+
+		result, err = qtx.GetTask(ctx, taskID)
+		return err
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+
+	// Nothing changed if the task was already in the requested state
+	if boardID != 0 {
+		eventType := events.TaskUncompleted
+		if completed {
+			eventType = events.TaskCompleted
+		}
+		s.broker.Publish(boardID, eventType, access.UserID, &result)
+	}
+
+	return &result, nil
+}
+
+// DeleteTask deletes a task with its assignees, labels, checklist and
+// comments. The deletion is recorded at project level since the task's
+// own activity loses its link to the task.
+func (s *TaskService) DeleteTask(ctx context.Context, access *ProjectAccess, taskID int64) error {
+	var task queries.Task
+	var boardID int64
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		var err error
+		task, err = qtx.GetTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		boardID, err = qtx.GetBoardIDByTask(ctx, taskID)
+		if err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteTask(ctx, taskID); err != nil {
+			return err
+		}
+
+		return recordActivity(ctx, qtx, access.Project.ID, access.UserID, 0, ActivityTaskDeleted, map[string]interface{}{
+			"task_id":   task.ID,
+			"title":     task.Title,
+			"column_id": task.ColumnID,
+		})
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrTaskNotFound
+		}
+		return err
+	}
+
+	s.broker.Publish(boardID, events.TaskDeleted, access.UserID, events.Refs{
+		ColumnID: task.ColumnID,
+		TaskID:   task.ID,
+	})
+
+	return nil
+}
+
+// getProjectColumn loads a column and checks that it belongs to the project
+func getProjectColumn(ctx context.Context, q *queries.Queries, projectID, columnID int64) (*queries.Column, error) {
+	columnProjectID, err := q.GetProjectIDByColumn(ctx, columnID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrColumnNotFound
+		}
+		return nil, err
+	}
+	if columnProjectID != projectID {
+		return nil, ErrColumnNotFound
+	}
+
+	column, err := q.GetColumn(ctx, columnID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrColumnNotFound
+		}
+		return nil, err
+	}
+
+	return &column, nil
+}
+
+// checkWipLimit decides whether one more open task may enter a column that
+// currently holds count open tasks. It reports whether the limit was
+// overridden, which only project admins may do.
+func checkWipLimit(access *ProjectAccess, column *queries.Column, count int64, override bool) (bool, error) {
+	if !column.WipLimit.Valid || count < column.WipLimit.Int64 {
+		return false, nil
+	}
+
+	if !override {
+		return false, &WipLimitError{
+			ColumnID: column.ID,
+			Limit:    column.WipLimit.Int64,
+			Count:    count,
+		}
+	}
+
+	if !access.Role.AtLeast(RoleAdmin) {
+		return false, ErrForbidden
+	}
+
+	return true, nil
+}
+
+// recordWipOverride logs that an admin pushed a column past its WIP limit
+func recordWipOverride(ctx context.Context, q *queries.Queries, access *ProjectAccess, column *queries.Column, count, taskID int64) error {
+	return recordActivity(ctx, q, access.Project.ID, access.UserID, taskID, ActivityWipLimitOverridden, map[string]interface{}{
+		"column_id":   column.ID,
+		"column_name": column.Name,
+		"wip_limit":   column.WipLimit.Int64,
+		"task_count":  count + 1,
+	})
+}
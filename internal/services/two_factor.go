@@ -0,0 +1,374 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/database/queries"
+	"github.com/erickhilda/vugo/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrTwoFactorAlreadyEnabled is returned when enrolling a user who
+	// already has two-factor authentication enabled
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when an action requires two-factor
+	// authentication but the user has not enabled it
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidTwoFactorCode is returned when an authenticator or recovery
+	// code is wrong or has already been used
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidLoginChallenge is returned when a login challenge does not
+	// exist, has expired or has had too many wrong codes
+	ErrInvalidLoginChallenge = errors.New("invalid or expired login challenge")
+)
+
+const (
+	// totpIssuer is the name authenticator apps show next to the account
+	totpIssuer = "Vugo"
+	// recoveryCodeCount is how many recovery codes a user gets
+	recoveryCodeCount = 10
+	// loginChallengeTTL is how long a user has to enter their code after
+	// their password
+	loginChallengeTTL = 5 * time.Minute
+	// maxLoginChallengeAttempts is how many wrong codes a login challenge
+	// accepts before the password has to be entered again
+	maxLoginChallengeAttempts = 5
+)
+
+// recoveryCodeEncoding is lower case base32 without padding, which is easy
+// to read and type
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// TwoFactorStatus describes a user's two-factor authentication
+type TwoFactorStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int64
+}
+
+// TwoFactorEnrollment holds the secret for a user to add to their
+// authenticator app, both as is and as an otpauth:// URI
+type TwoFactorEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// TwoFactorStatus returns whether a user has two-factor authentication
+// enabled and how many unused recovery codes they have
+func (s *AuthService) TwoFactorStatus(ctx context.Context, userID int64) (*TwoFactorStatus, error) {
+	enabled, err := twoFactorEnabled(ctx, s.queries, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return &TwoFactorStatus{}, nil
+	}
+
+	left, err := s.queries.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorStatus{
+		Enabled:           true,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// EnrollTwoFactor starts enabling two-factor authentication by creating a
+// new secret for the user. It takes effect once confirmed with a code from
+// the authenticator app; enrolling again before that replaces the secret.
+func (s *AuthService) EnrollTwoFactor(ctx context.Context, user *queries.User) (*TwoFactorEnrollment, error) {
+	enabled, err := twoFactorEnabled(ctx, s.queries, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.queries.UpsertUserTOTP(ctx, queries.UpsertUserTOTPParams{
+		UserID: user.ID,
+		Secret: secret,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user enters
+// a code from their authenticator app, and returns their recovery codes.
+// The codes are only stored hashed, so this is the only time they are
+// shown.
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, userID int64, code string) ([]string, error) {
+	secret, err := s.queries.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, newValidationError("two-factor enrollment has not been started")
+		}
+		return nil, err
+	}
+	if secret.ConfirmedAt.Valid {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(secret.Secret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	var codes []string
+	err = inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		// The code used to confirm cannot be used to log in
+		if err := qtx.ConfirmUserTOTP(ctx, queries.ConfirmUserTOTPParams{
+			LastUsedStep: step,
+			UserID:       userID,
+		}); err != nil {
+			return err
+		}
+
+		codes, err = createRecoveryCodes(ctx, qtx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication after checking the
+// user's password and a current authenticator or recovery code
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID int64, password, code string) error {
+	user, err := s.queries.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrIncorrectPassword
+	}
+
+	return inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		if err := verifySecondFactor(ctx, qtx, userID, code); err != nil {
+			return err
+		}
+
+		if err := qtx.DeleteUserTOTP(ctx, userID); err != nil {
+			return err
+		}
+		return qtx.DeleteUserRecoveryCodes(ctx, userID)
+	})
+}
+
+// RegenerateRecoveryCodes replaces a user's recovery codes after checking
+// a current authenticator or recovery code, and returns the new codes
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	var codes []string
+	err := inTx(ctx, s.db, s.queries, func(qtx *queries.Queries) error {
+		if err := verifySecondFactor(ctx, qtx, userID, code); err != nil {
+			return err
+		}
+
+		var err error
+		codes, err = createRecoveryCodes(ctx, qtx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// CompleteLogin finishes a login started by Login for a user with
+// two-factor authentication, creating a session once an authenticator or
+// recovery code is verified
+func (s *AuthService) CompleteLogin(ctx context.Context, challenge, code string, client SessionClient) (*LoginResult, error) {
+	tokenHash := hashToken(challenge)
+	pending, err := s.queries.GetLoginChallenge(ctx, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInvalidLoginChallenge
+		}
+		return nil, err
+	}
+	if time.Now().After(pending.ExpiresAt) || pending.Attempts >= maxLoginChallengeAttempts {
+		_ = s.queries.DeleteLoginChallenge(ctx, tokenHash)
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	user, err := s.queries.GetUser(ctx, pending.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrong codes count as failed logins, so codes cannot be guessed by
+	// starting new logins with a known password
+	if err := s.limiter.Check(ctx, user.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	if err := verifySecondFactor(ctx, s.queries, pending.UserID, code); err != nil {
+		if err == ErrInvalidTwoFactorCode {
+			if err := s.queries.IncrementLoginChallengeAttempts(ctx, tokenHash); err != nil {
+				return nil, err
+			}
+			return nil, s.loginFailed(ctx, user.Email, client, sql.NullInt64{Int64: user.ID, Valid: true}, err)
+		}
+		return nil, err
+	}
+
+	if err := s.queries.DeleteLoginChallenge(ctx, tokenHash); err != nil {
+		return nil, err
+	}
+	if err := s.limiter.Succeed(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	session, token, err := s.createSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		User:         user,
+		Session:      session,
+		SessionToken: token,
+	}, nil
+}
+
+// CleanupExpiredLoginChallenges deletes expired login challenges
+func (s *AuthService) CleanupExpiredLoginChallenges(ctx context.Context) error {
+	return s.queries.DeleteExpiredLoginChallenges(ctx)
+}
+
+// twoFactorEnabled reports whether a user has confirmed two-factor
+// authentication
+func twoFactorEnabled(ctx context.Context, q *queries.Queries, userID int64) (bool, error) {
+	secret, err := q.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return secret.ConfirmedAt.Valid, nil
+}
+
+// createLoginChallenge creates a login challenge for a user who has entered
+// their password and returns its token
+func (s *AuthService) createLoginChallenge(ctx context.Context, userID int64) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.queries.CreateLoginChallenge(ctx, queries.CreateLoginChallengeParams{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(loginChallengeTTL),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// verifySecondFactor checks an authenticator code or recovery code for a
+// user with two-factor authentication enabled and uses it up, so it cannot
+// be used again
+func verifySecondFactor(ctx context.Context, q *queries.Queries, userID int64, code string) error {
+	secret, err := q.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+	if !secret.ConfirmedAt.Valid {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	if step, ok := totp.Validate(secret.Secret, code, time.Now(), secret.LastUsedStep); ok {
+		// Recording the step fails if a concurrent login used the code first
+		used, err := q.UseTOTPStep(ctx, queries.UseTOTPStepParams{
+			LastUsedStep:   step,
+			UserID:         userID,
+			LastUsedStep_2: step,
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := q.UseRecoveryCode(ctx, queries.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashToken(normalizeRecoveryCode(code)),
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+// createRecoveryCodes replaces a user's recovery codes with new ones and
+// returns them
+func createRecoveryCodes(ctx context.Context, q *queries.Queries, userID int64) ([]string, error) {
+	if err := q.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		encoded := recoveryCodeEncoding.EncodeToString(b)
+		codes[i] = encoded[:4] + "-" + encoded[4:]
+
+		if err := q.CreateRecoveryCode(ctx, queries.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeRecoveryCode(codes[i])),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode returns a recovery code in the form it is hashed
+// in, so it can be typed in any case and with or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/totp"
+)
+
+// testTwoFactor is a user's two-factor secret and recovery codes, and the
+// time step of the code used to confirm enrollment
+type testTwoFactor struct {
+	secret        string
+	confirmedStep int64
+	recoveryCodes []string
+}
+
+// code returns the authenticator code offset steps from the confirmed one
+func (f *testTwoFactor) code(t *testing.T, offset int64) string {
+	t.Helper()
+
+	code, err := totp.Code(f.secret, f.confirmedStep+offset)
+	if err != nil {
+		t.Fatalf("totp.Code: %v", err)
+	}
+	return code
+}
+
+// enableTestTwoFactor creates a user with two-factor authentication
+// enabled with the code for the current step
+func enableTestTwoFactor(t *testing.T, s *AuthService, email string) *testTwoFactor {
+	t.Helper()
+	ctx := context.Background()
+
+	user := createTestUser(t, s.queries, email, "password123")
+	enrollment, err := s.EnrollTwoFactor(ctx, &user)
+	if err != nil {
+		t.Fatalf("EnrollTwoFactor: %v", err)
+	}
+
+	f := &testTwoFactor{secret: enrollment.Secret, confirmedStep: totp.Step(time.Now())}
+	f.recoveryCodes, err = s.ConfirmTwoFactor(ctx, user.ID, f.code(t, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTwoFactor: %v", err)
+	}
+	if len(f.recoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(f.recoveryCodes), recoveryCodeCount)
+	}
+	return f
+}
+
+// startTestLogin enters a user's password and returns the login challenge
+func startTestLogin(t *testing.T, s *AuthService, email string) string {
+	t.Helper()
+
+	result, err := s.Login(context.Background(), email, "password123", SessionClient{IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if !result.TwoFactorRequired || result.Challenge == "" {
+		t.Fatal("Login did not ask for a second factor")
+	}
+	return result.Challenge
+}
+
+func TestTwoFactorLoginRejectsReplayedCodes(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAuthService(t, lenientLoginPolicy)
+	f := enableTestTwoFactor(t, s, "alice@example.com")
+	client := SessionClient{IPAddress: "192.0.2.1"}
+
+	// The code used to confirm enrollment cannot sign in
+	challenge := startTestLogin(t, s, "alice@example.com")
+	if _, err := s.CompleteLogin(ctx, challenge, f.code(t, 0), client); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("CompleteLogin with confirmation code: %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	// The next step is within the allowed skew
+	result, err := s.CompleteLogin(ctx, challenge, f.code(t, 1), client)
+	if err != nil {
+		t.Fatalf("CompleteLogin with next code: %v", err)
+	}
+	if result.SessionToken == "" {
+		t.Error("CompleteLogin did not create a session")
+	}
+
+	// Neither that code nor an older one works again
+	for _, offset := range []int64{1, -1} {
+		challenge := startTestLogin(t, s, "alice@example.com")
+		if _, err := s.CompleteLogin(ctx, challenge, f.code(t, offset), client); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Errorf("CompleteLogin with used step %+d: %v, want ErrInvalidTwoFactorCode", offset, err)
+		}
+	}
+}
+
+func TestTwoFactorRecoveryCodesAreSingleUse(t *testing.T) {
+	ctx := context.Background()
+	s, q := newTestAuthService(t, lenientLoginPolicy)
+	f := enableTestTwoFactor(t, s, "alice@example.com")
+	codes := f.recoveryCodes
+	client := SessionClient{IPAddress: "192.0.2.1"}
+
+	// Recovery codes can be typed in any case and without the dash
+	typed := strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))
+
+	challenge := startTestLogin(t, s, "alice@example.com")
+	if _, err := s.CompleteLogin(ctx, challenge, typed, client); err != nil {
+		t.Fatalf("CompleteLogin with recovery code: %v", err)
+	}
+
+	challenge = startTestLogin(t, s, "alice@example.com")
+	if _, err := s.CompleteLogin(ctx, challenge, codes[0], client); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("CompleteLogin with used recovery code: %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	user, err := q.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetUserByEmail: %v", err)
+	}
+	status, err := s.TwoFactorStatus(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("TwoFactorStatus: %v", err)
+	}
+	if status.RecoveryCodesLeft != recoveryCodeCount-1 {
+		t.Errorf("RecoveryCodesLeft = %d, want %d", status.RecoveryCodesLeft, recoveryCodeCount-1)
+	}
+
+	// Another unused code still works
+	challenge = startTestLogin(t, s, "alice@example.com")
+	if _, err := s.CompleteLogin(ctx, challenge, codes[1], client); err != nil {
+		t.Errorf("CompleteLogin with another recovery code: %v", err)
+	}
+}
+
+func TestTwoFactorLoginChallengeAttemptCap(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newTestAuthService(t, lenientLoginPolicy)
+	f := enableTestTwoFactor(t, s, "alice@example.com")
+	client := SessionClient{IPAddress: "192.0.2.1"}
+
+	challenge := startTestLogin(t, s, "alice@example.com")
+	for i := 0; i < maxLoginChallengeAttempts; i++ {
+		if _, err := s.CompleteLogin(ctx, challenge, "000000", client); !errors.Is(err, ErrInvalidTwoFactorCode) {
+			t.Fatalf("wrong code %d: %v, want ErrInvalidTwoFactorCode", i+1, err)
+		}
+	}
+
+	// Once the cap is reached even a correct code needs a new login
+	if _, err := s.CompleteLogin(ctx, challenge, f.code(t, 1), client); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Fatalf("CompleteLogin after %d wrong codes: %v, want ErrInvalidLoginChallenge", maxLoginChallengeAttempts, err)
+	}
+
+	challenge = startTestLogin(t, s, "alice@example.com")
+	if _, err := s.CompleteLogin(ctx, challenge, f.code(t, 1), client); err != nil {
+		t.Errorf("CompleteLogin with a new challenge: %v", err)
+	}
+}
+
+func TestTwoFactorUnknownChallenge(t *testing.T) {
+	s, _ := newTestAuthService(t, lenientLoginPolicy)
+
+	if _, err := s.CompleteLogin(context.Background(), "no-such-challenge", "123456", SessionClient{}); !errors.Is(err, ErrInvalidLoginChallenge) {
+		t.Errorf("CompleteLogin: %v, want ErrInvalidLoginChallenge", err)
+	}
+}
@@ -1,50 +1,173 @@
 package templates
 
 import (
+	"bytes"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
-	"path/filepath"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erickhilda/vugo/internal/markdown"
+)
+
+// Files holds the templates shipped with the binary
+//
+//go:embed emails/*.html layouts/*.html pages/*.html partials/*.html
+var Files embed.FS
+
+// Template directories. Layouts and partials are shared by every page;
+// emails and pages are the templates that get rendered.
+const (
+	layoutsDir  = "layouts"
+	partialsDir = "partials"
 )
 
-// Templates holds all parsed templates
-var Templates *template.Template
+// set holds one parsed template per renderable file, each with its own copy
+// of the layouts and partials so pages can define the same blocks
+type set map[string]*template.Template
 
-// Init initializes and parses all templates
+// engine is the template set in use
+var engine struct {
+	sync.RWMutex
+	fsys   fs.FS
+	reload bool
+	pages  set
+}
+
+// Init parses all templates in templatesFS, usually Files
 func Init(templatesFS fs.FS) error {
-	tmpl := template.New("").Funcs(templateFuncs())
+	return load(templatesFS, false)
+}
+
+// InitDev parses the templates in dir and parses them again on every
+// render, so edits show up without restarting the server
+func InitDev(dir string) error {
+	return load(os.DirFS(dir), true)
+}
+
+// load parses the templates in fsys and makes them the ones in use
+func load(fsys fs.FS, reload bool) error {
+	pages, err := parse(fsys)
+	if err != nil {
+		return err
+	}
+
+	engine.Lock()
+	defer engine.Unlock()
+	engine.fsys = fsys
+	engine.reload = reload
+	engine.pages = pages
+	return nil
+}
 
-	// Walk through templates directory
-	err := fs.WalkDir(templatesFS, ".", func(path string, d fs.DirEntry, err error) error {
+// parse parses every page in fsys together with the layouts and partials.
+// A page uses a layout by executing it and defining the blocks it leaves
+// open, e.g. {{template "layouts/email.html" .}}{{define "content"}}...{{end}}.
+// Templates are named by their path, e.g. "emails/notification.html".
+func parse(fsys fs.FS) (set, error) {
+	base := template.New("").Funcs(templateFuncs())
+
+	var pages []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// Only process .html files
-		if d.IsDir() || filepath.Ext(path) != ".html" {
+		if d.IsDir() || path.Ext(name) != ".html" {
+			return nil
+		}
+
+		dir, _, _ := strings.Cut(name, "/")
+		if dir != layoutsDir && dir != partialsDir {
+			pages = append(pages, name)
 			return nil
 		}
 
-		// Read template file
-		data, err := fs.ReadFile(templatesFS, path)
+		// Read and parse shared template
+		data, err := fs.ReadFile(fsys, name)
 		if err != nil {
 			return err
 		}
-
-		// Parse template
-		name := path
-		if _, err := tmpl.New(name).Parse(string(data)); err != nil {
+		if _, err := base.New(name).Parse(string(data)); err != nil {
 			return err
 		}
-
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make(set, len(pages))
+	for _, name := range pages {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		tmpl, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.New(name).Parse(string(data)); err != nil {
+			return nil, err
+		}
+		parsed[name] = tmpl
+	}
+
+	return parsed, nil
+}
+
+// lookup returns a page's template, parsing the templates again first in
+// dev mode
+func lookup(name string) (*template.Template, error) {
+	engine.RLock()
+	fsys, reload, pages := engine.fsys, engine.reload, engine.pages
+	engine.RUnlock()
+
+	if pages == nil {
+		return nil, errors.New("templates not initialized")
+	}
 
+	if reload {
+		var err error
+		if pages, err = parse(fsys); err != nil {
+			return nil, err
+		}
+	}
+
+	tmpl, ok := pages[name]
+	if !ok {
+		return nil, fmt.Errorf("template %q not found", name)
+	}
+	return tmpl, nil
+}
+
+// RenderTo renders a template with data to w
+func RenderTo(w io.Writer, name string, data interface{}) error {
+	tmpl, err := lookup(name)
 	if err != nil {
 		return err
 	}
+	return tmpl.ExecuteTemplate(w, name, data)
+}
 
-	Templates = tmpl
-	return nil
+// Render renders a template with data to a string. Nothing is returned if
+// rendering fails part way.
+func Render(name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := RenderTo(&buf, name, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 // templateFuncs returns template helper functions
@@ -68,14 +191,63 @@ func templateFuncs() template.FuncMap {
 		"lt": func(a, b int) bool {
 			return a < b
 		},
+		"date": func(t interface{}) string {
+			return formatTime(t, "Jan 2, 2006")
+		},
+		"datetime": func(t interface{}) string {
+			return formatTime(t, "Jan 2, 2006 15:04 MST")
+		},
+		"markdown": func(source string) (template.HTML, error) {
+			// The rendered HTML is sanitized, so it is safe to insert as is
+			html, err := markdown.Render(source)
+			return template.HTML(html), err
+		},
+		"pluralize": func(count interface{}, singular, plural string) (string, error) {
+			n, err := toInt64(count)
+			if err != nil {
+				return "", err
+			}
+			if n == 1 {
+				return singular, nil
+			}
+			return plural, nil
+		},
 	}
 }
 
-// Render renders a template with data
-func Render(name string, data interface{}) (string, error) {
-	var buf []byte
-	// This will be implemented when we have templates
-	_ = buf
-	_ = data
-	return "", nil
+// formatTime formats a time.Time, *time.Time or sql.NullTime in UTC. Zero
+// and missing times format as an empty string.
+func formatTime(value interface{}, layout string) string {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v != nil {
+			t = *v
+		}
+	case sql.NullTime:
+		if v.Valid {
+			t = v.Time
+		}
+	}
+
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+// toInt64 converts a template number to an int64
+func toInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("pluralize: %T is not an integer", value)
+	}
 }